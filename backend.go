@@ -4,10 +4,30 @@
 
 package logging
 
-import "io"
+import (
+	"io"
+	"sync/atomic"
+)
 
-// defaultBackend is the backend used for all logging calls.
-var defaultBackend LeveledBackend
+// defaultBackend stores the backendBox of the backend used for all logging
+// calls, set by SetBackend.
+var defaultBackend atomic.Value
+
+// backendBox keeps the stored backends of the same type, as required by
+// atomic.Value.
+type backendBox struct {
+	backend LeveledBackend
+}
+
+// getDefaultBackend returns the default backend. Before the first SetBackend,
+// which is done by the package initialization, it returns a null backend, so
+// logging is a no-op instead of a panic.
+func getDefaultBackend() LeveledBackend {
+	if box, ok := defaultBackend.Load().(backendBox); ok && box.backend != nil {
+		return box.backend
+	}
+	return nullBackend{}
+}
 
 // Backend is the interface which a log backend need to implement to be able to
 // be used as a logging backend.
@@ -22,10 +42,35 @@ type BackendCloser interface {
 	io.Closer
 }
 
+// Printer is implemented by the backends which write values as is. Printed
+// values have no level nor module, so they skip the level filtering and the
+// formatting, eg. to write banners or already formatted lines. Use
+// LevelPrinter to print values which respect them.
 type Printer interface {
 	Print(args ...interface{}) (err error)
 }
 
+// LevelPrinter is implemented by the backends which print values as a record
+// of a level, built by PrintRecord. Unlike Print, the record goes through the
+// normal path: it's dropped by the level of the backend, the level of the
+// records without module, and formatted by the backend formatter.
+type LevelPrinter interface {
+	PrintLevel(level Level, args ...interface{}) error
+}
+
+// PrintRecord returns the record of values printed with level. It has no
+// module and uses the default formatter.
+func PrintRecord(level Level, args ...interface{}) *Record {
+	r := &Record{
+		Time:      timeNow(),
+		Level:     level,
+		Args:      args,
+		formatter: GetFormatter(),
+	}
+	r.setID()
+	return r
+}
+
 type MustPrint func(args ...interface{}) (err error)
 
 func (this MustPrint) Print(args ...interface{}) {
@@ -46,6 +91,46 @@ type BackendPrintCloser interface {
 	io.Closer
 }
 
+// Flusher is the interface implemented by the backends which buffer or queue
+// the records, so they can be written on demand, eg. before the application
+// exits.
+type Flusher interface {
+	Flush() error
+}
+
+// FlushBackend flushes backend if it implements Flusher. The backends of this
+// package wrapping other backends, like the leveled and multi ones, flush the
+// wrapped backends.
+func FlushBackend(backend Backend) error {
+	if f, ok := backend.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// backendWrapper is implemented by the backends which pass the records to
+// other backends, and whose Flush only flushes them.
+type backendWrapper interface {
+	wrappedBackends() []Backend
+}
+
+// asyncUnflushed reports whether backend, or a backend wrapped by it, logs
+// the records in background and can't be flushed by FlushBackend, like the
+// ChannelMemoryBackend.
+func asyncUnflushed(backend Backend) bool {
+	switch b := backend.(type) {
+	case backendWrapper:
+		for _, inner := range b.wrappedBackends() {
+			if asyncUnflushed(inner) {
+				return true
+			}
+		}
+	case *ChannelMemoryBackend:
+		return true
+	}
+	return false
+}
+
 type backendPrintClose struct {
 	BackendPrinter
 	io.Closer
@@ -58,6 +143,15 @@ func NewBackendPrintClose(backend Backend, closer ...io.Closer) BackendCloser {
 	return &backendClose{Backend: backend, Closer: c}
 }
 
+// Flush implements the Flusher interface.
+func (this backendPrintClose) Flush() error {
+	return FlushBackend(this.BackendPrinter)
+}
+
+func (this backendPrintClose) wrappedBackends() []Backend {
+	return []Backend{this.BackendPrinter}
+}
+
 func (this backendPrintClose) Close() error {
 	if this.Closer != nil {
 		return this.Closer.Close()
@@ -77,6 +171,15 @@ func NewBackendClose(backend Backend, closer ...io.Closer) BackendCloser {
 	return &backendClose{Backend: backend, Closer: c}
 }
 
+// Flush implements the Flusher interface.
+func (this backendClose) Flush() error {
+	return FlushBackend(this.Backend)
+}
+
+func (this backendClose) wrappedBackends() []Backend {
+	return []Backend{this.Backend}
+}
+
 func (this backendClose) Close() error {
 	if this.Closer != nil {
 		return this.Closer.Close()
@@ -85,7 +188,7 @@ func (this backendClose) Close() error {
 }
 
 // SetBackend replaces the backend currently set with the given new logging
-// backend.
+// backend. It's safe to call while other goroutines are logging.
 func SetBackend(backends ...Backend) LeveledBackend {
 	var backend Backend
 	if len(backends) == 1 {
@@ -94,19 +197,35 @@ func SetBackend(backends ...Backend) LeveledBackend {
 		backend = MultiLogger(backends...)
 	}
 
-	defaultBackend = AddModuleLevel(backend)
-	return defaultBackend
+	leveled := AddModuleLevel(backend)
+	defaultBackend.Store(backendBox{leveled})
+	invalidateLevels()
+	return leveled
+}
+
+// GetBackend returns the backend currently set.
+func GetBackend() LeveledBackend {
+	return getDefaultBackend()
 }
 
 // SetLevel sets the logging level for the specified module. The module
 // corresponds to the string specified in GetOrCreateLogger.
 func SetLevel(level Level, module string) {
-	defaultBackend.SetLevel(level, module)
+	getDefaultBackend().SetLevel(level, module)
 }
 
 // GetLevel returns the logging level for the specified module.
 func GetLevel(module string) Level {
-	return defaultBackend.GetLevel(module)
+	return getDefaultBackend().GetLevel(module)
+}
+
+// LevelMap returns the module levels configured in the default backend, or nil
+// if it doesn't implement LevelMapper.
+func LevelMap() map[string]Level {
+	if m, ok := getDefaultBackend().(LevelMapper); ok {
+		return m.LevelMap()
+	}
+	return nil
 }
 
 // SetLogLevel sets the logging level for the specified module in Log.
@@ -115,7 +234,7 @@ func SetLogLevel(log Logger, level Level, module string) {
 		backend.SetLevel(level, module)
 		return
 	}
-	defaultBackend.SetLevel(level, module)
+	getDefaultBackend().SetLevel(level, module)
 }
 
 // GetLogLevel returns the logging level for the specified module in Log.
@@ -123,13 +242,11 @@ func GetLogLevel(log Logger, module string) Level {
 	if backend := log.Backend(); backend != nil {
 		return backend.GetLevel(module)
 	}
-	return defaultBackend.GetLevel(module)
+	return getDefaultBackend().GetLevel(module)
 }
 
 func DefaultBackendProxy() LeveledBackend {
-	return &LeveledBackendProxy{func() LeveledBackend {
-		return defaultBackend
-	}}
+	return &LeveledBackendProxy{getDefaultBackend}
 }
 
 type LeveledBackendProxy struct {
@@ -152,6 +269,23 @@ func (this LeveledBackendProxy) SetLevel(level Level, module string) {
 	this.Get().SetLevel(level, module)
 }
 
+// LevelMap implements the LevelMapper interface if the proxied backend does.
+func (this LeveledBackendProxy) LevelMap() map[string]Level {
+	if m, ok := this.Get().(LevelMapper); ok {
+		return m.LevelMap()
+	}
+	return nil
+}
+
 func (this LeveledBackendProxy) IsEnabledFor(level Level, module string) bool {
 	return this.Get().IsEnabledFor(level, module)
 }
+
+// Flush implements the Flusher interface.
+func (this LeveledBackendProxy) Flush() error {
+	return FlushBackend(this.Get())
+}
+
+func (this LeveledBackendProxy) wrappedBackends() []Backend {
+	return []Backend{this.Get()}
+}
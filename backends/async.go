@@ -0,0 +1,293 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// DefaultQueueSize is the default size of the async backends queue.
+const DefaultQueueSize = 1024
+
+// ErrFlushTimeout is returned when the pending writes were not done before the
+// timeout.
+var ErrFlushTimeout = errors.New("backends: flush timeout")
+
+// AsyncError is an error of an async write, reported to the error handler.
+type AsyncError struct {
+	// Backend is the type and the name of the backend, eg. `file "app.log"`.
+	Backend string
+	Err     error
+}
+
+func (this *AsyncError) Error() string {
+	return this.Backend + " failed: " + this.Err.Error()
+}
+
+// asyncWorker executes functions on a bounded queue consumed by a fixed number
+// of goroutines. The functions doing I/O should use the worker context, which
+// is canceled when the worker closes before they are done.
+type asyncWorker struct {
+	// dropped is the number of functions dropped by the overflow policy.
+	dropped  uint64
+	queue    chan func()
+	overflow logging.OverflowPolicy
+	mu       sync.Mutex
+	cond     *sync.Cond
+	pending  int
+	// finished is the number of functions done or dropped.
+	finished uint64
+	closed   bool
+	stopWg   sync.WaitGroup
+	report   chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+func newAsyncWorker(size, workers int, overflow logging.OverflowPolicy) *asyncWorker {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	w := &asyncWorker{queue: make(chan func(), size), overflow: overflow}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	w.cond = sync.NewCond(&w.mu)
+	w.stopWg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go w.run()
+	}
+	return w
+}
+
+func (w *asyncWorker) run() {
+	defer w.stopWg.Done()
+	for f := range w.queue {
+		f()
+		w.done()
+	}
+}
+
+// done marks an enqueued function as done or dropped.
+func (w *asyncWorker) done() {
+	w.mu.Lock()
+	w.pending--
+	w.finished++
+	w.cond.Broadcast()
+	w.mu.Unlock()
+}
+
+func (w *asyncWorker) drop() {
+	atomic.AddUint64(&w.dropped, 1)
+	w.done()
+}
+
+// Do enqueues f. When the queue is full, it blocks or drops a function
+// according to the overflow policy. It returns false if the worker was closed.
+func (w *asyncWorker) Do(f func()) bool {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return false
+	}
+	w.pending++
+	w.mu.Unlock()
+
+	switch w.overflow {
+	case logging.OverflowDropNewest:
+		select {
+		case w.queue <- f:
+		default:
+			w.drop()
+		}
+	case logging.OverflowDropOldest:
+		for {
+			select {
+			case w.queue <- f:
+				return true
+			default:
+			}
+			select {
+			case <-w.queue:
+				w.drop()
+			default:
+			}
+		}
+	default:
+		w.queue <- f
+	}
+	return true
+}
+
+// Dropped returns the number of functions dropped by the overflow policy.
+func (w *asyncWorker) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// ReportDropped calls report, on each interval, with the number of functions
+// dropped since the previous call, if any. It stops when the worker is
+// closed.
+func (w *asyncWorker) ReportDropped(interval time.Duration, report func(n uint64)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed || w.report != nil {
+		return
+	}
+	w.report = make(chan struct{})
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var last uint64
+		for {
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+			if n := w.Dropped(); n > last {
+				report(n - last)
+				last = n
+			}
+		}
+	}(w.report)
+}
+
+// Later enqueues f after the delay d. It must be called from a function
+// running in the worker, so the pending function is also waited by Flush and
+// Close.
+func (w *asyncWorker) Later(d time.Duration, f func()) {
+	w.mu.Lock()
+	w.pending++
+	w.mu.Unlock()
+	time.AfterFunc(d, func() {
+		w.queue <- f
+	})
+}
+
+// Context returns the context of the worker, canceled when it closes before
+// the pending functions are done.
+func (w *asyncWorker) Context() context.Context {
+	return w.ctx
+}
+
+// Flush waits until all enqueued functions are done. If timeout is positive
+// and expires before, returns ErrFlushTimeout.
+func (w *asyncWorker) Flush(timeout time.Duration) error {
+	if timeout <= 0 {
+		return w.FlushContext(context.Background())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if w.FlushContext(ctx) != nil {
+		return ErrFlushTimeout
+	}
+	return nil
+}
+
+// FlushQueued waits until as many functions as were pending when it was called
+// are done or dropped. Unlike Flush, it doesn't wait for the functions
+// enqueued later, which may keep the worker busy indefinitely.
+func (w *asyncWorker) FlushQueued() {
+	w.mu.Lock()
+	for target := w.finished + uint64(w.pending); w.finished < target; {
+		w.cond.Wait()
+	}
+	w.mu.Unlock()
+}
+
+// FlushContext waits until all enqueued functions are done or ctx is done,
+// returning ctx.Err().
+func (w *asyncWorker) FlushContext(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		w.mu.Lock()
+		for w.pending > 0 {
+			w.cond.Wait()
+		}
+		w.mu.Unlock()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close rejects new functions, waits for the pending ones and stops the
+// goroutines. If timeout is positive and expires before, the worker context
+// is canceled and ErrFlushTimeout is returned.
+func (w *asyncWorker) Close(timeout time.Duration) error {
+	return w.close(func() error {
+		return w.Flush(timeout)
+	})
+}
+
+// CloseContext is like Close, but waits for the pending functions until ctx is
+// done, returning ctx.Err().
+func (w *asyncWorker) CloseContext(ctx context.Context) error {
+	return w.close(func() error {
+		return w.FlushContext(ctx)
+	})
+}
+
+// close rejects new functions and waits for the pending ones using flush. If
+// they are done, the goroutines are stopped, else the worker context is
+// canceled, aborting the running functions.
+func (w *asyncWorker) close(flush func() error) (err error) {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	if w.report != nil {
+		close(w.report)
+	}
+	w.mu.Unlock()
+
+	if err = flush(); err == nil {
+		close(w.queue)
+		w.stopWg.Wait()
+	}
+	w.cancel()
+	return
+}
+
+// handleAsyncError sends err to the default logging error handler or, if
+// there isn't one, logs it using logger.
+func handleAsyncError(logger logging.Logger, err error, format string, args ...interface{}) {
+	if !logging.HandleError(err) {
+		logger.Errorf(format+": %s", append(args, err.Error())...)
+	}
+}
+
+// registry contains the backends closed by CloseAll.
+var registry sync.Map
+
+func register(c io.Closer) {
+	registry.Store(c, true)
+}
+
+func unregister(c io.Closer) {
+	registry.Delete(c)
+}
+
+// CloseAll flushes and closes all backends created by this package. It can be
+// called from a signal handler before the application exits.
+func CloseAll() (err error) {
+	registry.Range(func(key, _ interface{}) bool {
+		if e := key.(io.Closer).Close(); e != nil && err == nil {
+			err = e
+		}
+		return true
+	})
+	return
+}
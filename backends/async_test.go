@@ -0,0 +1,261 @@
+package backends
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+func TestAsyncFileBackendClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "async.log")
+	b, err := NewFileBackend(path, FileOptions{Async: true, QueueSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := logging.NewLogger("async")
+	log.SetBackend(logging.AddModuleLevel(b))
+	for i := 0; i < 100; i++ {
+		log.Info("line")
+	}
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "line\n"); n != 100 {
+		t.Errorf("expected 100 lines, got %d", n)
+	}
+}
+
+func TestAsyncFileBackendOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "async.log")
+	b, err := NewFileBackend(path, FileOptions{Async: true, QueueSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := logging.NewLogger("async")
+	log.SetBackend(logging.AddModuleLevel(b))
+	for i := 0; i < 500; i++ {
+		log.Infof("line %d", i)
+	}
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 500 {
+		t.Fatalf("%d lines written", len(lines))
+	}
+	for i, line := range lines {
+		if expected := fmt.Sprintf("line %d", i); !strings.HasSuffix(line, expected) {
+			t.Fatalf("%q written instead of %q", line, expected)
+		}
+	}
+}
+func TestAsyncHttpBackendCloseAll(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&count, 1)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := NewHttpBackend(*u, HttpOptions{Async: true, Workers: 2}, nil)
+
+	log := logging.NewLogger("async-http")
+	log.SetBackend(logging.AddModuleLevel(b))
+	for i := 0; i < 20; i++ {
+		log.Info("line")
+	}
+	if err := CloseAll(); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&count); n != 20 {
+		t.Errorf("expected 20 requests, got %d", n)
+	}
+}
+
+func TestAsyncHttpBackendCloseContext(t *testing.T) {
+	canceled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the connection is watched after the body is read
+		ioutil.ReadAll(r.Body)
+		<-r.Context().Done()
+		close(canceled)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := NewHttpBackend(*u, HttpOptions{Async: true, Timeout: 10, MaxRetries: 3}, nil)
+
+	log := logging.NewLogger("async-http")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info("hanging")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := b.CloseContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline error, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("close took %s", d)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Errorf("request not canceled")
+	}
+}
+
+func TestAsyncWorkerCloseTimeout(t *testing.T) {
+	w := newAsyncWorker(1, 1, logging.OverflowBlock)
+	release := make(chan struct{})
+	w.Do(func() { <-release })
+	if err := w.Close(time.Millisecond); err != ErrFlushTimeout {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	close(release)
+	if w.Do(func() {}) {
+		t.Errorf("closed worker accepted a new function")
+	}
+}
+
+func TestAsyncWorkerOverflow(t *testing.T) {
+	for _, test := range []struct {
+		overflow logging.OverflowPolicy
+		expected string
+	}{
+		{logging.OverflowDropNewest, "012"},
+		{logging.OverflowDropOldest, "045"},
+	} {
+		w := newAsyncWorker(2, 1, test.overflow)
+		release := make(chan struct{})
+		started := make(chan struct{})
+		var got []byte
+		w.Do(func() {
+			close(started)
+			<-release
+			got = append(got, '0')
+		})
+		<-started
+		for i := byte('1'); i <= '5'; i++ {
+			c := i
+			w.Do(func() { got = append(got, c) })
+		}
+		if n := w.Dropped(); n != 3 {
+			t.Errorf("%d: expected 3 dropped, got %d", test.overflow, n)
+		}
+		close(release)
+		w.Close(0)
+		if string(got) != test.expected {
+			t.Errorf("%d: expected %q, got %q", test.overflow, test.expected, got)
+		}
+	}
+}
+
+func TestAsyncWorkerReportDropped(t *testing.T) {
+	w := newAsyncWorker(1, 1, logging.OverflowDropNewest)
+	reports := make(chan uint64, 1)
+	w.ReportDropped(time.Millisecond, func(n uint64) {
+		reports <- n
+	})
+	release := make(chan struct{})
+	started := make(chan struct{})
+	w.Do(func() {
+		close(started)
+		<-release
+	})
+	<-started
+	for i := 0; i < 3; i++ {
+		w.Do(func() {})
+	}
+	select {
+	case n := <-reports:
+		if n != 2 {
+			t.Errorf("expected 2 dropped, got %d", n)
+		}
+	case <-time.After(time.Second):
+		t.Error("dropped records not reported")
+	}
+	close(release)
+	w.Close(0)
+}
+
+func TestAsyncWorkerFlushQueued(t *testing.T) {
+	w := newAsyncWorker(4, 1, logging.OverflowBlock)
+	defer w.Close(0)
+
+	var done int32
+	for i := 0; i < 4; i++ {
+		w.Do(func() {
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&done, 1)
+		})
+	}
+	// keeps the worker busy after FlushQueued is called
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				w.Do(func() { time.Sleep(100 * time.Microsecond) })
+			}
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-stopped
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	flushed := make(chan struct{})
+	go func() {
+		w.FlushQueued()
+		close(flushed)
+	}()
+	select {
+	case <-flushed:
+		if n := atomic.LoadInt32(&done); n != 4 {
+			t.Errorf("expected 4 functions done, got %d", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("FlushQueued waited for the functions enqueued after it")
+	}
+}
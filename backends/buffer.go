@@ -0,0 +1,146 @@
+package backends
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// DefaultBufferSize is the buffer size of the buffered file backends.
+const DefaultBufferSize = 32 * 1024
+
+// bufferedWriter buffers the writes into w. The buffer is written when full,
+// on each flush interval and when a record at or above the flush level is
+// logged. Each Write is kept whole, so a record is never split between two
+// writes of w.
+type bufferedWriter struct {
+	w     io.WriteCloser
+	level logging.Level
+	// syncLevel is the sync level of w, whose records are flushed before
+	// syncing.
+	syncLevel logging.Level
+
+	mu       sync.Mutex
+	buf      *bufio.Writer
+	stop     chan struct{}
+	stopOnce sync.Once
+	stopped  sync.WaitGroup
+}
+
+// newBufferedWriter wraps w with a buffer if options.Buffered is set.
+func newBufferedWriter(w io.WriteCloser, options FileOptions) io.WriteCloser {
+	if !options.Buffered {
+		return w
+	}
+	if options.BufferSize <= 0 {
+		options.BufferSize = DefaultBufferSize
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = time.Second
+	}
+	b := &bufferedWriter{
+		w:         w,
+		level:     options.FlushLevel,
+		syncLevel: options.SyncOnLevel,
+		buf:       bufio.NewWriterSize(w, options.BufferSize),
+		stop:      make(chan struct{}),
+	}
+	b.stopped.Add(1)
+	go b.run(options.FlushInterval)
+	return b
+}
+
+// run flushes the buffer on each interval until the writer is closed.
+func (this *bufferedWriter) run(interval time.Duration) {
+	defer this.stopped.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := this.Flush(); err != nil {
+				handleAsyncError(log_, err, "buffer flush failed")
+			}
+		case <-this.stop:
+			return
+		}
+	}
+}
+
+// Write implements io.Writer.
+func (this *bufferedWriter) Write(p []byte) (n int, err error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.buf.Buffered() > 0 && len(p) > this.buf.Available() {
+		if err = this.buf.Flush(); err != nil {
+			return
+		}
+	}
+	return this.buf.Write(p)
+}
+
+// Flush writes the buffered data into the underlying writer.
+func (this *bufferedWriter) Flush() error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.buf.Flush()
+}
+
+// logged flushes the buffer if level is at or above the flush or the sync
+// level, and notifies the underlying writer.
+func (this *bufferedWriter) logged(level logging.Level) error {
+	if level <= this.level || level <= this.syncLevel {
+		if err := this.Flush(); err != nil {
+			return err
+		}
+	}
+	if w, ok := this.w.(leveledWriter); ok {
+		return w.logged(level)
+	}
+	return nil
+}
+
+// Sync flushes the buffer and syncs the underlying writer if it supports it.
+func (this *bufferedWriter) Sync() error {
+	if err := this.Flush(); err != nil {
+		return err
+	}
+	if s, ok := this.w.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Reopen flushes the buffer and reopens the underlying writer.
+func (this *bufferedWriter) Reopen() error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if err := this.buf.Flush(); err != nil {
+		// Discard the data and the error kept by the buffer.
+		this.buf.Reset(this.w)
+	}
+	if r, ok := this.w.(reopener); ok {
+		return r.Reopen()
+	}
+	return nil
+}
+
+// Close stops the periodic flush, flushes the buffer and closes the
+// underlying writer.
+func (this *bufferedWriter) Close() (err error) {
+	this.stopOnce.Do(func() {
+		close(this.stop)
+	})
+	this.stopped.Wait()
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	err = this.buf.Flush()
+	if e := this.w.Close(); err == nil {
+		err = e
+	}
+	return
+}
@@ -0,0 +1,82 @@
+package backends
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+func TestBufferedFileBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "buffered.log")
+	b, err := NewFileBackend(path, FileOptions{Buffered: true, FlushInterval: time.Hour, FlushLevel: logging.ERROR})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := func() int {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Count(string(data), "\n")
+	}
+
+	log := logging.NewLogger("buffered")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info("a")
+	log.Warning("b")
+	if n := count(); n != 0 {
+		t.Errorf("expected buffered lines, got %d lines", n)
+	}
+	log.Error("c")
+	if n := count(); n != 3 {
+		t.Errorf("expected 3 lines after error, got %d", n)
+	}
+	log.Info("d")
+	if err = log.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 4 {
+		t.Errorf("expected 4 lines after logger flush, got %d", n)
+	}
+	log.Info("e")
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 5 {
+		t.Errorf("expected 5 lines after close, got %d", n)
+	}
+}
+
+func TestBufferedWriterFlushInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "interval.log")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := newBufferedWriter(f, FileOptions{Buffered: true, FlushInterval: 5 * time.Millisecond})
+	defer w.Close()
+
+	w.Write([]byte("line\n"))
+	time.Sleep(50 * time.Millisecond)
+	if data, _ := ioutil.ReadFile(path); string(data) != "line\n" {
+		t.Errorf("expected flushed line, got %q", data)
+	}
+}
@@ -0,0 +1,199 @@
+package backends
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+const (
+	// DefaultElasticIndex is the index used when ElasticOptions.Index is
+	// empty.
+	DefaultElasticIndex = "logs-%{2006.01.02}"
+	// DefaultElasticBatchSize is the number of records sent in each bulk
+	// request when ElasticOptions.BatchSize is zero.
+	DefaultElasticBatchSize = 500
+)
+
+// ElasticOptions configures NewElasticBackend.
+type ElasticOptions struct {
+	// HttpOptions configures the requests. The records are always sent in
+	// batches of BatchSize, which defaults to DefaultElasticBatchSize, and
+	// HttpGet and Formatted are ignored.
+	HttpOptions
+
+	// URL of the Elasticsearch or OpenSearch cluster, eg.
+	// "http://localhost:9200".
+	URL string
+	// Index is the name of the index. Each %{layout} placeholder in it is
+	// replaced by the UTC time of the record formatted with the time layout,
+	// so "logs-%{2006.01.02}" creates an index per day. Defaults to
+	// DefaultElasticIndex.
+	Index string
+	// Username and Password enable the basic authentication.
+	Username string
+	Password string
+	// APIKey is the base64 encoded API key, sent instead of the basic
+	// authentication.
+	APIKey string
+}
+
+// ElasticBackend indexes the records into Elasticsearch or OpenSearch using the
+// bulk API.
+type ElasticBackend struct {
+	*HttpBackend
+	Index string
+}
+
+// elasticDocument is the document indexed for each record.
+type elasticDocument struct {
+	Timestamp time.Time `json:"@timestamp"`
+	logging.RecordData
+	File string `json:",omitempty"`
+	Line int    `json:",omitempty"`
+	Func string `json:",omitempty"`
+}
+
+// elasticBulkResponse is the response of the bulk API. Each item has the
+// result of the action, keyed by the action name.
+type elasticBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Index  string          `json:"_index"`
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// NewElasticBackend creates a backend which sends the records in batches to
+// the _bulk endpoint of opts.URL. The batch is sent when full, on each
+// FlushInterval and on Flush or Close. The items rejected by the cluster are
+// reported to the error handler.
+func NewElasticBackend(opts ElasticOptions) (b *ElasticBackend, err error) {
+	var u *url.URL
+	if u, err = url.Parse(opts.URL); err != nil {
+		return
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/_bulk"
+
+	if opts.Index == "" {
+		opts.Index = DefaultElasticIndex
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultElasticBatchSize
+	}
+
+	headers := http.Header{}
+	for key, values := range opts.Headers {
+		headers[key] = values
+	}
+	if opts.APIKey != "" {
+		headers.Set("Authorization", "ApiKey "+opts.APIKey)
+	} else if opts.Username != "" {
+		headers.Set("Authorization", basicAuth(opts.Username, opts.Password))
+	}
+
+	httpOpts := opts.HttpOptions
+	httpOpts.HttpGet, httpOpts.Formatted, httpOpts.BatchSize = false, false, 0
+	httpOpts.Headers = headers
+
+	b = &ElasticBackend{NewHttpBackend(*u, httpOpts, nil), opts.Index}
+	b.Logger = logging.WithPrefix(log_, "elastic")
+	b.batch = newHttpBatchEncoder(b.HttpBackend, opts.BatchSize, opts.FlushInterval, b.encode, b.request)
+	return
+}
+
+// encode returns the action and the document of the record, as two NDJSON
+// lines.
+func (this *ElasticBackend) encode(calldepth int, rec *logging.Record) (item []byte, err error) {
+	doc := elasticDocument{Timestamp: rec.Time, RecordData: rec.Data()}
+	file, line, fn, ok := rec.Caller()
+	if !ok {
+		var pc uintptr
+		if pc, file, line, ok = runtime.Caller(calldepth + 1); ok {
+			if f := runtime.FuncForPC(pc); f != nil {
+				fn = f.Name()
+			}
+		}
+	}
+	if ok {
+		doc.File, doc.Line, doc.Func = filepath.Base(file), line, fn
+	}
+
+	var action, data []byte
+	if action, err = json.Marshal(map[string]interface{}{
+		"index": map[string]string{"_index": elasticIndex(this.Index, rec.Time.UTC())},
+	}); err != nil {
+		return
+	}
+	if data, err = json.Marshal(doc); err != nil {
+		return
+	}
+	return append(append(action, '\n'), data...), nil
+}
+
+var elasticIndexRe = regexp.MustCompile(`%{([^}]*)}`)
+
+// elasticIndex returns index with the %{layout} placeholders replaced by t
+// formatted with their layout. The rest of the index is kept as is.
+func elasticIndex(index string, t time.Time) string {
+	if !strings.Contains(index, "%{") {
+		return index
+	}
+	return elasticIndexRe.ReplaceAllStringFunc(index, func(placeholder string) string {
+		return t.Format(placeholder[2 : len(placeholder)-1])
+	})
+}
+
+// request returns the bulk request of the items.
+func (this *ElasticBackend) request(items []json.RawMessage) (*httpRequest, error) {
+	var body bytes.Buffer
+	for _, item := range items {
+		body.Write(item)
+		body.WriteByte('\n')
+	}
+	return &httpRequest{
+		method:      http.MethodPost,
+		url:         this.URL.String(),
+		body:        body.Bytes(),
+		contentType: "application/x-ndjson",
+		response:    this.response,
+	}, nil
+}
+
+// response reports the items which failed.
+func (this *ElasticBackend) response(body io.Reader) {
+	var resp elasticBulkResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		handleAsyncError(this.Logger, err, "%q invalid response", this.URL.String())
+		return
+	}
+	if !resp.Errors {
+		return
+	}
+	for _, item := range resp.Items {
+		for action, result := range item {
+			if len(result.Error) == 0 || string(result.Error) == "null" {
+				continue
+			}
+			err := fmt.Errorf("%s into %q failed with status %d: %s", action, result.Index, result.Status, result.Error)
+			handleAsyncError(this.Logger, err, "%q bulk item failed", this.URL.String())
+		}
+	}
+}
+
+// Print implements the Printer interface. The values are indexed as an INFO
+// record.
+func (this *ElasticBackend) Print(args ...interface{}) error {
+	return this.batch.add(1, &logging.Record{Time: time.Now(), Level: logging.INFO, Args: args})
+}
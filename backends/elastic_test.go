@@ -0,0 +1,114 @@
+package backends
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+func TestElasticBackend(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		actions []map[string]map[string]string
+		docs    []map[string]interface{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("unexpected content type %q", ct)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "bob" || pass != "secret" {
+			t.Errorf("unexpected auth %q", r.Header.Get("Authorization"))
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var action map[string]map[string]string
+			json.Unmarshal(scanner.Bytes(), &action)
+			actions = append(actions, action)
+			if !scanner.Scan() {
+				t.Fatal("action without document")
+			}
+			var doc map[string]interface{}
+			json.Unmarshal(scanner.Bytes(), &doc)
+			docs = append(docs, doc)
+		}
+		w.Write([]byte(`{"errors":true,"items":[
+			{"index":{"_index":"app-x","status":201}},
+			{"index":{"_index":"app-x","status":400,"error":{"type":"mapper_parsing_exception"}}}
+		]}`))
+	}))
+	defer server.Close()
+
+	var errs []error
+	logging.SetErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+	defer logging.SetErrorHandler(nil)
+
+	b, err := NewElasticBackend(ElasticOptions{
+		HttpOptions: HttpOptions{BatchSize: 2, FlushInterval: time.Hour},
+		URL:         server.URL + "/",
+		Index:       "app2-v1-%{2006.01.02}",
+		Username:    "bob",
+		Password:    "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := logging.NewLogger("elastic")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info("first")
+	log.WithFields("user", "bob").Warning("second")
+	log.Error("third")
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(docs) != 3 {
+		t.Fatalf("unexpected documents: %v", docs)
+	}
+	index := "app2-v1-" + time.Now().UTC().Format("2006.01.02")
+	for i, msg := range []string{"first", "second", "third"} {
+		if got := actions[i]["index"]["_index"]; got != index {
+			t.Errorf("unexpected index %q", got)
+		}
+		if docs[i]["Message"] != msg {
+			t.Errorf("unexpected message %v", docs[i]["Message"])
+		}
+		if docs[i]["File"] != "elastic_test.go" || docs[i]["@timestamp"] == nil {
+			t.Errorf("unexpected document %v", docs[i])
+		}
+	}
+	if fields, _ := docs[1]["Fields"].(map[string]interface{}); fields["user"] != "bob" {
+		t.Errorf("unexpected fields %v", docs[1]["Fields"])
+	}
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "mapper_parsing_exception") {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestElasticIndex(t *testing.T) {
+	now := time.Date(2020, 10, 5, 0, 0, 0, 0, time.UTC)
+	for index, expected := range map[string]string{
+		"logs-v1":                     "logs-v1",
+		"app2":                        "app2",
+		"app2-%{2006.01.02}":          "app2-2020.10.05",
+		"logs-v1-%{2006}-%{01}.%{02}": "logs-v1-2020-10.05",
+	} {
+		if got := elasticIndex(index, now); got != expected {
+			t.Errorf("%s: %q != %q", index, got, expected)
+		}
+	}
+}
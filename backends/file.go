@@ -6,7 +6,9 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	path_helpers "github.com/moisespsena-go/path-helpers"
 
@@ -19,6 +21,63 @@ type FileOptions struct {
 	Async    bool
 	Truncate bool
 	Perm     os.FileMode
+	// QueueSize is the size of the async queue. Defaults to DefaultQueueSize.
+	QueueSize int
+	// Workers is the number of goroutines writing the async records. The
+	// default, 1, writes the records in the order they were logged. More
+	// workers trade the ordering for throughput.
+	Workers int
+	// Overflow is the policy used when the async queue is full. Defaults to
+	// block until the queue has room.
+	Overflow logging.OverflowPolicy
+	// DroppedReportInterval enables a periodic warning, in the package logger,
+	// with the number of records dropped by the Overflow policy.
+	DroppedReportInterval time.Duration
+
+	// Buffered buffers the writes into the file, reducing the number of
+	// syscalls. The buffer is written when full, every FlushInterval, when a
+	// record at or above FlushLevel is logged and on Close.
+	Buffered bool
+	// BufferSize defaults to DefaultBufferSize.
+	BufferSize int
+	// FlushInterval is the maximum time the records are kept in the buffer,
+	// so it's what a crash can lose. Defaults to one second.
+	FlushInterval time.Duration
+	// FlushLevel is the least severe level which flushes the buffer
+	// immediately. Defaults to CRITICAL.
+	FlushLevel logging.Level
+
+	// SyncOnLevel is the least severe level which commits the file to the
+	// stable storage, using fsync, after writing the record. Defaults to
+	// CRITICAL. See also FileBackend.Sync.
+	SyncOnLevel logging.Level
+
+	// FormattedPrint makes Print write the values as an INFO record,
+	// formatted like the logged ones, instead of the raw values. See
+	// FileBackend.Print.
+	FormattedPrint bool
+	// PrintFormatter formats the printed values of the FormattedPrint mode.
+	// Defaults to the default formatter, see logging.SetFormatter.
+	PrintFormatter logging.Formatter
+}
+
+// equal reports whether the options are the same. The PrintFormatter isn't
+// compared, as the formatters may not be comparable, and equivalent ones are
+// usually built on each call.
+func (this FileOptions) equal(other FileOptions) bool {
+	return this.Async == other.Async &&
+		this.Truncate == other.Truncate &&
+		this.Perm == other.Perm &&
+		this.QueueSize == other.QueueSize &&
+		this.Workers == other.Workers &&
+		this.Overflow == other.Overflow &&
+		this.DroppedReportInterval == other.DroppedReportInterval &&
+		this.Buffered == other.Buffered &&
+		this.BufferSize == other.BufferSize &&
+		this.FlushInterval == other.FlushInterval &&
+		this.FlushLevel == other.FlushLevel &&
+		this.SyncOnLevel == other.SyncOnLevel &&
+		this.FormattedPrint == other.FormattedPrint
 }
 
 type WriteCloserBackend struct {
@@ -26,37 +85,257 @@ type WriteCloserBackend struct {
 	logging.Backend
 	Name  string
 	Async bool
+	// QueueSize is the size of the async queue. Defaults to DefaultQueueSize.
+	QueueSize int
+	// Workers is the number of goroutines writing the async records. Defaults
+	// to 1, which keeps the records ordered.
+	Workers int
+	// Overflow is the policy used when the async queue is full.
+	Overflow logging.OverflowPolicy
+	// DroppedReportInterval enables a periodic warning with the number of
+	// dropped records.
+	DroppedReportInterval time.Duration
+	// ErrorHandler receives the errors of the async writes, as *AsyncError.
+	// Defaults to the logging error handler or, if there isn't one, the
+	// package logger.
+	ErrorHandler logging.ErrorHandler
+
+	// kind is the type of the backend used in the error messages, eg. "file".
+	kind     string
+	workerMu sync.Mutex
+	worker   *asyncWorker
 }
 
 func NewWriteCloserBackend(name string, wc io.WriteCloser, async bool) *WriteCloserBackend {
-	return &WriteCloserBackend{
+	return newWriteCloserBackend("write_closer", name, wc, async)
+}
+
+func newWriteCloserBackend(kind, name string, wc io.WriteCloser, async bool) *WriteCloserBackend {
+	b := &WriteCloserBackend{
 		WriteCloser: wc,
 		Name:        name,
 		Backend:     logging.NewLogBackend(wc, "", log.LstdFlags),
 		Async:       async,
+		kind:        kind,
 	}
+	register(b)
+	return b
+}
+
+// label returns the type and the name of the backend, eg. `file "app.log"`.
+func (this *WriteCloserBackend) label() string {
+	return fmt.Sprintf("%s %q", this.kind, strings.TrimPrefix(this.Name, this.kind+":"))
 }
 
+// handleError reports an async write error.
+func (this *WriteCloserBackend) handleError(err error) {
+	err = &AsyncError{Backend: this.label(), Err: err}
+	if this.ErrorHandler != nil {
+		this.ErrorHandler(err)
+	} else if !logging.HandleError(err) {
+		log_.Error(err.Error())
+	}
+}
+
+func (this *WriteCloserBackend) getWorker(create bool) *asyncWorker {
+	this.workerMu.Lock()
+	defer this.workerMu.Unlock()
+	if this.worker == nil && create {
+		this.worker = newAsyncWorker(this.QueueSize, this.Workers, this.Overflow)
+		if this.DroppedReportInterval > 0 {
+			this.worker.ReportDropped(this.DroppedReportInterval, func(n uint64) {
+				log_.Warningf("%s dropped %d records", this.label(), n)
+			})
+		}
+	}
+	return this.worker
+}
+
+// Log implements the logging.Backend interface. The synchronous records, see
+// logging.SyncLogger, are written after the pending async ones, and flushed.
 func (this *WriteCloserBackend) Log(level logging.Level, calldepth int, rec *logging.Record) (err error) {
+	if rec.IsSync() {
+		return this.logSync(level, calldepth+1, rec)
+	}
 	if this.Async {
-		go func() {
-			r := *rec
-			if err := this.Backend.Log(level, calldepth, &r); err != nil {
-				log_.Errorf("write_closer %q failed: %s", this.Name, err.Error())
+		r := rec.Clone()
+		this.getWorker(true).Do(func() {
+			if err := this.log(level, calldepth, r); err != nil {
+				this.handleError(err)
 			}
-		}()
+		})
 		return
 	}
-	return this.Backend.Log(level, calldepth, rec)
+	return this.log(level, calldepth, rec)
+}
+
+// logSync writes the pending async records, then rec, and flushes the buffer.
+func (this *WriteCloserBackend) logSync(level logging.Level, calldepth int, rec *logging.Record) (err error) {
+	if worker := this.getWorker(false); worker != nil {
+		worker.FlushQueued()
+	}
+	if err = this.log(level, calldepth+1, rec); err != nil {
+		return
+	}
+	if b, ok := this.WriteCloser.(*bufferedWriter); ok {
+		err = b.Flush()
+	}
+	return
+}
+
+func (this *WriteCloserBackend) log(level logging.Level, calldepth int, rec *logging.Record) (err error) {
+	if err = this.Backend.Log(level, calldepth+1, rec); err != nil {
+		return
+	}
+	if w, ok := this.WriteCloser.(leveledWriter); ok {
+		err = w.logged(level)
+	}
+	return
+}
+
+// PrintLevel implements the logging.LevelPrinter interface. Unlike Print, the
+// values are formatted and written as a record of level.
+func (this *WriteCloserBackend) PrintLevel(level logging.Level, args ...interface{}) error {
+	return this.Log(level, 1, logging.PrintRecord(level, args...))
+}
+
+// Dropped returns the number of async records dropped by the Overflow policy.
+func (this *WriteCloserBackend) Dropped() uint64 {
+	if worker := this.getWorker(false); worker != nil {
+		return worker.Dropped()
+	}
+	return 0
+}
+
+// Flush waits until all pending async records are written and writes the
+// buffered data.
+func (this *WriteCloserBackend) Flush() error {
+	if worker := this.getWorker(false); worker != nil {
+		if err := worker.Flush(0); err != nil {
+			return err
+		}
+	}
+	if b, ok := this.WriteCloser.(*bufferedWriter); ok {
+		return b.Flush()
+	}
+	return nil
+}
+
+// Sync writes the pending async records and the buffered data, and commits
+// them to the stable storage if the writer supports it, like the files.
+func (this *WriteCloserBackend) Sync() error {
+	if err := this.Flush(); err != nil {
+		return err
+	}
+	if s, ok := this.WriteCloser.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
 }
 
+// Close writes the pending async records and closes the writer.
 func (this *WriteCloserBackend) Close() error {
+	unregister(this)
+	if worker := this.getWorker(false); worker != nil {
+		worker.Close(0)
+	}
 	if this.WriteCloser != nil {
 		return this.WriteCloser.Close()
 	}
 	return nil
 }
 
+// Reopen closes and reopens the writer if it supports reopening, like the
+// file backends. See ReopenAll.
+func (this *WriteCloserBackend) Reopen() error {
+	if r, ok := this.WriteCloser.(reopener); ok {
+		return r.Reopen()
+	}
+	return fmt.Errorf("%s does not support reopen", this.label())
+}
+
+type reopener interface {
+	Reopen() error
+}
+
+type syncer interface {
+	Sync() error
+}
+
+// leveledWriter is a writer which is notified of the level of each record
+// written, eg. to flush or sync it.
+type leveledWriter interface {
+	logged(level logging.Level) error
+}
+
+// reopenableFile is a file which can be reopened while other goroutines are
+// writing into it.
+type reopenableFile struct {
+	path      string
+	perm      os.FileMode
+	syncLevel logging.Level
+	mu        sync.Mutex
+	f         *os.File
+	closed    bool
+}
+
+func (this *reopenableFile) Write(p []byte) (n int, err error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.f == nil {
+		return 0, os.ErrClosed
+	}
+	return this.f.Write(p)
+}
+
+// Reopen closes the current file and opens the path again.
+func (this *reopenableFile) Reopen() (err error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.closed {
+		return os.ErrClosed
+	}
+	if this.f != nil {
+		this.f.Close()
+		this.f = nil
+	}
+	this.f, err = os.OpenFile(this.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, this.perm)
+	return
+}
+
+// Sync commits the file to the stable storage.
+func (this *reopenableFile) Sync() error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.f == nil {
+		return os.ErrClosed
+	}
+	return this.f.Sync()
+}
+
+// logged syncs the file if level is at or above the sync level.
+func (this *reopenableFile) logged(level logging.Level) error {
+	if level <= this.syncLevel {
+		return this.Sync()
+	}
+	return nil
+}
+
+func (this *reopenableFile) Close() (err error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.closed = true
+	if this.f != nil {
+		err = this.f.Close()
+		this.f = nil
+	}
+	return
+}
+
+// NewFileBackend opens the file backend of path. The backends are cached by
+// path, so opening the same path again returns the same backend, or an error
+// if the options are not the same, eg. Truncate or Perm differ, or if the path
+// was opened by NewRotatingFileBackend.
 func NewFileBackend(path string, options FileOptions) (b *FileBackend, err error) {
 	var f *os.File
 	if options.Perm == 0 {
@@ -64,7 +343,12 @@ func NewFileBackend(path string, options FileOptions) (b *FileBackend, err error
 	}
 
 	if v, ok := fileMap.Load(path); ok {
-		b = v.(*FileBackend)
+		var isFile bool
+		if b, isFile = v.(*FileBackend); !isFile {
+			err = fmt.Errorf("file %q already opened with rotation", path)
+		} else if !b.options.equal(options) {
+			b, err = nil, fmt.Errorf("file %q already opened with other options", path)
+		}
 		return
 	}
 
@@ -83,18 +367,61 @@ func NewFileBackend(path string, options FileOptions) (b *FileBackend, err error
 
 	b = &FileBackend{
 		path,
-		NewWriteCloserBackend("file:"+path, f, options.Async),
+		options,
+		newWriteCloserBackend("file", "file:"+path, newBufferedWriter(&reopenableFile{path: path, perm: options.Perm, syncLevel: options.SyncOnLevel, f: f}, options), options.Async),
 	}
+	b.QueueSize = options.QueueSize
+	b.Workers = options.Workers
+	b.Overflow = options.Overflow
+	b.DroppedReportInterval = options.DroppedReportInterval
+	// CloseAll closes the FileBackend, removing it from the files cache
+	unregister(b.WriteCloserBackend)
+	register(b)
 	fileMap.Store(path, b)
 	return
 }
 
 type FileBackend struct {
-	path string
+	path    string
+	options FileOptions
 	*WriteCloserBackend
 }
 
-func (this *FileBackend) Print(args ...interface{}) (err error) {
+// Close writes the pending async records, closes the file and removes the
+// backend from the files cache, so NewFileBackend opens the path again.
+func (this *FileBackend) Close() error {
+	unregister(this)
+	uncache(this.path, this)
+	return this.WriteCloserBackend.Close()
+}
+
+// uncache removes b from the files cache, unless the path was opened again
+// by other backend.
+func uncache(path string, b interface{}) {
+	if v, ok := fileMap.Load(path); ok && v == b {
+		fileMap.Delete(path)
+	}
+}
+
+// Print implements the logging.Printer interface. With the FormattedPrint
+// option, the values are written as an INFO record formatted by the
+// PrintFormatter, so the printed lines look like the logged ones in the same
+// file. Otherwise, they are written raw, like RawPrint. Use PrintLevel to
+// print a record of other level.
+func (this *FileBackend) Print(args ...interface{}) error {
+	if !this.options.FormattedPrint {
+		return this.RawPrint(args...)
+	}
+	if this.options.PrintFormatter == nil {
+		return this.PrintLevel(logging.INFO, args...)
+	}
+	backend := logging.NewBackendFormatter(this.WriteCloserBackend, this.options.PrintFormatter)
+	return backend.Log(logging.INFO, 1, logging.PrintRecord(logging.INFO, args...))
+}
+
+// RawPrint writes the values followed by a newline, without any formatting,
+// eg. to write the banners or the output of other tools as they are.
+func (this *FileBackend) RawPrint(args ...interface{}) (err error) {
 	_, err = this.Write([]byte(fmt.Sprint(args...) + "\n"))
 	return
 }
@@ -102,3 +429,35 @@ func (this *FileBackend) Print(args ...interface{}) (err error) {
 func (this *FileBackend) Path() string {
 	return this.path
 }
+
+// Options returns the options used to open the file.
+func (this *FileBackend) Options() FileOptions {
+	return this.options
+}
+
+// ReopenAll reopens all file backends. It should be called from a SIGHUP
+// handler after the files were moved by logrotate, eg:
+//
+//	c := make(chan os.Signal, 1)
+//	signal.Notify(c, syscall.SIGHUP)
+//	go func() {
+//		for range c {
+//			backends.ReopenAll()
+//		}
+//	}()
+func ReopenAll() (err error) {
+	fileMap.Range(func(_, v interface{}) bool {
+		var e error
+		switch t := v.(type) {
+		case *FileBackend:
+			e = t.Reopen()
+		case *RotatingFileBackend:
+			e = t.Reopen()
+		}
+		if e != nil && err == nil {
+			err = e
+		}
+		return true
+	})
+	return
+}
@@ -0,0 +1,318 @@
+package backends
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+func TestFileBackendReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	b, err := NewFileBackend(path, FileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	b.Print("before")
+	if err = os.Rename(path, path+".old"); err != nil {
+		t.Fatal(err)
+	}
+	if err = ReopenAll(); err != nil {
+		t.Fatal(err)
+	}
+	b.Print("after")
+
+	for name, content := range map[string]string{path + ".old": "before\n", path: "after\n"} {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("%s: %q != %q", name, data, content)
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func (failingWriter) Close() error {
+	return nil
+}
+
+func TestWriteCloserBackendAsyncError(t *testing.T) {
+	b := newWriteCloserBackend("file", "file:app.log", failingWriter{}, true)
+	errs := make(chan error, 1)
+	b.ErrorHandler = func(err error) {
+		errs <- err
+	}
+	defer b.Close()
+
+	log := logging.NewLogger("async-error")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info("hello")
+
+	err, ok := (<-errs).(*AsyncError)
+	if !ok {
+		t.Fatalf("unexpected error %T", err)
+	}
+	if err.Error() != `file "app.log" failed: disk full` {
+		t.Errorf("unexpected error %q", err)
+	}
+}
+
+func TestFileBackendCacheOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	b, err := NewFileBackend(path, FileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if cached, err := NewFileBackend(path, FileOptions{Perm: 0666}); err != nil || cached != b {
+		t.Errorf("file backend is not cached: %v", err)
+	}
+	for name, options := range map[string]FileOptions{
+		"truncate": {Truncate: true},
+		"perm":     {Perm: 0600},
+	} {
+		if _, err := NewFileBackend(path, options); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	path = filepath.Join(dir, "print.log")
+	options := FileOptions{FormattedPrint: true, PrintFormatter: partsFormatter{"a"}}
+	if b, err = NewFileBackend(path, options); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	for _, formatter := range []logging.Formatter{partsFormatter{"b"}, logging.MustStringFormatter("%{message}")} {
+		options.PrintFormatter = formatter
+		if cached, err := NewFileBackend(path, options); err != nil || cached != b {
+			t.Errorf("file backend with other print formatter is not cached: %v", err)
+		}
+	}
+}
+
+// partsFormatter is a formatter of a not comparable type.
+type partsFormatter []string
+
+func (f partsFormatter) Format(calldepth int, r *logging.Record, w io.Writer) error {
+	_, err := io.WriteString(w, strings.Join(f, " ")+r.Message())
+	return err
+}
+
+func TestFileBackendClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	b, err := NewFileBackend(path, FileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Reopen(); err != os.ErrClosed {
+		t.Errorf("closed file reopened: %v", err)
+	}
+	reopened, err := NewFileBackend(path, FileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened == b {
+		t.Fatal("the closed backend is still cached")
+	}
+	if err = reopened.RawPrint("line"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	rotating, err := NewRotatingFileBackend(filepath.Join(dir, "rotating.log"), RotateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = CloseAll(); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{path, rotating.Path()} {
+		if _, ok := fileMap.Load(path); ok {
+			t.Errorf("%s still cached after CloseAll", path)
+		}
+	}
+	if err = rotating.RawPrint("line"); err != os.ErrClosed {
+		t.Errorf("closed rotating file written: %v", err)
+	}
+	if err = rotating.Rotate(); err != os.ErrClosed {
+		t.Errorf("closed rotating file rotated: %v", err)
+	}
+}
+
+func TestFileBackendSync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	b, err := NewFileBackend(path, FileOptions{Buffered: true, FlushInterval: time.Hour, SyncOnLevel: logging.ERROR})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	read := func() string {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	log := logging.NewLogger("sync")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info("info")
+	if data := read(); data != "" {
+		t.Fatalf("info record written: %q", data)
+	}
+	log.Error("error")
+	if data := read(); !strings.HasSuffix(data, "error\n") {
+		t.Fatalf("error record not synced: %q", data)
+	}
+	log.Info("pending")
+	if err = b.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if data := read(); !strings.HasSuffix(data, "pending\n") {
+		t.Fatalf("pending record not synced: %q", data)
+	}
+}
+
+func TestFileBackendPrintLevel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "print.log")
+	b, err := NewFileBackend(path, FileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer logging.SetFormatter(logging.DefaultFormatter)
+	logging.SetFormatter(logging.MustStringFormatter("%{level} %{message}"))
+
+	b.Print("raw")
+	b.PrintLevel(logging.NOTICE, "formatted")
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || lines[0] != "raw" || !strings.HasSuffix(lines[1], " NOTICE formatted") {
+		t.Errorf("unexpected output: %q", data)
+	}
+}
+
+func TestFileBackendFormattedPrint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "print.log")
+	b, err := NewFileBackend(path, FileOptions{
+		FormattedPrint: true,
+		PrintFormatter: logging.MustStringFormatter("%{level} %{message}"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.Print("formatted")
+	b.RawPrint("raw")
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], " INFO formatted") || lines[1] != "raw" {
+		t.Errorf("unexpected output: %q", data)
+	}
+}
+
+func TestFileBackendSyncRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	b, err := NewFileBackend(path, FileOptions{Async: true, Buffered: true, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	log := logging.NewLogger("sync")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info("async")
+	if err = log.Sync().Critical("audit"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"); len(lines) != 2 ||
+		!strings.HasSuffix(lines[0], "async") || !strings.HasSuffix(lines[1], "audit") {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	failing := newWriteCloserBackend("file", "file:app.log", failingWriter{}, true)
+	defer failing.Close()
+	log.SetBackend(logging.AddModuleLevel(failing))
+	if err = log.Sync().Info("audit"); err == nil || err.Error() != "disk full" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -2,13 +2,19 @@ package backends
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
 	"time"
+	"unicode/utf8"
 
 	"github.com/moisespsena-go/logging"
 )
@@ -23,16 +29,124 @@ type HttpOptions struct {
 	HttpGet   bool
 	Formatted bool
 	Async     bool
+	// QueryParam is the name of the query parameter of the message in the
+	// HttpGet mode. Defaults to "message".
+	QueryParam string
+	// MaxGetLen is the maximum length of the escaped message in the HttpGet
+	// mode. The longer messages are sent by POST, or truncated if TruncateGet
+	// is set. Zero means no limit.
+	MaxGetLen int
+	// TruncateGet truncates the messages longer than MaxGetLen instead of
+	// sending them by POST. The truncated JSON records aren't valid JSON, so
+	// it should be used with Formatted.
+	TruncateGet bool
+	// QueueSize is the size of the async queue. Defaults to DefaultQueueSize.
+	QueueSize int
+	// Workers is the number of goroutines sending the async requests.
+	// Defaults to 1, which sends the records in the order they were logged.
+	// More workers trade the ordering for throughput.
+	Workers int
+	// Overflow is the policy used when the async queue is full. Defaults to
+	// block until the queue has room.
+	Overflow logging.OverflowPolicy
+	// DroppedReportInterval enables a periodic warning, in the backend Logger,
+	// with the number of records dropped by the Overflow policy.
+	DroppedReportInterval time.Duration
+	// CloseTimeout is the maximum time Close waits for the pending async
+	// requests. Zero waits forever.
+	CloseTimeout time.Duration
+	// BatchSize enables the batch mode when greater than 1: the records are
+	// sent as a JSON array by POST when the batch is full or on each
+	// FlushInterval.
+	BatchSize int
+	// FlushInterval is the maximum time a record waits in the batch. Defaults
+	// to one second.
+	FlushInterval time.Duration
+	// MaxRetries is the number of times a request is retried after a network
+	// error, a 5xx, 408 or 429 response.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled on each retry
+	// and limited to the client Timeout. Defaults to 100 milliseconds.
+	RetryBackoff time.Duration
+	// GzipRequest compresses the body of the POST requests, setting the
+	// Content-Encoding: gzip header.
+	GzipRequest bool
+	// Headers are added to all requests, eg. the Authorization header.
+	Headers http.Header
+	// ResponseHandler inspects the 2xx responses, eg. the acks of the
+	// ingestion endpoints. An error fails the request, which is retried up to
+	// MaxRetries, like the network errors. The body is drained and closed
+	// after the handler returns.
+	ResponseHandler func(resp *http.Response) error
+	// Marshaler encodes the records which aren't Formatted, returning the
+	// body and its Content-Type, eg. msgpack or protobuf. Defaults to
+	// MarshalRecordJSON, or MarshalRecordJSONCaller with the Caller option.
+	// The batch mode always sends a JSON array.
+	Marshaler func(rec *logging.Record) (body []byte, contentType string, err error)
+	// Caller adds the file, line and function of the caller into the JSON
+	// records, see logging.Record.DataWithCaller.
+	Caller bool
+}
+
+// MarshalRecordJSON is the default HttpOptions.Marshaler, which encodes the
+// record data as JSON.
+func MarshalRecordJSON(rec *logging.Record) ([]byte, string, error) {
+	body, err := json.Marshal(rec.Data())
+	return body, "application/json", err
+}
+
+// MarshalRecordJSONCaller is like MarshalRecordJSON, but adds the caller of
+// the record.
+func MarshalRecordJSONCaller(rec *logging.Record) ([]byte, string, error) {
+	body, err := json.Marshal(rec.DataWithCaller())
+	return body, "application/json", err
+}
+
+// HttpStatusError is returned when the server responds with a non 2xx status.
+type HttpStatusError struct {
+	URL        string
+	Status     string
+	StatusCode int
+}
+
+func (this *HttpStatusError) Error() string {
+	return fmt.Sprintf("%q responded with status %s", this.URL, this.Status)
+}
+
+// Temporary reports whether the request may succeed if retried.
+func (this *HttpStatusError) Temporary() bool {
+	return this.StatusCode >= 500 || this.StatusCode == http.StatusRequestTimeout ||
+		this.StatusCode == http.StatusTooManyRequests
 }
 
 type HttpBackend struct {
 	Client        *http.Client
 	URL           url.URL
 	HttpGet       bool
+	QueryParam    string
+	MaxGetLen     int
+	TruncateGet   bool
 	Formatted     bool
 	defaultClient bool
 	Async         bool
 	Logger        logging.Logger
+	CloseTimeout  time.Duration
+	MaxRetries    int
+	RetryBackoff  time.Duration
+	GzipRequest   bool
+	Headers       http.Header
+	worker        *asyncWorker
+	batch         *httpBatch
+	failover      *httpFailover
+
+	// ResponseHandler, if set, inspects the 2xx responses. See
+	// HttpOptions.ResponseHandler.
+	ResponseHandler func(resp *http.Response) error
+	// Marshaler encodes the records which aren't Formatted. See
+	// HttpOptions.Marshaler.
+	Marshaler func(rec *logging.Record) (body []byte, contentType string, err error)
+	// Caller adds the caller into the JSON records. See HttpOptions.Caller.
+	Caller bool
 }
 
 func NewHttpBackend(URL url.URL, opt HttpOptions, client *http.Client) (wsb *HttpBackend) {
@@ -69,87 +183,380 @@ func NewHttpBackend(URL url.URL, opt HttpOptions, client *http.Client) (wsb *Htt
 		Client:        client,
 		URL:           URL,
 		HttpGet:       opt.HttpGet,
+		QueryParam:    opt.QueryParam,
+		MaxGetLen:     opt.MaxGetLen,
+		TruncateGet:   opt.TruncateGet,
 		Formatted:     opt.Formatted,
 		defaultClient: defaultClient,
 		Async:         opt.Async,
 		Logger:        logging.WithPrefix(log_, logPrefix),
+		CloseTimeout:  opt.CloseTimeout,
+		MaxRetries:    opt.MaxRetries,
+		RetryBackoff:  opt.RetryBackoff,
+		GzipRequest:   opt.GzipRequest,
+		Headers:       opt.Headers,
+
+		ResponseHandler: opt.ResponseHandler,
+		Marshaler:       opt.Marshaler,
+		Caller:          opt.Caller,
+	}
+	if wsb.QueryParam == "" {
+		wsb.QueryParam = "message"
+	}
+	if wsb.Marshaler == nil {
+		if wsb.Caller {
+			wsb.Marshaler = MarshalRecordJSONCaller
+		} else {
+			wsb.Marshaler = MarshalRecordJSON
+		}
+	}
+	if wsb.RetryBackoff == 0 {
+		wsb.RetryBackoff = 100 * time.Millisecond
 	}
+	if opt.Async {
+		wsb.worker = newAsyncWorker(opt.QueueSize, opt.Workers, opt.Overflow)
+		if opt.DroppedReportInterval > 0 {
+			wsb.worker.ReportDropped(opt.DroppedReportInterval, func(n uint64) {
+				wsb.Logger.Warningf("%q dropped %d records", wsb.URL.String(), n)
+			})
+		}
+	}
+	if opt.BatchSize > 1 {
+		wsb.batch = newHttpBatch(wsb, opt.BatchSize, opt.FlushInterval)
+	}
+	register(wsb)
 	return
 }
 
-func (this *HttpBackend) log(level logging.Level, calldepth int, rec *logging.Record) (err error) {
-	var msg []byte
+// basicAuth returns the value of the Authorization header of the basic
+// authentication.
+func basicAuth(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+// httpRequest is a request which can be sent many times.
+type httpRequest struct {
+	method string
+	url    string
+	body   []byte
+	// contentType of the POST requests. Defaults to application/json.
+	contentType string
+	// response, if set, reads the body of the 2xx responses. The request was
+	// done, so it isn't retried and response handles its own errors.
+	response func(body io.Reader)
+	// gzipped is the compressed body, kept for the retries.
+	gzipped []byte
+}
+
+// gzipBody returns the compressed body of the request.
+func (this *httpRequest) gzipBody() (body []byte, err error) {
+	if this.gzipped == nil {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err = gz.Write(this.body); err == nil {
+			err = gz.Close()
+		}
+		if err != nil {
+			return
+		}
+		this.gzipped = buf.Bytes()
+	}
+	return this.gzipped, nil
+}
+
+func (this *HttpBackend) logRequest(calldepth int, rec *logging.Record) (req *httpRequest, err error) {
+	var (
+		msg         []byte
+		contentType string
+	)
 	if this.Formatted {
 		msg = []byte(rec.Formatted(calldepth))
-	} else if msg, err = json.Marshal(rec.Data()); err != nil {
+	} else if msg, contentType, err = this.Marshaler(rec); err != nil {
 		return
 	}
-	var resp *http.Response
-	defer func() {
-		if resp != nil && resp.Body != nil {
-			resp.Body.Close()
-		}
-	}()
 	if this.HttpGet {
-		var url = this.URL
-		url.Query().Set("message", string(msg))
-		_, err = this.Client.Get(url.String())
-	} else {
-		_, err = this.Client.Post(this.URL.String(), "application/json", bytes.NewBuffer(msg))
+		if req = this.getRequest(this.QueryParam, string(msg)); req != nil {
+			return
+		}
 	}
-	return
+	return &httpRequest{method: http.MethodPost, url: this.URL.String(), body: msg, contentType: contentType}, nil
 }
 
-func (this *HttpBackend) print(args ...interface{}) (err error) {
+func (this *HttpBackend) printRequest(args ...interface{}) *httpRequest {
 	msg := []byte(fmt.Sprint(args...))
-	var resp *http.Response
-	defer func() {
-		if resp != nil && resp.Body != nil {
-			resp.Body.Close()
+	if this.HttpGet {
+		if req := this.getRequest("string", string(msg)); req != nil {
+			return req
+		}
+	}
+	return &httpRequest{method: http.MethodPost, url: this.queryURL("string", "true"), body: msg}
+}
+
+// getRequest returns the GET request with the query parameter name set to msg,
+// or nil if the escaped msg is longer than MaxGetLen and it must be sent by
+// POST.
+func (this *HttpBackend) getRequest(name, msg string) *httpRequest {
+	if this.MaxGetLen > 0 && len(url.QueryEscape(msg)) > this.MaxGetLen {
+		if !this.TruncateGet {
+			return nil
+		}
+		msg = truncateQuery(msg, this.MaxGetLen)
+	}
+	return &httpRequest{method: http.MethodGet, url: this.queryURL(name, msg)}
+}
+
+// truncateQuery returns the longest prefix of value, cut at a rune boundary,
+// whose query escape is up to max bytes.
+func truncateQuery(value string, max int) string {
+	var n int
+	for i := 0; i < len(value); {
+		_, size := utf8.DecodeRuneInString(value[i:])
+		if n += len(url.QueryEscape(value[i : i+size])); n > max {
+			return value[:i]
+		}
+		i += size
+	}
+	return value
+}
+
+// queryURL returns the URL with the query parameter name set to value. The
+// value is escaped, so the newlines, quotes and the unicode characters of the
+// messages are kept.
+func (this *HttpBackend) queryURL(name, value string) string {
+	var url = this.URL
+	q := url.Query()
+	q.Set(name, value)
+	url.RawQuery = q.Encode()
+	return url.String()
+}
+
+// send does a single request attempt. The failover backends try the
+// endpoints until one of them accepts the request.
+func (this *HttpBackend) send(r *httpRequest) error {
+	if this.failover != nil {
+		return this.sendFailover(r)
+	}
+	return this.sendTo(r, r.url)
+}
+
+// sendTo does a single request attempt to rawurl.
+func (this *HttpBackend) sendTo(r *httpRequest, rawurl string) (err error) {
+	var (
+		req      *http.Request
+		resp     *http.Response
+		body     = r.body
+		compress = this.GzipRequest && r.method == http.MethodPost
+	)
+	if compress {
+		if body, err = r.gzipBody(); err != nil {
+			return
 		}
+	}
+	if req, err = http.NewRequest(r.method, rawurl, bytes.NewReader(body)); err != nil {
+		return
+	}
+	if this.worker != nil {
+		// the requests are canceled when the worker closes before they are
+		// done
+		req = req.WithContext(this.worker.Context())
+	}
+	for key, values := range this.Headers {
+		req.Header[key] = values
+	}
+	if r.method == http.MethodPost {
+		contentType := r.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	if compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if resp, err = this.Client.Do(req); err != nil {
+		return
+	}
+	defer func() {
+		// drains the body, so the connection can be reused
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
 	}()
-	if this.HttpGet {
-		var url = this.URL
-		url.Query().Set("string", string(msg))
-		_, err = this.Client.Get(url.String())
-	} else {
-		var url = this.URL
-		url.Query().Set("string", "true")
-		_, err = this.Client.Post(url.String(), "application/json", bytes.NewBuffer(msg))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &HttpStatusError{rawurl, resp.Status, resp.StatusCode}
+	}
+	if this.ResponseHandler != nil {
+		if err = this.ResponseHandler(resp); err != nil {
+			return
+		}
+	}
+	if r.response != nil {
+		r.response(resp.Body)
 	}
 	return
 }
 
+// retryable reports whether the request which failed with err can be retried.
+func (this *HttpBackend) retryable(err error, attempt int) bool {
+	if attempt >= this.MaxRetries {
+		return false
+	}
+	if statusErr, ok := err.(*HttpStatusError); ok {
+		return statusErr.Temporary()
+	}
+	return true
+}
+
+// backoff returns the delay before the retry number attempt+1.
+func (this *HttpBackend) backoff(attempt int) time.Duration {
+	d := this.RetryBackoff << uint(attempt)
+	if this.Client.Timeout > 0 && (d > this.Client.Timeout || d <= 0) {
+		d = this.Client.Timeout
+	}
+	return d
+}
+
+// do sends the request, retrying on failures.
+func (this *HttpBackend) do(r *httpRequest) (err error) {
+	for attempt := 0; ; attempt++ {
+		if err = this.send(r); err == nil || !this.retryable(err, attempt) {
+			return
+		}
+		time.Sleep(this.backoff(attempt))
+	}
+}
+
+// doAsync sends the request from the async worker. The retries are scheduled
+// into the worker, so they don't block the other requests.
+func (this *HttpBackend) doAsync(r *httpRequest, attempt int) {
+	err := this.send(r)
+	if err == nil {
+		return
+	}
+	if this.retryable(err, attempt) && this.worker.Context().Err() == nil {
+		this.worker.Later(this.backoff(attempt), func() {
+			this.doAsync(r, attempt+1)
+		})
+		return
+	}
+	handleAsyncError(this.Logger, err, "%q failed", this.URL.String())
+}
+
 func (this *HttpBackend) Print(args ...interface{}) (err error) {
 	if this.Async {
-		go func() {
-			if err := this.print(args...); err != nil {
-				this.Logger.Errorf("%q failed: %s", this.URL.String(), err.Error())
-			}
-		}()
+		this.worker.Do(func() {
+			this.doAsync(this.printRequest(args...), 0)
+		})
 	} else {
-		err = this.print(args...)
+		err = this.do(this.printRequest(args...))
 	}
 	return
 }
 
+// PrintLevel implements the logging.LevelPrinter interface. Unlike Print, the
+// values are sent as a record of level, like the logged ones.
+func (this *HttpBackend) PrintLevel(level logging.Level, args ...interface{}) error {
+	return this.Log(level, 1, logging.PrintRecord(level, args...))
+}
+
+// Log implements the logging.Backend interface. The synchronous records, see
+// logging.SyncLogger, are sent on their own, after the pending async requests,
+// and the error is returned.
 func (this *HttpBackend) Log(level logging.Level, calldepth int, rec *logging.Record) (err error) {
+	if rec.IsSync() {
+		return this.logSync(calldepth+1, rec)
+	}
+	if this.batch != nil {
+		return this.batch.add(calldepth+1, rec)
+	}
 	if this.Async {
-		go func() {
-			r := *rec
-			if err := this.log(level, calldepth, &r); err != nil {
-				this.Logger.Errorf("%q failed: %s", this.URL.String(), err.Error())
+		r := rec.Clone()
+		this.worker.Do(func() {
+			req, err := this.logRequest(calldepth, r)
+			if err != nil {
+				handleAsyncError(this.Logger, err, "%q failed", this.URL.String())
+				return
 			}
-		}()
-	} else {
-		err = this.log(level, calldepth, rec)
+			this.doAsync(req, 0)
+		})
+		return
 	}
-	return
+	var req *httpRequest
+	if req, err = this.logRequest(calldepth, rec); err != nil {
+		return
+	}
+	return this.do(req)
+}
+
+// logSync sends rec after the current batch and the pending async requests.
+func (this *HttpBackend) logSync(calldepth int, rec *logging.Record) (err error) {
+	if this.batch != nil {
+		if err = this.batch.flush(); err != nil {
+			return
+		}
+	}
+	if this.worker != nil {
+		this.worker.FlushQueued()
+	}
+	var req *httpRequest
+	if req, err = this.logRequest(calldepth, rec); err != nil {
+		return
+	}
+	return this.do(req)
+}
+
+// Dropped returns the number of async requests dropped by the Overflow policy.
+func (this *HttpBackend) Dropped() uint64 {
+	if this.worker != nil {
+		return this.worker.Dropped()
+	}
+	return 0
 }
 
+// Flush sends the current batch and waits until all pending async requests
+// are done.
+func (this *HttpBackend) Flush() error {
+	if this.batch != nil {
+		if err := this.batch.flush(); err != nil {
+			return err
+		}
+	}
+	if this.worker != nil {
+		return this.worker.Flush(0)
+	}
+	return nil
+}
+
+// Close sends the current batch and waits, up to CloseTimeout, for the
+// pending async requests. If CloseTimeout expires, the in-flight requests are
+// canceled. The idle connections of the failover backends are released, for
+// all endpoints.
 func (this *HttpBackend) Close() error {
+	return this.close(func() error {
+		return this.worker.Close(this.CloseTimeout)
+	})
+}
+
+// CloseContext is like Close, but waits for the pending async requests until
+// ctx is done, eg. the deadline of the application shutdown. Then the
+// in-flight requests are canceled and ctx.Err() is returned.
+func (this *HttpBackend) CloseContext(ctx context.Context) error {
+	return this.close(func() error {
+		return this.worker.CloseContext(ctx)
+	})
+}
+
+func (this *HttpBackend) close(closeWorker func() error) (err error) {
+	unregister(this)
+	if this.batch != nil {
+		err = this.batch.close()
+	}
+	if this.worker != nil {
+		if e := closeWorker(); err == nil {
+			err = e
+		}
+	}
 	if !this.defaultClient {
 		this.Client.CloseIdleConnections()
 	}
-	return nil
+	return
 }
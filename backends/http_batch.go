@@ -0,0 +1,156 @@
+package backends
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// httpBatch accumulates the encoded records of a HttpBackend and sends them
+// together, by default as a JSON array.
+type httpBatch struct {
+	backend *HttpBackend
+	size    int
+	// encode returns the item of the record.
+	encode func(calldepth int, rec *logging.Record) ([]byte, error)
+	// request returns the request sending the items.
+	request  func(items []json.RawMessage) (*httpRequest, error)
+	mu       sync.Mutex
+	items    []json.RawMessage
+	stop     chan struct{}
+	stopOnce sync.Once
+	stopped  sync.WaitGroup
+}
+
+func newHttpBatch(backend *HttpBackend, size int, interval time.Duration) *httpBatch {
+	return newHttpBatchEncoder(backend, size, interval, nil, nil)
+}
+
+// newHttpBatchEncoder creates a batch which encodes the records and builds the
+// requests using the given functions. The nil ones default to the JSON array.
+func newHttpBatchEncoder(
+	backend *HttpBackend,
+	size int,
+	interval time.Duration,
+	encode func(calldepth int, rec *logging.Record) ([]byte, error),
+	request func(items []json.RawMessage) (*httpRequest, error),
+) *httpBatch {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	b := &httpBatch{
+		backend: backend,
+		size:    size,
+		encode:  encode,
+		request: request,
+		stop:    make(chan struct{}),
+	}
+	if b.encode == nil {
+		b.encode = b.encodeJSON
+	}
+	if b.request == nil {
+		b.request = b.jsonRequest
+	}
+	b.stopped.Add(1)
+	go b.run(interval)
+	return b
+}
+
+// run flushes the batch on each interval until the batch is closed.
+func (this *httpBatch) run(interval time.Duration) {
+	defer this.stopped.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := this.flush(); err != nil {
+				handleAsyncError(this.backend.Logger, err, "%q batch failed", this.backend.URL.String())
+			}
+		case <-this.stop:
+			return
+		}
+	}
+}
+
+// add encodes the record and appends it into the batch. The record isn't kept,
+// so it's safe to reuse after this call.
+func (this *httpBatch) add(calldepth int, rec *logging.Record) (err error) {
+	var item []byte
+	if item, err = this.encode(calldepth+1, rec); err != nil {
+		return
+	}
+
+	this.mu.Lock()
+	this.items = append(this.items, item)
+	var items []json.RawMessage
+	if len(this.items) >= this.size {
+		items = this.take()
+	}
+	this.mu.Unlock()
+
+	return this.send(items)
+}
+
+// encodeJSON returns the record, or the formatted record, as JSON.
+func (this *httpBatch) encodeJSON(calldepth int, rec *logging.Record) ([]byte, error) {
+	if this.backend.Formatted {
+		return json.Marshal(rec.Formatted(calldepth + 1))
+	}
+	if this.backend.Caller {
+		return json.Marshal(rec.DataWithCaller())
+	}
+	return json.Marshal(rec.Data())
+}
+
+// jsonRequest returns the POST request of the items as a JSON array.
+func (this *httpBatch) jsonRequest(items []json.RawMessage) (*httpRequest, error) {
+	body, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	return &httpRequest{method: http.MethodPost, url: this.backend.URL.String(), body: body}, nil
+}
+
+// take returns the current items and starts a new batch.
+func (this *httpBatch) take() (items []json.RawMessage) {
+	items, this.items = this.items, nil
+	return
+}
+
+func (this *httpBatch) flush() error {
+	this.mu.Lock()
+	items := this.take()
+	this.mu.Unlock()
+	return this.send(items)
+}
+
+// send posts the items, using the async worker of the backend if enabled.
+func (this *httpBatch) send(items []json.RawMessage) (err error) {
+	if len(items) == 0 {
+		return
+	}
+	var req *httpRequest
+	if req, err = this.request(items); err != nil {
+		return
+	}
+	if this.backend.Async {
+		this.backend.worker.Do(func() {
+			this.backend.doAsync(req, 0)
+		})
+		return
+	}
+	return this.backend.do(req)
+}
+
+// close stops the flusher and sends the remaining items.
+func (this *httpBatch) close() error {
+	this.stopOnce.Do(func() {
+		close(this.stop)
+	})
+	this.stopped.Wait()
+	return this.flush()
+}
@@ -0,0 +1,131 @@
+package backends
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHttpProbeInterval is the interval between the attempts to use the
+// primary endpoint again when HttpFailoverOptions.ProbeInterval is zero.
+const DefaultHttpProbeInterval = 30 * time.Second
+
+// HttpFailoverOptions configures NewHttpFailoverBackend.
+type HttpFailoverOptions struct {
+	HttpOptions
+
+	// ProbeInterval is the minimum time between the attempts to send the
+	// requests to the primary endpoint again, after it failed. Defaults to
+	// DefaultHttpProbeInterval.
+	ProbeInterval time.Duration
+}
+
+// httpFailover tracks the healthy endpoint of a failover backend.
+type httpFailover struct {
+	urls          []url.URL
+	probeInterval time.Duration
+	mu            sync.Mutex
+	current       int
+	probedAt      time.Time
+}
+
+// NewHttpFailoverBackend creates a backend which sends the requests to the
+// first of urls, the primary endpoint, and falls back to the next ones, in
+// order, when it fails with a network error or a 5xx, 408 or 429 response. The
+// endpoint which accepted the last request is used until it fails, and the
+// primary one is probed again, with a real request, once per ProbeInterval.
+//
+// The retries of MaxRetries happen when all endpoints failed. The requests are
+// built for the primary endpoint and sent to the others replacing its scheme,
+// user, host and path prefix, so the query of the requests is kept.
+func NewHttpFailoverBackend(urls []url.URL, opt HttpFailoverOptions, client *http.Client) (b *HttpBackend, err error) {
+	if len(urls) == 0 {
+		return nil, errors.New("http failover: no URLs")
+	}
+	if opt.ProbeInterval <= 0 {
+		opt.ProbeInterval = DefaultHttpProbeInterval
+	}
+	if client == nil {
+		// a client with its own transport, whose idle connections are
+		// closed by Close, instead of the shared one of http.DefaultClient
+		client = &http.Client{}
+	}
+	b = NewHttpBackend(urls[0], opt.HttpOptions, client)
+	b.failover = &httpFailover{
+		urls:          append([]url.URL(nil), urls...),
+		probeInterval: opt.ProbeInterval,
+	}
+	return
+}
+
+// order returns the indexes of the endpoints in the order they're tried: the
+// primary one, if it's time to probe it, the current one and then the others.
+func (this *httpFailover) order() []int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	order := make([]int, 0, len(this.urls))
+	if this.current != 0 && time.Since(this.probedAt) >= this.probeInterval {
+		this.probedAt = time.Now()
+		order = append(order, 0)
+	}
+	order = append(order, this.current)
+	for i := range this.urls {
+		if i != this.current && i != order[0] {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// succeeded makes i the current endpoint. It returns true if it changed.
+func (this *httpFailover) succeeded(i int) bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.current == i {
+		return false
+	}
+	if this.current == 0 {
+		this.probedAt = time.Now()
+	}
+	this.current = i
+	return true
+}
+
+// requestURL returns rawurl, built for the primary endpoint, for the endpoint
+// i.
+func (this *httpFailover) requestURL(rawurl string, i int) string {
+	if i == 0 {
+		return rawurl
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return rawurl
+	}
+	from, to := this.urls[0], this.urls[i]
+	u.Scheme, u.User, u.Host = to.Scheme, to.User, to.Host
+	u.Path = strings.TrimSuffix(to.Path, "/") + strings.TrimPrefix(u.Path, strings.TrimSuffix(from.Path, "/"))
+	u.RawPath = ""
+	return u.String()
+}
+
+// sendFailover sends the request to the endpoints until one of them accepts
+// it. The requests rejected with other errors aren't sent to the next
+// endpoints.
+func (this *HttpBackend) sendFailover(r *httpRequest) (err error) {
+	for _, i := range this.failover.order() {
+		rawurl := this.failover.requestURL(r.url, i)
+		if err = this.sendTo(r, rawurl); err == nil {
+			if this.failover.succeeded(i) {
+				this.Logger.Warningf("switched to %q", this.failover.urls[i].String())
+			}
+			return
+		}
+		if statusErr, ok := err.(*HttpStatusError); ok && !statusErr.Temporary() {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,112 @@
+package backends
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHttpFailoverBackend(t *testing.T) {
+	var primaryDown, primaryCount int32 = 1, 0
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&primaryCount, 1)
+		if atomic.LoadInt32(&primaryDown) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer primary.Close()
+	paths := make(chan string, 10)
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.RequestURI()
+	}))
+	defer secondary.Close()
+
+	u1, _ := url.Parse(primary.URL + "/logs")
+	u2, _ := url.Parse(secondary.URL + "/collector/logs")
+	b, err := NewHttpFailoverBackend([]url.URL{*u1, *u2}, HttpFailoverOptions{ProbeInterval: 50 * time.Millisecond}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if err := b.Print("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Print("b"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&primaryCount); n != 1 {
+		t.Errorf("expected 1 request to the primary, got %d", n)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("expected 2 requests to the secondary, got %d", len(paths))
+	}
+	if path := <-paths; path != "/collector/logs?string=true" {
+		t.Errorf("unexpected secondary path: %s", path)
+	}
+	<-paths
+
+	atomic.StoreInt32(&primaryDown, 0)
+	time.Sleep(60 * time.Millisecond)
+	if err := b.Print("c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Print("d"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&primaryCount); n != 3 {
+		t.Errorf("expected 3 requests to the primary, got %d", n)
+	}
+	if len(paths) != 0 {
+		t.Errorf("expected no requests to the secondary, got %d", len(paths))
+	}
+}
+
+func TestHttpFailoverBackendAllFailed(t *testing.T) {
+	primary, primaryCount := statusServer(http.StatusInternalServerError, http.StatusInternalServerError)
+	defer primary.Close()
+	secondary, secondaryCount := statusServer(http.StatusBadGateway, http.StatusOK)
+	defer secondary.Close()
+
+	u1, _ := url.Parse(primary.URL)
+	u2, _ := url.Parse(secondary.URL)
+	b, err := NewHttpFailoverBackend([]url.URL{*u1, *u2}, HttpFailoverOptions{
+		HttpOptions: HttpOptions{MaxRetries: 1, RetryBackoff: time.Millisecond},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if err := b.Print("retry"); err != nil {
+		t.Fatal(err)
+	}
+	if n1, n2 := atomic.LoadInt32(primaryCount), atomic.LoadInt32(secondaryCount); n1 != 2 || n2 != 2 {
+		t.Errorf("unexpected requests: %d %d", n1, n2)
+	}
+
+	if _, err := NewHttpFailoverBackend(nil, HttpFailoverOptions{}, nil); err == nil {
+		t.Errorf("expected error without URLs")
+	}
+}
+
+func TestHttpFailoverBackendTransport(t *testing.T) {
+	shared := &http.Transport{}
+	defer func(transport http.RoundTripper) {
+		http.DefaultClient.Transport = transport
+	}(http.DefaultClient.Transport)
+	http.DefaultClient.Transport = shared
+
+	u, _ := url.Parse("http://localhost")
+	b, err := NewHttpFailoverBackend([]url.URL{*u}, HttpFailoverOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if b.Client.Transport == shared || b.Client.Transport == http.DefaultTransport {
+		t.Error("expected a transport of the backend")
+	}
+}
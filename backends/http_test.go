@@ -0,0 +1,398 @@
+package backends
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+func TestHttpBackendBatch(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]logging.RecordData
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []logging.RecordData
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &batch); err != nil {
+			t.Errorf("invalid batch %q: %v", body, err)
+		}
+		mu.Lock()
+		batches = append(batches, batch)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := NewHttpBackend(*u, HttpOptions{BatchSize: 3, FlushInterval: time.Hour}, nil)
+
+	log := logging.NewLogger("batch")
+	log.SetBackend(logging.AddModuleLevel(b))
+	for i := 0; i < 7; i++ {
+		log.Infof("line %d", i)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(batches) != 3 || len(batches[0]) != 3 || len(batches[1]) != 3 || len(batches[2]) != 1 {
+		t.Fatalf("unexpected batches: %v", batches)
+	}
+	if batches[2][0].Message != "line 6" {
+		t.Errorf("unexpected message: %q", batches[2][0].Message)
+	}
+}
+
+func TestHttpBackendBatchInterval(t *testing.T) {
+	received := make(chan []string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []string
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &batch)
+		received <- batch
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := NewHttpBackend(*u, HttpOptions{BatchSize: 10, FlushInterval: 10 * time.Millisecond, Formatted: true, Async: true}, nil)
+	defer b.Close()
+
+	log := logging.NewLogger("batch")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info("a")
+	log.Info("b")
+
+	select {
+	case batch := <-received:
+		if len(batch) != 2 || batch[0] != "a" || batch[1] != "b" {
+			t.Errorf("unexpected batch: %q", batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("batch not flushed")
+	}
+}
+
+func statusServer(statuses ...int) (*httptest.Server, *int32) {
+	var count int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(&count, 1)) - 1
+		if i < len(statuses) {
+			w.WriteHeader(statuses[i])
+		}
+	})), &count
+}
+
+func TestHttpBackendRetry(t *testing.T) {
+	server, count := statusServer(http.StatusServiceUnavailable, http.StatusBadGateway)
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := NewHttpBackend(*u, HttpOptions{MaxRetries: 3, RetryBackoff: time.Millisecond}, nil)
+	defer b.Close()
+
+	if err := b.Print("retry"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(count); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestHttpBackendStatusError(t *testing.T) {
+	server, count := statusServer(http.StatusBadRequest)
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := NewHttpBackend(*u, HttpOptions{MaxRetries: 3, RetryBackoff: time.Millisecond}, nil)
+	defer b.Close()
+
+	err := b.Print("bad")
+	if statusErr, ok := err.(*HttpStatusError); !ok || statusErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(count); n != 1 {
+		t.Errorf("4xx should not be retried, got %d requests", n)
+	}
+}
+
+func TestHttpBackendAsyncRetry(t *testing.T) {
+	server, count := statusServer(http.StatusServiceUnavailable)
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := NewHttpBackend(*u, HttpOptions{Async: true, MaxRetries: 1, RetryBackoff: time.Millisecond}, nil)
+	b.Print("retry")
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(count); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestAsyncHttpBackendSyncRecord(t *testing.T) {
+	server, count := statusServer(http.StatusOK, http.StatusBadRequest)
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := NewHttpBackend(*u, HttpOptions{Async: true}, nil)
+	defer b.Close()
+
+	log := logging.NewLogger("sync")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info("async")
+	err := log.Sync().Critical("audit")
+	if statusErr, ok := err.(*HttpStatusError); !ok || statusErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(count); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestHttpBackendGzipRequest(t *testing.T) {
+	var attempts int32
+	received := make(chan logging.RecordData, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("unexpected encoding: %q", r.Header.Get("Content-Encoding"))
+		}
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data logging.RecordData
+		if err = json.NewDecoder(gz).Decode(&data); err != nil {
+			t.Errorf("invalid body: %v", err)
+		}
+		received <- data
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := NewHttpBackend(*u, HttpOptions{GzipRequest: true, MaxRetries: 1, RetryBackoff: time.Millisecond}, nil)
+	defer b.Close()
+
+	log := logging.NewLogger("gzip")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Warning("compressed")
+
+	if data := <-received; data.Message != "compressed" || data.Level != logging.WARNING {
+		t.Errorf("unexpected record: %+v", data)
+	}
+}
+
+func TestHttpBackendGet(t *testing.T) {
+	queries := make(chan url.Values, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("unexpected method %s", r.Method)
+		}
+		queries <- r.URL.Query()
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/log?app=api")
+	b := NewHttpBackend(*u, HttpOptions{HttpGet: true, QueryParam: "msg"}, nil)
+	defer b.Close()
+
+	log := logging.NewLogger("get")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info("hello world & more")
+	if err := b.Print("printed"); err != nil {
+		t.Fatal(err)
+	}
+
+	var record logging.RecordData
+	q := <-queries
+	if err := json.Unmarshal([]byte(q.Get("msg")), &record); err != nil {
+		t.Fatalf("invalid message %q: %v", q.Get("msg"), err)
+	}
+	if record.Message != "hello world & more" || q.Get("app") != "api" {
+		t.Errorf("unexpected query %v", q)
+	}
+	if q = <-queries; q.Get("string") != "printed" {
+		t.Errorf("unexpected query %v", q)
+	}
+}
+
+func TestHttpBackendGetEscape(t *testing.T) {
+	queries := make(chan url.Values, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries <- r.URL.Query()
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := NewHttpBackend(*u, HttpOptions{HttpGet: true}, nil)
+	defer b.Close()
+
+	const msg = "line 1\nline \"2\" 'três' 日本語 & a=b+c 100%#"
+	log := logging.NewLogger("get")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info(msg)
+	if err := b.Print(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	var record logging.RecordData
+	q := <-queries
+	if err := json.Unmarshal([]byte(q.Get("message")), &record); err != nil {
+		t.Fatalf("invalid message %q: %v", q.Get("message"), err)
+	}
+	if record.Message != msg {
+		t.Errorf("unexpected message %q", record.Message)
+	}
+	if q = <-queries; q.Get("string") != msg {
+		t.Errorf("unexpected message %q", q.Get("string"))
+	}
+}
+
+func TestHttpBackendMaxGetLen(t *testing.T) {
+	type request struct {
+		method, query, body string
+	}
+	requests := make(chan request, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- request{r.Method, r.URL.Query().Get("string"), string(body)}
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := NewHttpBackend(*u, HttpOptions{HttpGet: true, MaxGetLen: 12}, nil)
+	defer b.Close()
+
+	if err := b.Print("short"); err != nil {
+		t.Fatal(err)
+	}
+	if r := <-requests; r.method != http.MethodGet || r.query != "short" {
+		t.Errorf("unexpected request %+v", r)
+	}
+	if err := b.Print("a message longer than the limit"); err != nil {
+		t.Fatal(err)
+	}
+	if r := <-requests; r.method != http.MethodPost || r.body != "a message longer than the limit" {
+		t.Errorf("unexpected request %+v", r)
+	}
+
+	b.TruncateGet = true
+	if err := b.Print("ação açúcar"); err != nil {
+		t.Fatal(err)
+	}
+	// "a%C3%A7%C3%A3o" has 14 bytes, so the message is cut after "a%C3%A7".
+	if r := <-requests; r.method != http.MethodGet || r.query != "aç" {
+		t.Errorf("unexpected request %+v", r)
+	}
+}
+
+func TestHttpBackendResponseHandler(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Write([]byte(`{"accepted":0}`))
+		} else {
+			w.Write([]byte(`{"accepted":1}`))
+		}
+	}))
+	defer server.Close()
+
+	var accepted []int
+	u, _ := url.Parse(server.URL)
+	b := NewHttpBackend(*u, HttpOptions{
+		MaxRetries:   1,
+		RetryBackoff: time.Millisecond,
+		ResponseHandler: func(resp *http.Response) error {
+			var ack struct{ Accepted int }
+			if err := json.NewDecoder(resp.Body).Decode(&ack); err != nil {
+				return err
+			}
+			accepted = append(accepted, ack.Accepted)
+			if ack.Accepted == 0 {
+				return errors.New("record rejected")
+			}
+			return nil
+		},
+	}, nil)
+	defer b.Close()
+
+	log := logging.NewLogger("ack")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info("acked")
+
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+	if len(accepted) != 2 || accepted[0] != 0 || accepted[1] != 1 {
+		t.Errorf("unexpected acks: %v", accepted)
+	}
+}
+
+func TestHttpBackendMarshaler(t *testing.T) {
+	type request struct {
+		contentType, body string
+	}
+	requests := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- request{r.Header.Get("Content-Type"), string(body)}
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := NewHttpBackend(*u, HttpOptions{
+		Marshaler: func(rec *logging.Record) ([]byte, string, error) {
+			return []byte(rec.Level.String() + "|" + rec.Message()), "text/x-custom", nil
+		},
+	}, nil)
+	defer b.Close()
+
+	log := logging.NewLogger("marshaler")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Notice("custom")
+
+	if r := <-requests; r.contentType != "text/x-custom" || r.body != "NOTICE|custom" {
+		t.Errorf("unexpected request %+v", r)
+	}
+}
+
+func TestHttpBackendCaller(t *testing.T) {
+	received := make(chan logging.RecordData, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data logging.RecordData
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			t.Error(err)
+		}
+		received <- data
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := NewHttpBackend(*u, HttpOptions{Caller: true}, nil)
+	defer b.Close()
+
+	log := logging.NewLogger("caller")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info("located")
+
+	data := <-received
+	if data.Message != "located" || !strings.HasSuffix(data.File, "http_test.go") || data.Line == 0 ||
+		!strings.HasSuffix(data.Func, ".TestHttpBackendCaller") {
+		t.Errorf("unexpected record: %+v", data)
+	}
+}
@@ -0,0 +1,243 @@
+//go:build linux
+// +build linux
+
+package backends
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// DefaultJournaldSocket is the socket of the journald native protocol.
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+// ErrJournaldUnavailable is returned when the journald socket isn't found.
+var ErrJournaldUnavailable = errors.New("journald: socket not found")
+
+// JournaldOptions configures NewJournaldBackend.
+type JournaldOptions struct {
+	// Identifier is the SYSLOG_IDENTIFIER field. Defaults to the base name of
+	// the program.
+	Identifier string
+	// Formatted sends the formatted record as the MESSAGE field instead of
+	// the message.
+	Formatted bool
+	// Socket defaults to DefaultJournaldSocket.
+	Socket string
+}
+
+// JournaldBackend sends the records to the systemd journal using its native
+// protocol, so the fields are kept as journal fields, instead of flattened
+// into the message like on the stderr of the services.
+type JournaldBackend struct {
+	Identifier string
+
+	opts   JournaldOptions
+	addr   *net.UnixAddr
+	mu     sync.Mutex
+	conn   *net.UnixConn
+	closed bool
+}
+
+// NewJournaldBackend creates a backend which sends each record as a journal
+// entry with the fields:
+//
+//	MESSAGE            the message, or the formatted record
+//	PRIORITY           the syslog severity of the level, see SyslogSeverity
+//	SYSLOG_IDENTIFIER  the Identifier
+//	LOGGER_MODULE      the module of the record
+//	CODE_FILE          the caller file
+//	CODE_LINE          the caller line
+//	CODE_FUNC          the caller function
+//
+// The record fields are added with their keys uppercased and the characters
+// not allowed in the journal field names replaced by underscores, eg. the
+// "user.id" field is added as USER_ID. If the journald socket doesn't exist,
+// ErrJournaldUnavailable is returned.
+func NewJournaldBackend(options ...JournaldOptions) (b *JournaldBackend, err error) {
+	var opts JournaldOptions
+	for _, opts = range options {
+	}
+	if opts.Identifier == "" {
+		opts.Identifier = filepath.Base(os.Args[0])
+	}
+	if opts.Socket == "" {
+		opts.Socket = DefaultJournaldSocket
+	}
+	if _, err = os.Stat(opts.Socket); err != nil {
+		return nil, ErrJournaldUnavailable
+	}
+
+	b = &JournaldBackend{
+		Identifier: opts.Identifier,
+		opts:       opts,
+		addr:       &net.UnixAddr{Name: opts.Socket, Net: "unixgram"},
+	}
+	if b.conn, err = net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"}); err != nil {
+		return nil, err
+	}
+	register(b)
+	return
+}
+
+// journaldFieldName returns key as a valid journal field name: uppercase
+// letters, digits and underscores, not starting with an underscore nor a
+// digit. The names of the fields written by the backend itself are prefixed
+// by F_, so they aren't duplicated in the entry.
+func journaldFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return '_'
+	}, key)
+	name = strings.TrimLeft(name, "_")
+	if name != "" && (name[0] >= '0' && name[0] <= '9' || journaldReservedField(name)) {
+		name = "F_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// journaldReservedField reports whether name is written by the backend.
+func journaldReservedField(name string) bool {
+	switch name {
+	case "MESSAGE", "PRIORITY", "SYSLOG_IDENTIFIER", "LOGGER_MODULE":
+		return true
+	}
+	return strings.HasPrefix(name, "CODE_")
+}
+
+// writeJournaldField writes the field in the native protocol format. The
+// values with newlines are written as binary data, prefixed by their length.
+func writeJournaldField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if strings.IndexByte(value, '\n') < 0 {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+	} else {
+		buf.WriteByte('\n')
+		binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+		buf.WriteString(value)
+	}
+	buf.WriteByte('\n')
+}
+
+// entry returns the journal entry of the record.
+func (this *JournaldBackend) entry(level logging.Level, rec *logging.Record, msg string) []byte {
+	var buf bytes.Buffer
+	writeJournaldField(&buf, "MESSAGE", msg)
+	writeJournaldField(&buf, "PRIORITY", strconv.Itoa(SyslogSeverity(level)))
+	writeJournaldField(&buf, "SYSLOG_IDENTIFIER", this.Identifier)
+	if rec.Module != "" {
+		writeJournaldField(&buf, "LOGGER_MODULE", rec.Module)
+	}
+	if file, line, fn, ok := rec.Caller(); ok {
+		writeJournaldField(&buf, "CODE_FILE", file)
+		writeJournaldField(&buf, "CODE_LINE", strconv.Itoa(line))
+		writeJournaldField(&buf, "CODE_FUNC", fn)
+	}
+	for _, field := range rec.Fields {
+		name := journaldFieldName(field.Key)
+		if name == "" {
+			continue
+		}
+		value := field.Value
+		if redactor, ok := value.(logging.Redactor); ok {
+			value = redactor.Redacted()
+		}
+		writeJournaldField(&buf, name, fmt.Sprint(value))
+	}
+	return buf.Bytes()
+}
+
+// send writes the entry into the socket. The entries too large for a datagram
+// are written into a temporary file, whose descriptor is sent instead.
+func (this *JournaldBackend) send(entry []byte) (err error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.closed {
+		return errors.New("journald: backend is closed")
+	}
+	if _, _, err = this.conn.WriteMsgUnix(entry, nil, this.addr); err == nil {
+		return
+	}
+	if errno, ok := errorErrno(err); !ok || errno != syscall.EMSGSIZE && errno != syscall.ENOBUFS {
+		return
+	}
+
+	var f *os.File
+	if f, err = ioutil.TempFile("/dev/shm", "journal."); err != nil {
+		if f, err = ioutil.TempFile("", "journal."); err != nil {
+			return
+		}
+	}
+	defer f.Close()
+	if err = os.Remove(f.Name()); err != nil {
+		return
+	}
+	if _, err = f.Write(entry); err != nil {
+		return
+	}
+	_, _, err = this.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), this.addr)
+	return
+}
+
+// errorErrno returns the syscall.Errno of the socket error err.
+func errorErrno(err error) (syscall.Errno, bool) {
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	errno, ok := err.(syscall.Errno)
+	return errno, ok
+}
+
+// Log implements the Backend interface.
+func (this *JournaldBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	var msg string
+	if this.opts.Formatted {
+		msg = rec.Formatted(calldepth + 1)
+	} else {
+		msg = rec.Message()
+	}
+	return this.send(this.entry(level, rec, msg))
+}
+
+// Print implements the Printer interface. The values are logged with the INFO
+// priority.
+func (this *JournaldBackend) Print(args ...interface{}) error {
+	return this.send(this.entry(logging.INFO, &logging.Record{Time: time.Now()}, fmt.Sprint(args...)))
+}
+
+// Close closes the socket.
+func (this *JournaldBackend) Close() (err error) {
+	unregister(this)
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if !this.closed {
+		this.closed = true
+		err = this.conn.Close()
+	}
+	return
+}
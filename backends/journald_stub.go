@@ -0,0 +1,48 @@
+//go:build !linux
+// +build !linux
+
+package backends
+
+import (
+	"errors"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// DefaultJournaldSocket is the socket of the journald native protocol.
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+// ErrJournaldUnavailable is returned by NewJournaldBackend outside Linux.
+var ErrJournaldUnavailable = errors.New("journald: not supported on this platform")
+
+// JournaldOptions configures NewJournaldBackend.
+type JournaldOptions struct {
+	Identifier string
+	Formatted  bool
+	Socket     string
+}
+
+// JournaldBackend is only available on Linux.
+type JournaldBackend struct {
+	Identifier string
+}
+
+// NewJournaldBackend returns ErrJournaldUnavailable outside Linux.
+func NewJournaldBackend(options ...JournaldOptions) (*JournaldBackend, error) {
+	return nil, ErrJournaldUnavailable
+}
+
+// Log implements the Backend interface.
+func (this *JournaldBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	return ErrJournaldUnavailable
+}
+
+// Print implements the Printer interface.
+func (this *JournaldBackend) Print(args ...interface{}) error {
+	return ErrJournaldUnavailable
+}
+
+// Close implements the io.Closer interface.
+func (this *JournaldBackend) Close() error {
+	return nil
+}
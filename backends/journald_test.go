@@ -0,0 +1,116 @@
+//go:build linux
+// +build linux
+
+package backends
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// decodeJournald decodes the fields of a native protocol entry.
+func decodeJournald(t *testing.T, data []byte) map[string]string {
+	fields := map[string]string{}
+	for len(data) > 0 {
+		i := bytes.IndexAny(data, "=\n")
+		if i < 0 {
+			t.Fatalf("invalid entry: %q", data)
+		}
+		name := string(data[:i])
+		if data[i] == '=' {
+			data = data[i+1:]
+			j := bytes.IndexByte(data, '\n')
+			fields[name] = string(data[:j])
+			data = data[j+1:]
+		} else {
+			size := binary.LittleEndian.Uint64(data[i+1 : i+9])
+			data = data[i+9:]
+			fields[name] = string(data[:size])
+			data = data[size+1:]
+		}
+	}
+	return fields
+}
+
+func TestJournaldBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	b, err := NewJournaldBackend(JournaldOptions{Identifier: "app", Socket: socket})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	log := logging.NewLogger("web")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.WithFields("user.id", 7, "trace", "a\nb", "priority", "high").Error("failed")
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Count(buf[:n], []byte("\nPRIORITY=")) != 1 {
+		t.Errorf("duplicated PRIORITY: %q", buf[:n])
+	}
+	fields := decodeJournald(t, buf[:n])
+	for name, value := range map[string]string{
+		"MESSAGE":           "failed",
+		"PRIORITY":          "3",
+		"SYSLOG_IDENTIFIER": "app",
+		"LOGGER_MODULE":     "web",
+		"USER_ID":           "7",
+		"TRACE":             "a\nb",
+		"F_PRIORITY":        "high",
+	} {
+		if fields[name] != value {
+			t.Errorf("unexpected %s: %q", name, fields[name])
+		}
+	}
+	if !strings.HasSuffix(fields["CODE_FILE"], "journald_test.go") || fields["CODE_LINE"] == "" {
+		t.Errorf("unexpected caller: %v", fields)
+	}
+}
+
+func TestJournaldUnavailable(t *testing.T) {
+	if _, err := NewJournaldBackend(JournaldOptions{Socket: "/nonexistent/socket"}); err != ErrJournaldUnavailable {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestJournaldFieldName(t *testing.T) {
+	for key, name := range map[string]string{
+		"user.id": "USER_ID",
+		"_secret": "SECRET",
+		"2fa":     "F_2FA",
+		"Ação":    "A__O",
+		"message": "F_MESSAGE",
+		"code.id": "F_CODE_ID",
+		"module":  "MODULE",
+	} {
+		if got := journaldFieldName(key); got != name {
+			t.Errorf("%q: expected %q, got %q", key, name, got)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package backends
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// LevelFilesBackend writes the records into one file for each level range.
+type LevelFilesBackend struct {
+	logging.LeveledBackend
+	files []*FileBackend
+}
+
+// NewLevelFilesBackend opens one file backend for each path in files, using
+// NewFileBackend, and combines them into a multi logger. The file of each
+// level accepts the records of its level and of the less severe levels, up to
+// the next level in files, eg:
+//
+//	NewLevelFilesBackend(map[logging.Level]string{
+//		logging.DEBUG: "debug.log", // NOTICE, INFO and DEBUG
+//		logging.WARNING: "warning.log", // WARNING
+//		logging.ERROR: "error.log", // CRITICAL and ERROR
+//	}, FileOptions{})
+//
+// The records less severe than the least severe level in files are discarded.
+func NewLevelFilesBackend(files map[logging.Level]string, options FileOptions) (b *LevelFilesBackend, err error) {
+	levels := make([]int, 0, len(files))
+	for level := range files {
+		levels = append(levels, int(level))
+	}
+	sort.Ints(levels)
+
+	b = &LevelFilesBackend{}
+	backends := make([]logging.Backend, len(levels))
+	for i, level := range levels {
+		var fb *FileBackend
+		if fb, err = NewFileBackend(files[logging.Level(level)], options); err != nil {
+			b.Close()
+			return nil, err
+		}
+		b.files = append(b.files, fb)
+
+		min, max := logging.Level(-1), logging.Level(level)
+		if i > 0 {
+			min = logging.Level(levels[i-1])
+		}
+		backends[i] = logging.NewFilterBackend(fb, func(rec *logging.Record) bool {
+			return rec.Level > min && rec.Level <= max
+		})
+	}
+	b.LeveledBackend = logging.MultiLogger(backends...)
+	return
+}
+
+// Files returns the file backends, from the most to the least severe level.
+func (this *LevelFilesBackend) Files() []*FileBackend {
+	return this.files
+}
+
+// Flush implements the logging.Flusher interface.
+func (this *LevelFilesBackend) Flush() error {
+	return logging.FlushBackend(this.LeveledBackend)
+}
+
+// Close closes all files and removes them from the files cache.
+func (this *LevelFilesBackend) Close() (err error) {
+	for _, fb := range this.files {
+		if e := fb.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// LevelFilesConfig is the declarative form of NewLevelFilesBackend, so it can
+// be loaded from a YAML or JSON file, eg:
+//
+//	files:
+//	  debug: /var/log/app/debug.log
+//	  error: /var/log/app/error.log
+//	options:
+//	  async: true
+type LevelFilesConfig struct {
+	// Files maps the level names, as accepted by logging.LogLevel, to the
+	// paths.
+	Files   map[string]string `yaml:"files" json:"files"`
+	Options FileOptions       `yaml:"options" json:"options"`
+}
+
+// Backend creates the backend declared by the config.
+func (this LevelFilesConfig) Backend() (*LevelFilesBackend, error) {
+	files := make(map[logging.Level]string, len(this.Files))
+	for name, path := range this.Files {
+		level, err := logging.LogLevel(name)
+		if err != nil {
+			return nil, fmt.Errorf("level files: %v", err)
+		}
+		files[level] = path
+	}
+	return NewLevelFilesBackend(files, this.Options)
+}
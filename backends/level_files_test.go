@@ -0,0 +1,72 @@
+package backends
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moisespsena-go/logging"
+)
+
+func TestLevelFilesBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := LevelFilesConfig{Files: map[string]string{
+		"debug":   filepath.Join(dir, "debug.log"),
+		"warning": filepath.Join(dir, "warning.log"),
+		"error":   filepath.Join(dir, "error.log"),
+	}}
+	b, err := config.Backend()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := logging.NewLogger("level-files")
+	log.SetBackend(b)
+	log.Critical("critical")
+	log.Error("error")
+	log.Warning("warning")
+	log.Info("info")
+	log.Debug("debug")
+
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fileMap.Load(config.Files["debug"]); ok {
+		t.Error("file backend not removed from the cache")
+	}
+
+	expected := map[string][]string{
+		"error.log":   {"critical", "error"},
+		"warning.log": {"warning"},
+		"debug.log":   {"info", "debug"},
+	}
+	for name, messages := range expected {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != len(messages) {
+			t.Fatalf("%s: %q", name, data)
+		}
+		for i, msg := range messages {
+			if !strings.HasSuffix(lines[i], msg) {
+				t.Errorf("%s: %q doesn't end with %q", name, lines[i], msg)
+			}
+		}
+	}
+}
+
+func TestLevelFilesConfigInvalidLevel(t *testing.T) {
+	config := LevelFilesConfig{Files: map[string]string{"verbose": "verbose.log"}}
+	if _, err := config.Backend(); err == nil {
+		t.Error("expected an error")
+	}
+}
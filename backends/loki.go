@@ -0,0 +1,200 @@
+package backends
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// DefaultLokiBatchSize is the number of records sent in each push request when
+// LokiOptions.BatchSize is zero.
+const DefaultLokiBatchSize = 1000
+
+// LokiOptions configures NewLokiBackend.
+type LokiOptions struct {
+	// HttpOptions configures the requests. The records are always sent in
+	// batches of BatchSize, which defaults to DefaultLokiBatchSize, and
+	// HttpGet is ignored. Formatted sends the formatted records as the log
+	// lines, instead of the message followed by the fields not used as
+	// labels.
+	HttpOptions
+
+	// URL of the Loki server, eg. "http://localhost:3100".
+	URL string
+	// Username and Password enable the basic authentication.
+	Username string
+	Password string
+	// TenantID is sent in the X-Scope-OrgID header.
+	TenantID string
+	// Labels are added to all streams, eg. {"job": "api"}.
+	Labels map[string]string
+	// FieldLabels are the keys of the fields used as labels, in addition to
+	// the module and level labels.
+	FieldLabels []string
+}
+
+// LokiBackend pushes the records to Grafana Loki, grouped into streams by the
+// module, the level and the FieldLabels.
+type LokiBackend struct {
+	*HttpBackend
+	Labels      map[string]string
+	FieldLabels []string
+}
+
+// lokiEntry is the batch item of a record.
+type lokiEntry struct {
+	Labels map[string]string `json:"l"`
+	Time   string            `json:"t"`
+	Line   string            `json:"m"`
+}
+
+// lokiStream is a stream of the push request.
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// NewLokiBackend creates a backend which pushes the records in batches to the
+// /loki/api/v1/push endpoint of opts.URL. The batch is sent when full, on each
+// FlushInterval and on Flush or Close.
+func NewLokiBackend(opts LokiOptions) (b *LokiBackend, err error) {
+	var u *url.URL
+	if u, err = url.Parse(opts.URL); err != nil {
+		return
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/loki/api/v1/push"
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultLokiBatchSize
+	}
+
+	headers := http.Header{}
+	for key, values := range opts.Headers {
+		headers[key] = values
+	}
+	if opts.Username != "" {
+		headers.Set("Authorization", basicAuth(opts.Username, opts.Password))
+	}
+	if opts.TenantID != "" {
+		headers.Set("X-Scope-OrgID", opts.TenantID)
+	}
+
+	httpOpts := opts.HttpOptions
+	httpOpts.HttpGet, httpOpts.BatchSize = false, 0
+	httpOpts.Headers = headers
+
+	b = &LokiBackend{NewHttpBackend(*u, httpOpts, nil), opts.Labels, opts.FieldLabels}
+	b.Logger = logging.WithPrefix(log_, "loki")
+	b.batch = newHttpBatchEncoder(b.HttpBackend, opts.BatchSize, opts.FlushInterval, b.encode, b.request)
+	return
+}
+
+// lokiLabelName replaces the characters not allowed in the label names by
+// underscores.
+func lokiLabelName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// encode returns the labels, the timestamp and the line of the record.
+func (this *LokiBackend) encode(calldepth int, rec *logging.Record) ([]byte, error) {
+	entry := lokiEntry{
+		Labels: map[string]string{
+			"module": rec.Module,
+			"level":  strings.ToLower(rec.Level.String()),
+		},
+		Time: strconv.FormatInt(rec.Time.UnixNano(), 10),
+	}
+	for name, value := range this.Labels {
+		entry.Labels[lokiLabelName(name)] = value
+	}
+
+	var fields logging.Fields
+	for _, field := range rec.Fields {
+		var label bool
+		for _, key := range this.FieldLabels {
+			if field.Key == key {
+				label = true
+				break
+			}
+		}
+		if !label {
+			fields = append(fields, field)
+			continue
+		}
+		value := field.Value
+		if redactor, ok := value.(logging.Redactor); ok {
+			value = redactor.Redacted()
+		}
+		entry.Labels[lokiLabelName(field.Key)] = fmt.Sprint(value)
+	}
+
+	if this.Formatted {
+		entry.Line = rec.Formatted(calldepth + 1)
+	} else {
+		entry.Line = rec.Message()
+		if len(fields) > 0 {
+			entry.Line += " " + fields.String()
+		}
+	}
+	return json.Marshal(entry)
+}
+
+// request returns the push request of the items, grouped into streams by
+// their labels.
+func (this *LokiBackend) request(items []json.RawMessage) (*httpRequest, error) {
+	var (
+		streams []*lokiStream
+		byKey   = map[string]*lokiStream{}
+	)
+	for _, item := range items {
+		var entry lokiEntry
+		if err := json.Unmarshal(item, &entry); err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(entry.Labels))
+		for name := range entry.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var key strings.Builder
+		for _, name := range names {
+			fmt.Fprintf(&key, "%s=%q,", name, entry.Labels[name])
+		}
+
+		stream, ok := byKey[key.String()]
+		if !ok {
+			stream = &lokiStream{Stream: entry.Labels}
+			byKey[key.String()] = stream
+			streams = append(streams, stream)
+		}
+		stream.Values = append(stream.Values, [2]string{entry.Time, entry.Line})
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"streams": streams})
+	if err != nil {
+		return nil, err
+	}
+	return &httpRequest{method: http.MethodPost, url: this.URL.String(), body: body}, nil
+}
+
+// Print implements the Printer interface. The values are pushed as an INFO
+// record.
+func (this *LokiBackend) Print(args ...interface{}) error {
+	return this.batch.add(1, &logging.Record{Time: time.Now(), Level: logging.INFO, Args: args})
+}
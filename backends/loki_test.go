@@ -0,0 +1,93 @@
+package backends
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+func TestLokiBackend(t *testing.T) {
+	var pushes [][]lokiStream
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/push" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if tenant := r.Header.Get("X-Scope-OrgID"); tenant != "acme" {
+			t.Errorf("unexpected tenant %q", tenant)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "bob" || pass != "secret" {
+			t.Errorf("unexpected auth %q", r.Header.Get("Authorization"))
+		}
+		var push struct{ Streams []lokiStream }
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &push); err != nil {
+			t.Errorf("invalid push %q: %v", body, err)
+		}
+		pushes = append(pushes, push.Streams)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	b, err := NewLokiBackend(LokiOptions{
+		HttpOptions: HttpOptions{FlushInterval: time.Hour},
+		URL:         server.URL,
+		Username:    "bob",
+		Password:    "secret",
+		TenantID:    "acme",
+		Labels:      map[string]string{"job": "api"},
+		FieldLabels: []string{"app.region"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now()
+	log := logging.NewLogger("loki")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info("first")
+	log.WithFields("app.region", "eu", "user", "bob").Info("second")
+	log.Info("third")
+	log.Error("failed")
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pushes) != 1 || len(pushes[0]) != 3 {
+		t.Fatalf("unexpected pushes: %v", pushes)
+	}
+	expected := []struct {
+		labels map[string]string
+		lines  []string
+	}{
+		{map[string]string{"job": "api", "module": "loki", "level": "info"}, []string{"first", "third"}},
+		{map[string]string{"job": "api", "module": "loki", "level": "info", "app_region": "eu"}, []string{"second user=bob"}},
+		{map[string]string{"job": "api", "module": "loki", "level": "error"}, []string{"failed"}},
+	}
+	for i, stream := range pushes[0] {
+		if len(stream.Stream) != len(expected[i].labels) {
+			t.Errorf("unexpected labels %v", stream.Stream)
+		}
+		for name, value := range expected[i].labels {
+			if stream.Stream[name] != value {
+				t.Errorf("unexpected labels %v", stream.Stream)
+			}
+		}
+		if len(stream.Values) != len(expected[i].lines) {
+			t.Fatalf("unexpected values %v", stream.Values)
+		}
+		for j, line := range expected[i].lines {
+			if stream.Values[j][1] != line {
+				t.Errorf("%q != %q", stream.Values[j][1], line)
+			}
+			if ns, err := strconv.ParseInt(stream.Values[j][0], 10, 64); err != nil || ns < before.UnixNano() {
+				t.Errorf("invalid timestamp %q", stream.Values[j][0])
+			}
+		}
+	}
+}
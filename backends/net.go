@@ -0,0 +1,290 @@
+package backends
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// DefaultNetWriteTimeout is the deadline of each write when
+// NetOptions.WriteTimeout is zero.
+const DefaultNetWriteTimeout = 5 * time.Second
+
+type NetOptions struct {
+	// Formatted sends the formatted record instead of the JSON of the record
+	// data.
+	Formatted bool
+	// DialTimeout defaults to 2 seconds.
+	DialTimeout time.Duration
+	// WriteTimeout is the deadline of each write. Defaults to
+	// DefaultNetWriteTimeout. A negative value disables it.
+	WriteTimeout time.Duration
+	// MinBackoff and MaxBackoff are the bounds of the exponential delay between
+	// TCP reconnections. Defaults to 100 milliseconds and 30 seconds.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// BufferSize is the number of lines kept until they are sent into the TCP
+	// connection, eg. while it's down. The oldest lines are dropped when full.
+	// Defaults to 1024.
+	BufferSize int
+}
+
+// NetBackend writes each record as a line into a TCP or UDP connection.
+type NetBackend struct {
+	Network   string
+	Addr      string
+	Formatted bool
+
+	opts    NetOptions
+	mu      sync.Mutex
+	closed  bool
+	pending [][]byte
+
+	// conn, backoff and nextDial of the TCP backends are only used by the
+	// goroutine sending the pending lines.
+	conn     net.Conn
+	backoff  time.Duration
+	nextDial time.Time
+
+	wake    chan struct{}
+	flushes chan chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewTCPBackend creates a backend which writes the records into a TCP
+// connection. The lines are appended to a buffer and sent by a goroutine, so
+// a slow or stuck peer doesn't block the logging calls. The connection is
+// opened on the first record and reopened, with exponential backoff, after
+// failures, which are passed to the error handler. While disconnected, up to
+// BufferSize lines are kept and sent after the reconnection.
+func NewTCPBackend(addr string, opts NetOptions) (b *NetBackend, err error) {
+	if _, _, err = net.SplitHostPort(addr); err != nil {
+		return
+	}
+	b = newNetBackend("tcp", addr, opts)
+	b.wake = make(chan struct{}, 1)
+	b.flushes = make(chan chan struct{})
+	b.stop = make(chan struct{})
+	b.stopped = make(chan struct{})
+	go b.run()
+	return
+}
+
+// NewUDPBackend creates a backend which writes each record as a datagram. Lines
+// are fire-and-forget: they are neither buffered nor resent.
+func NewUDPBackend(addr string, opts NetOptions) (b *NetBackend, err error) {
+	b = newNetBackend("udp", addr, opts)
+	if b.conn, err = net.DialTimeout("udp", addr, b.opts.DialTimeout); err != nil {
+		unregister(b)
+		return nil, err
+	}
+	return
+}
+
+func newNetBackend(network, addr string, opts NetOptions) *NetBackend {
+	if opts.DialTimeout == 0 {
+		opts.DialTimeout = 2 * time.Second
+	}
+	if opts.WriteTimeout == 0 {
+		opts.WriteTimeout = DefaultNetWriteTimeout
+	}
+	if opts.MinBackoff == 0 {
+		opts.MinBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	if opts.BufferSize == 0 {
+		opts.BufferSize = 1024
+	}
+	b := &NetBackend{
+		Network:   network,
+		Addr:      addr,
+		Formatted: opts.Formatted,
+		opts:      opts,
+	}
+	register(b)
+	return b
+}
+
+func (this *NetBackend) write(line []byte) (err error) {
+	if this.opts.WriteTimeout > 0 {
+		this.conn.SetWriteDeadline(time.Now().Add(this.opts.WriteTimeout))
+	}
+	_, err = this.conn.Write(line)
+	return
+}
+
+// send writes the UDP datagram, or appends the line to the TCP buffer and
+// wakes up the goroutine sending it.
+func (this *NetBackend) send(line []byte) (err error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	if this.closed {
+		return fmt.Errorf("%s %q is closed", this.Network, this.Addr)
+	}
+	if this.Network == "udp" {
+		return this.write(line)
+	}
+
+	this.queue(line)
+	select {
+	case this.wake <- struct{}{}:
+	default:
+	}
+	return
+}
+
+// queue appends the lines to the pending ones, dropping the oldest ones above
+// BufferSize. The caller must hold mu.
+func (this *NetBackend) queue(lines ...[]byte) {
+	this.pending = append(this.pending, lines...)
+	if over := len(this.pending) - this.opts.BufferSize; over > 0 {
+		this.pending = this.pending[over:]
+	}
+}
+
+// run sends the pending lines when woken up, flushed or, after a failure,
+// when it's time to reconnect, until the backend is closed.
+func (this *NetBackend) run() {
+	defer close(this.stopped)
+	var retry <-chan time.Time
+	for {
+		var flushed chan struct{}
+		select {
+		case <-this.stop:
+			if this.conn != nil {
+				this.conn.Close()
+			}
+			return
+		case <-this.wake:
+		case <-retry:
+		case flushed = <-this.flushes:
+		}
+		retry = nil
+		if wait := this.sendPending(); wait > 0 {
+			retry = time.After(wait)
+		}
+		if flushed != nil {
+			close(flushed)
+		}
+	}
+}
+
+// sendPending writes the pending lines, connecting if required. After a
+// failure, the lines not sent are kept and the time to wait before retrying
+// is returned.
+func (this *NetBackend) sendPending() (retry time.Duration) {
+	this.mu.Lock()
+	lines := this.pending
+	this.pending = nil
+	this.mu.Unlock()
+	if len(lines) == 0 {
+		return
+	}
+
+	defer func() {
+		if len(lines) > 0 {
+			this.mu.Lock()
+			this.pending = append(lines, this.pending...)
+			this.queue()
+			this.mu.Unlock()
+		}
+	}()
+
+	var err error
+	if this.conn == nil {
+		if wait := time.Until(this.nextDial); wait > 0 {
+			return wait
+		}
+		if this.conn, err = net.DialTimeout(this.Network, this.Addr, this.opts.DialTimeout); err != nil {
+			this.conn = nil
+			return this.fail(err)
+		}
+	}
+	for len(lines) > 0 {
+		if err = this.write(lines[0]); err != nil {
+			this.conn.Close()
+			this.conn = nil
+			return this.fail(err)
+		}
+		lines = lines[1:]
+	}
+	this.backoff = 0
+	return
+}
+
+// fail reports err and schedules the next reconnection, returning the time to
+// wait for it.
+func (this *NetBackend) fail(err error) time.Duration {
+	handleAsyncError(log_, err, "%s %q failed", this.Network, this.Addr)
+	if this.backoff == 0 {
+		this.backoff = this.opts.MinBackoff
+	} else if this.backoff *= 2; this.backoff > this.opts.MaxBackoff {
+		this.backoff = this.opts.MaxBackoff
+	}
+	this.nextDial = time.Now().Add(this.backoff)
+	return this.backoff
+}
+
+// Log implements the Backend interface.
+func (this *NetBackend) Log(level logging.Level, calldepth int, rec *logging.Record) (err error) {
+	var line []byte
+	if this.Formatted {
+		line = []byte(rec.Formatted(calldepth + 1))
+	} else if line, err = json.Marshal(rec.Data()); err != nil {
+		return
+	}
+	return this.send(append(line, '\n'))
+}
+
+// Print implements the Printer interface.
+func (this *NetBackend) Print(args ...interface{}) error {
+	return this.send([]byte(fmt.Sprint(args...) + "\n"))
+}
+
+// Flush implements the logging.Flusher interface. It waits for an attempt to
+// send the pending TCP lines, which fails while the connection is down and
+// it's not time to reconnect yet.
+func (this *NetBackend) Flush() error {
+	if this.Network == "udp" {
+		return nil
+	}
+	flushed := make(chan struct{})
+	select {
+	case this.flushes <- flushed:
+		<-flushed
+	case <-this.stopped:
+	}
+	return nil
+}
+
+// Close sends the pending lines, as Flush, and closes the connection. The
+// lines not sent are discarded.
+func (this *NetBackend) Close() (err error) {
+	unregister(this)
+	this.mu.Lock()
+	if this.closed {
+		this.mu.Unlock()
+		return
+	}
+	this.closed = true
+	this.mu.Unlock()
+
+	if this.Network == "udp" {
+		return this.conn.Close()
+	}
+	this.Flush()
+	close(this.stop)
+	<-this.stopped
+	this.mu.Lock()
+	this.pending = nil
+	this.mu.Unlock()
+	return
+}
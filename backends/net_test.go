@@ -0,0 +1,135 @@
+package backends
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+func TestTCPBackend(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	b, err := NewTCPBackend(addr, NetOptions{Formatted: true, MinBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	log := logging.NewLogger("tcp")
+	log.SetBackend(logging.AddModuleLevel(b))
+
+	// The server is down, the line must be buffered.
+	log.Info("first")
+
+	if l, err = net.Listen("tcp", addr); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	time.Sleep(5 * time.Millisecond)
+	log.Info("second")
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for _, expected := range []string{"first\n", "second\n"} {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(line, expected) {
+			t.Errorf("%q != %q", line, expected)
+		}
+	}
+}
+
+func TestTCPBackendStuckPeer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	// the peer accepts the connection but never reads from it
+	conns := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := l.Accept(); err == nil {
+			conns <- conn
+		}
+	}()
+
+	b, err := NewTCPBackend(l.Addr().String(), NetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.opts.WriteTimeout != DefaultNetWriteTimeout {
+		t.Errorf("unexpected default write timeout %v", b.opts.WriteTimeout)
+	}
+	b.Close()
+
+	b, err = NewTCPBackend(l.Addr().String(), NetOptions{WriteTimeout: 50 * time.Millisecond, BufferSize: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line := strings.Repeat("x", 64<<10)
+	start := time.Now()
+	for i := 0; i < 256; i++ {
+		if err = b.Print(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("logging blocked by the peer for %v", d)
+	}
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if conn := <-conns; conn != nil {
+		conn.Close()
+	}
+}
+
+func TestUDPBackend(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	b, err := NewUDPBackend(pc.LocalAddr().String(), NetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	log := logging.NewLogger("udp")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info("datagram")
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data logging.RecordData
+	if err = json.Unmarshal(buf[:n], &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Message != "datagram" || data.Module != "udp" {
+		t.Errorf("unexpected data: %+v", data)
+	}
+}
@@ -0,0 +1,360 @@
+package backends
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	path_helpers "github.com/moisespsena-go/path-helpers"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// RotateOptions configures NewRotatingFileBackend.
+type RotateOptions struct {
+	FileOptions
+
+	// MaxSizeBytes rotates the file once it exceeds this size. Zero disables
+	// size based rotation.
+	MaxSizeBytes int64
+	// MaxAge rotates the file once it has been opened for longer than this
+	// duration. Zero disables age based rotation.
+	MaxAge time.Duration
+	// Daily rotates the file when the local date changes.
+	Daily bool
+	// MaxBackups is the number of rotated files kept as path.1, path.2, etc.
+	// Zero keeps all of them.
+	MaxBackups int
+	// Compress gzips the rotated files (path.1.gz, path.2.gz, etc). The file
+	// is renamed to path.1 and compressed in background, so the rotation
+	// doesn't wait for it. If the compression fails, the error is passed to
+	// the error handler and the file is kept uncompressed, shifted as the
+	// compressed ones.
+	Compress bool
+}
+
+// equal reports whether the options are the same, see FileOptions.equal.
+func (this RotateOptions) equal(other RotateOptions) bool {
+	return this.FileOptions.equal(other.FileOptions) &&
+		this.MaxSizeBytes == other.MaxSizeBytes &&
+		this.MaxAge == other.MaxAge &&
+		this.Daily == other.Daily &&
+		this.MaxBackups == other.MaxBackups &&
+		this.Compress == other.Compress
+}
+
+// rotatingFile is an io.WriteCloser which swaps the underlying file when one of
+// the rotation conditions is reached.
+type rotatingFile struct {
+	path     string
+	options  RotateOptions
+	mu       sync.Mutex
+	f        *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+	// compressing waits for the background compression of path.1.
+	compressing sync.WaitGroup
+}
+
+func (this *rotatingFile) open() (err error) {
+	var (
+		f    *os.File
+		info os.FileInfo
+	)
+	if f, err = os.OpenFile(this.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, this.options.Perm); err != nil {
+		return
+	}
+	if info, err = f.Stat(); err != nil {
+		f.Close()
+		return
+	}
+	this.f = f
+	this.size = info.Size()
+	this.openedAt = time.Now()
+	return
+}
+
+func (this *rotatingFile) shouldRotate(n int, now time.Time) bool {
+	if this.size == 0 {
+		return false
+	}
+	if this.options.MaxSizeBytes > 0 && this.size+int64(n) > this.options.MaxSizeBytes {
+		return true
+	}
+	if this.options.MaxAge > 0 && now.Sub(this.openedAt) >= this.options.MaxAge {
+		return true
+	}
+	if this.options.Daily {
+		y1, m1, d1 := this.openedAt.Date()
+		y2, m2, d2 := now.Date()
+		return y1 != y2 || m1 != m2 || d1 != d2
+	}
+	return false
+}
+
+func (this *rotatingFile) backupName(i int) string {
+	return this.backupPath(i, this.options.Compress)
+}
+
+func (this *rotatingFile) backupPath(i int, compressed bool) string {
+	name := this.path + "." + strconv.Itoa(i)
+	if compressed {
+		name += ".gz"
+	}
+	return name
+}
+
+// backupExists reports whether path.i or path.i.gz exists. With Compress,
+// path.i is left uncompressed if its compression fails.
+func (this *rotatingFile) backupExists(i int) bool {
+	for _, compressed := range []bool{true, false} {
+		if _, err := os.Stat(this.backupPath(i, compressed)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *rotatingFile) rotate() (err error) {
+	if err = this.f.Close(); err != nil {
+		return
+	}
+	this.f = nil
+
+	// path.1 is still being compressed by the previous rotation.
+	this.compressing.Wait()
+
+	// Shift path.N to path.N+1, dropping the ones above MaxBackups. Both the
+	// compressed and the uncompressed backups are shifted, so the ones whose
+	// compression failed aren't overwritten.
+	var last int
+	for last = 1; this.backupExists(last); last++ {
+	}
+	for i := last - 1; i >= 1; i-- {
+		for _, compressed := range []bool{true, false} {
+			name := this.backupPath(i, compressed)
+			if _, err := os.Stat(name); err != nil {
+				continue
+			}
+			if this.options.MaxBackups > 0 && i >= this.options.MaxBackups {
+				err = os.Remove(name)
+			} else {
+				err = os.Rename(name, this.backupPath(i+1, compressed))
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	if !this.options.Compress {
+		if err = os.Rename(this.path, this.backupName(1)); err != nil {
+			return
+		}
+		return this.open()
+	}
+
+	src := this.path + ".1"
+	if err = os.Rename(this.path, src); err != nil {
+		return
+	}
+	this.compressing.Add(1)
+	go func(dst string) {
+		defer this.compressing.Done()
+		if err := compressFile(src, dst, this.options.Perm); err != nil {
+			handleAsyncError(log_, err, "compress %q failed", src)
+		}
+	}(this.backupName(1))
+	return this.open()
+}
+
+// Write implements io.Writer. It rotates the file before writing p if
+// required.
+func (this *rotatingFile) Write(p []byte) (n int, err error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	if this.closed {
+		return 0, os.ErrClosed
+	}
+	if this.f == nil {
+		if err = this.open(); err != nil {
+			return
+		}
+	}
+	if this.shouldRotate(len(p), time.Now()) {
+		if err = this.rotate(); err != nil {
+			return
+		}
+	}
+	n, err = this.f.Write(p)
+	this.size += int64(n)
+	return
+}
+
+// Reopen closes the current file and opens the path again.
+func (this *rotatingFile) Reopen() (err error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.closed {
+		return os.ErrClosed
+	}
+	if this.f != nil {
+		this.f.Close()
+		this.f = nil
+	}
+	return this.open()
+}
+
+// Sync commits the current file to the stable storage.
+func (this *rotatingFile) Sync() error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.f == nil {
+		return nil
+	}
+	return this.f.Sync()
+}
+
+// logged syncs the file if level is at or above the sync level.
+func (this *rotatingFile) logged(level logging.Level) error {
+	if level <= this.options.SyncOnLevel {
+		return this.Sync()
+	}
+	return nil
+}
+
+// Close implements io.Closer. It waits for the background compression.
+func (this *rotatingFile) Close() (err error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.closed = true
+	if this.f != nil {
+		err = this.f.Close()
+		this.f = nil
+	}
+	this.compressing.Wait()
+	return
+}
+
+// compressFile writes src gzipped into dst and removes src.
+func compressFile(src, dst string, perm os.FileMode) (err error) {
+	var in, out *os.File
+	if in, err = os.Open(src); err != nil {
+		return
+	}
+	defer in.Close()
+
+	if out, err = os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm); err != nil {
+		return
+	}
+	gz := gzip.NewWriter(out)
+	if _, err = io.Copy(gz, in); err == nil {
+		err = gz.Close()
+	}
+	if e := out.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(dst)
+		return
+	}
+	return os.Remove(src)
+}
+
+// RotatingFileBackend is a FileBackend which rotates its file by size and/or
+// time.
+type RotatingFileBackend struct {
+	*FileBackend
+	options RotateOptions
+}
+
+// NewRotatingFileBackend creates a file backend which rotates path when one of
+// the conditions in options is reached, renaming the old file to path.1,
+// path.2, etc. Like NewFileBackend, the backends are cached by path.
+func NewRotatingFileBackend(path string, options RotateOptions) (b *RotatingFileBackend, err error) {
+	if options.Perm == 0 {
+		options.Perm = 0666
+	}
+
+	if v, ok := fileMap.Load(path); ok {
+		var isRotating bool
+		if b, isRotating = v.(*RotatingFileBackend); !isRotating {
+			err = fmt.Errorf("file %q already opened without rotation", path)
+		} else if !b.options.equal(options) {
+			b, err = nil, fmt.Errorf("file %q already opened with other options", path)
+		}
+		return
+	}
+
+	if err = path_helpers.MkdirAllIfNotExists(filepath.Dir(path)); err != nil {
+		return
+	}
+
+	if options.Truncate {
+		var f *os.File
+		if f, err = os.Create(path); err != nil {
+			return
+		}
+		f.Close()
+	}
+
+	w := &rotatingFile{path: path, options: options}
+	if err = w.open(); err != nil {
+		return
+	}
+
+	b = &RotatingFileBackend{
+		&FileBackend{
+			path,
+			options.FileOptions,
+			newWriteCloserBackend("file", "file:"+path, newBufferedWriter(w, options.FileOptions), options.Async),
+		},
+		options,
+	}
+	b.QueueSize = options.QueueSize
+	b.Workers = options.Workers
+	b.Overflow = options.Overflow
+	b.DroppedReportInterval = options.DroppedReportInterval
+	unregister(b.WriteCloserBackend)
+	register(b)
+	fileMap.Store(path, b)
+	return
+}
+
+// Rotate forces the rotation of the file.
+func (this *RotatingFileBackend) Rotate() (err error) {
+	var w *rotatingFile
+	if b, ok := this.WriteCloser.(*bufferedWriter); ok {
+		if err = b.Flush(); err != nil {
+			return
+		}
+		w = b.w.(*rotatingFile)
+	} else {
+		w = this.WriteCloser.(*rotatingFile)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.f == nil {
+		if err = w.open(); err != nil {
+			return
+		}
+	}
+	return w.rotate()
+}
+
+// Close closes the file and removes the backend from the files cache.
+func (this *RotatingFileBackend) Close() error {
+	unregister(this)
+	uncache(this.path, this)
+	return this.WriteCloserBackend.Close()
+}
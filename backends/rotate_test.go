@@ -0,0 +1,188 @@
+package backends
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moisespsena-go/logging"
+)
+
+func TestRotatingFileBackendSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	b, err := NewRotatingFileBackend(path, RotateOptions{MaxSizeBytes: 10, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	for _, line := range []string{"first", "second", "third", "fourth"} {
+		if err := b.Print(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for name, content := range expected {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("%s: %q != %q", name, data, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("unexpected backup file %s.3", path)
+	}
+}
+
+func TestRotatingFileBackendCacheOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	options := RotateOptions{MaxSizeBytes: 10}
+	options.PrintFormatter = partsFormatter{"a"}
+	b, err := NewRotatingFileBackend(path, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	options.PrintFormatter = partsFormatter{"b"}
+	if cached, err := NewRotatingFileBackend(path, options); err != nil || cached != b {
+		t.Errorf("rotating file backend is not cached: %v", err)
+	}
+	options.MaxBackups = 3
+	if _, err := NewRotatingFileBackend(path, options); err == nil {
+		t.Errorf("expected an error for other options")
+	}
+	if fb, err := NewFileBackend(path, options.FileOptions); err == nil || fb != nil {
+		t.Errorf("expected an error for the path opened with rotation")
+	}
+}
+
+func TestRotatingFileBackendCompress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	b, err := NewRotatingFileBackend(path, RotateOptions{Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	b.Print("compressed")
+	if err = b.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	b.Print("second")
+	if err = b.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	// Close waits for the background compression.
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path + ".1.gz"); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path + ".2.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "compressed") {
+		t.Errorf("unexpected rotated content: %q", data)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Errorf("uncompressed backup was not removed")
+	}
+}
+
+func TestRotatingFileBackendCompressFailed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	b, err := NewRotatingFileBackend(path, RotateOptions{Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	// path.1 left by a failed compression
+	if err = ioutil.WriteFile(path+".1", []byte("uncompressed\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	b.Print("current")
+	if err = b.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadFile(path + ".2"); err != nil || string(data) != "uncompressed\n" {
+		t.Errorf("the uncompressed backup was lost: %q %v", data, err)
+	}
+	if _, err = os.Stat(path + ".1.gz"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRotatingFileBackendLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	b, err := NewRotatingFileBackend(path, RotateOptions{MaxSizeBytes: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	log := logging.NewLogger("rotate")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info("one")
+	log.Info("two")
+
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Errorf("file was not rotated: %v", err)
+	}
+}
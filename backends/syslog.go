@@ -0,0 +1,286 @@
+package backends
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// SyslogPriority is the facility of the syslog messages. Its values are the
+// same of log/syslog, so a syslog.Priority can be converted into it. The
+// severity bits are ignored: it's taken from the level of each record.
+type SyslogPriority int
+
+// Syslog facilities.
+const (
+	SyslogKern   SyslogPriority = 0 << 3
+	SyslogUser   SyslogPriority = 1 << 3
+	SyslogDaemon SyslogPriority = 3 << 3
+	SyslogAuth   SyslogPriority = 4 << 3
+	SyslogLocal0 SyslogPriority = 16 << 3
+	SyslogLocal1 SyslogPriority = 17 << 3
+	SyslogLocal2 SyslogPriority = 18 << 3
+	SyslogLocal3 SyslogPriority = 19 << 3
+	SyslogLocal4 SyslogPriority = 20 << 3
+	SyslogLocal5 SyslogPriority = 21 << 3
+	SyslogLocal6 SyslogPriority = 22 << 3
+	SyslogLocal7 SyslogPriority = 23 << 3
+)
+
+// syslogSeverities maps the levels to the syslog severities.
+var syslogSeverities = []int{
+	logging.CRITICAL: 2, // crit
+	logging.ERROR:    3, // err
+	logging.WARNING:  4, // warning
+	logging.NOTICE:   5, // notice
+	logging.INFO:     6, // info
+	logging.DEBUG:    7, // debug
+}
+
+// SyslogSeverity returns the syslog severity of level.
+func SyslogSeverity(level logging.Level) int {
+	if level < 0 || int(level) >= len(syslogSeverities) {
+		return 7
+	}
+	return syslogSeverities[level]
+}
+
+// ErrSyslogUnavailable is returned when no local syslog socket is found.
+var ErrSyslogUnavailable = errors.New("syslog: local syslog socket not found")
+
+// syslogSockets are the paths of the local syslog daemon socket.
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogOptions configures NewRFC5424Backend.
+type SyslogOptions struct {
+	// Network and Addr of the syslog server, eg. "udp" and "localhost:514".
+	// Empty connects to the local syslog socket.
+	Network string
+	Addr    string
+	// Hostname defaults to os.Hostname.
+	Hostname string
+	// Formatted sends the formatted record instead of the message. The fields
+	// are sent as structured data anyway.
+	Formatted bool
+	// DialTimeout defaults to 2 seconds.
+	DialTimeout time.Duration
+}
+
+// SyslogBackend writes the records to a syslog daemon using the RFC3164 or
+// RFC5424 formats.
+type SyslogBackend struct {
+	Priority SyslogPriority
+	Tag      string
+
+	opts    SyslogOptions
+	rfc5424 bool
+	pid     string
+	mu      sync.Mutex
+	conn    net.Conn
+	closed  bool
+}
+
+// NewSyslogBackend connects to the local syslog daemon and writes the
+// formatted records using the traditional RFC3164 format. If tag is empty, the
+// base name of the program is used. On platforms without a local syslog
+// socket, like Windows, ErrSyslogUnavailable is returned.
+func NewSyslogBackend(priority SyslogPriority, tag string) (*SyslogBackend, error) {
+	return newSyslogBackend(priority, tag, false, SyslogOptions{Formatted: true})
+}
+
+// NewRFC5424Backend connects to a syslog daemon and writes the records using
+// the RFC5424 format. The module of the record is used as MSGID and the fields
+// are sent as the "fields@32473" structured data element.
+func NewRFC5424Backend(priority SyslogPriority, tag string, opts SyslogOptions) (*SyslogBackend, error) {
+	return newSyslogBackend(priority, tag, true, opts)
+}
+
+func newSyslogBackend(priority SyslogPriority, tag string, rfc5424 bool, opts SyslogOptions) (b *SyslogBackend, err error) {
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+	if opts.Hostname == "" {
+		if opts.Hostname, _ = os.Hostname(); opts.Hostname == "" {
+			opts.Hostname = "-"
+		}
+	}
+	if opts.DialTimeout == 0 {
+		opts.DialTimeout = 2 * time.Second
+	}
+	b = &SyslogBackend{
+		Priority: priority &^ 7,
+		Tag:      tag,
+		opts:     opts,
+		rfc5424:  rfc5424,
+		pid:      strconv.Itoa(os.Getpid()),
+	}
+	if err = b.connect(); err != nil {
+		return nil, err
+	}
+	register(b)
+	return
+}
+
+func (this *SyslogBackend) connect() (err error) {
+	if this.opts.Network != "" {
+		this.conn, err = net.DialTimeout(this.opts.Network, this.opts.Addr, this.opts.DialTimeout)
+		return
+	}
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range syslogSockets {
+			if this.conn, err = net.DialTimeout(network, path, this.opts.DialTimeout); err == nil {
+				return
+			}
+		}
+	}
+	return ErrSyslogUnavailable
+}
+
+// stream reports whether the messages needs framing.
+func (this *SyslogBackend) stream() bool {
+	switch this.conn.(type) {
+	case *net.TCPConn:
+		return true
+	case *net.UnixConn:
+		return this.conn.LocalAddr().Network() == "unix"
+	}
+	return false
+}
+
+// message returns the syslog message of msg with the header built from rec.
+func (this *SyslogBackend) message(level logging.Level, rec *logging.Record, msg string) string {
+	pri := int(this.Priority) | SyslogSeverity(level)
+
+	if !this.rfc5424 {
+		return fmt.Sprintf("<%d>%s %s[%s]: %s", pri, rec.Time.Format(time.Stamp), this.Tag, this.pid, msg)
+	}
+
+	msgID := syslogName(rec.Module, 32)
+	if msgID == "" {
+		msgID = "-"
+	}
+	return fmt.Sprintf("<%d>1 %s %s %s %s %s %s %s",
+		pri,
+		rec.Time.Format("2006-01-02T15:04:05.999999Z07:00"),
+		syslogName(this.opts.Hostname, 255),
+		syslogName(this.Tag, 48),
+		this.pid,
+		msgID,
+		structuredData(rec.Fields),
+		msg,
+	)
+}
+
+// syslogName removes the characters not allowed in the header fields and
+// truncates name to max bytes.
+func syslogName(name string, max int) string {
+	name = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, name)
+	if len(name) > max {
+		name = name[:max]
+	}
+	return name
+}
+
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// structuredData returns the fields as a RFC5424 structured data element, or
+// "-" if there are no fields.
+func structuredData(fields logging.Fields) string {
+	if len(fields) == 0 {
+		return "-"
+	}
+	var b strings.Builder
+	b.WriteString("[fields@32473")
+	for _, field := range fields {
+		name := strings.Map(func(r rune) rune {
+			if r == '=' || r == ']' || r == '"' {
+				return -1
+			}
+			return r
+		}, syslogName(field.Key, 32))
+		if name == "" {
+			continue
+		}
+		value := field.Value
+		if redactor, ok := value.(logging.Redactor); ok {
+			value = redactor.Redacted()
+		}
+		fmt.Fprintf(&b, ` %s="%s"`, name, sdEscaper.Replace(fmt.Sprint(value)))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func (this *SyslogBackend) write(msg string) (err error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	if this.closed {
+		return errors.New("syslog: backend is closed")
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		if this.conn == nil {
+			if err = this.connect(); err != nil {
+				return
+			}
+		}
+		data := msg
+		if this.stream() {
+			if this.rfc5424 {
+				// Octet counting framing, RFC6587.
+				data = strconv.Itoa(len(msg)) + " " + msg
+			} else {
+				data += "\n"
+			}
+		}
+		if _, err = this.conn.Write([]byte(data)); err == nil {
+			return
+		}
+		this.conn.Close()
+		this.conn = nil
+	}
+	return
+}
+
+// Log implements the Backend interface.
+func (this *SyslogBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	var msg string
+	if this.opts.Formatted {
+		msg = rec.Formatted(calldepth + 1)
+	} else {
+		msg = rec.Message()
+	}
+	return this.write(this.message(level, rec, msg))
+}
+
+// Print implements the Printer interface. The values are logged with the INFO
+// severity.
+func (this *SyslogBackend) Print(args ...interface{}) error {
+	return this.write(this.message(logging.INFO, &logging.Record{Time: time.Now()}, fmt.Sprint(args...)))
+}
+
+// Close closes the connection to the syslog daemon.
+func (this *SyslogBackend) Close() (err error) {
+	unregister(this)
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.closed = true
+	if this.conn != nil {
+		err = this.conn.Close()
+		this.conn = nil
+	}
+	return
+}
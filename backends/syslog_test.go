@@ -0,0 +1,97 @@
+package backends
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/moisespsena-go/logging"
+)
+
+func TestRFC5424Backend(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	b, err := NewRFC5424Backend(SyslogLocal3, "app", SyslogOptions{
+		Network:  "udp",
+		Addr:     pc.LocalAddr().String(),
+		Hostname: "host",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	log := logging.NewLogger("web")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Clock = logging.ClockFunc(func() time.Time {
+		return time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+	})
+	log.WithFields("user", `john "doe"`, "path", "/a]b").Error("failed")
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<155>1 2020-01-02T03:04:05.000006Z host app ` + strconv.Itoa(os.Getpid()) +
+		` web [fields@32473 user="john \"doe\"" path="/a\]b"] failed`
+	if string(buf[:n]) != expected {
+		t.Errorf("%q != %q", buf[:n], expected)
+	}
+}
+
+func TestSyslogBackendLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "log")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	defer func(sockets []string) { syslogSockets = sockets }(syslogSockets)
+	syslogSockets = []string{filepath.Join(dir, "missing"), path}
+
+	b, err := NewSyslogBackend(SyslogDaemon, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	log := logging.NewLogger("local")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Clock = logging.ClockFunc(func() time.Time {
+		return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	})
+	log.Warning("hello")
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "<28>Jan  2 03:04:05 app[" + strconv.Itoa(os.Getpid()) + "]: hello"
+	if string(buf[:n]) != expected {
+		t.Errorf("%q != %q", buf[:n], expected)
+	}
+
+	syslogSockets = nil
+	if _, err = NewSyslogBackend(SyslogDaemon, "app"); err != ErrSyslogUnavailable {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package logging
+
+import "runtime"
+
+// callerPC returns the program counter of the caller at skip frames above the
+// function calling it.
+func callerPC(skip int) uintptr {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return 0
+	}
+	return pcs[0]
+}
+
+// Caller returns the file, line and function of the code which logged the
+// record, taking into account the ExtraCalldepth of the logger. The program
+// counter is taken when the record is created and resolved on the first
+// call, so it's also available for hooks and async backends. Records not
+// created by a logger return ok false.
+func (r *Record) Caller() (file string, line int, fn string, ok bool) {
+	if r.caller == nil {
+		if r.pc == 0 {
+			return
+		}
+		frame, _ := runtime.CallersFrames([]uintptr{r.pc}).Next()
+		r.caller = &frame
+	}
+	return r.caller.File, r.caller.Line, r.caller.Function, r.caller.File != ""
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestRecordCaller(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	log := NewLogger("test")
+
+	_, _, line, _ := runtime.Caller(0)
+	log.Info("caller")
+	file, l, fn, ok := MemoryRecordN(backend, 0).Caller()
+	if !ok || filepath.Base(file) != "caller_test.go" || l != line+1 {
+		t.Errorf("unexpected caller %s:%d", file, l)
+	}
+	if fn != "github.com/moisespsena-go/logging.TestRecordCaller" {
+		t.Errorf("unexpected function %q", fn)
+	}
+
+	log.ExtraCalldepth = 1
+	wrapped := func() {
+		log.Info("wrapped")
+	}
+	wrapped()
+	if _, l, _, _ = MemoryRecordN(backend, 1).Caller(); l != line+14 {
+		t.Errorf("unexpected caller line %d, expected %d", l, line+14)
+	}
+
+	if _, _, _, ok = (&Record{}).Caller(); ok {
+		t.Error("unexpected caller for a record not created by a logger")
+	}
+}
+
+func TestRecordDataWithCaller(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	log := NewLogger("test")
+	log.ExtraCalldepth = 1
+	wrapped := func() {
+		log.Info("caller")
+	}
+
+	_, _, line, _ := runtime.Caller(0)
+	wrapped()
+	rec := MemoryRecordN(backend, 0)
+	if data := rec.Data(); data.File != "" || data.Line != 0 || data.Func != "" {
+		t.Errorf("unexpected caller in data: %+v", data)
+	}
+	data := rec.DataWithCaller()
+	if filepath.Base(data.File) != "caller_test.go" || data.Line != line+1 {
+		t.Errorf("unexpected caller %s:%d", data.File, data.Line)
+	}
+	if data.Func != "github.com/moisespsena-go/logging.TestRecordDataWithCaller" {
+		t.Errorf("unexpected function %q", data.Func)
+	}
+}
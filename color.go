@@ -0,0 +1,150 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+)
+
+var (
+	// NoColor disables the colors of the backends created by NewColorBackend.
+	NoColor bool
+	// ForceColor enables the colors of the backends created by
+	// NewColorBackend, even if the writer isn't a terminal or NO_COLOR is set.
+	// NoColor takes precedence.
+	ForceColor bool
+)
+
+var colorSeqRe = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// ColorEnabled reports whether the colors should be written into w. It's
+// false if NoColor is set, true if ForceColor is set, false if the NO_COLOR
+// environment variable is set, and otherwise whether w is a terminal.
+func ColorEnabled(w io.Writer) bool {
+	if NoColor {
+		return false
+	}
+	if ForceColor {
+		return true
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// ANSI attributes which can be combined with the colors of a ColorScheme, eg.
+// Color256(208) + AttrBold.
+const (
+	AttrReset     = "\033[0m"
+	AttrBold      = "\033[1m"
+	AttrUnderline = "\033[4m"
+)
+
+// ColorScheme contains the escape sequences written by the %{color} verb for
+// each level. See NewColorFormatter.
+type ColorScheme map[Level]string
+
+// DefaultColorScheme returns the 8 colors scheme used by default.
+func DefaultColorScheme() ColorScheme {
+	return ColorScheme{
+		CRITICAL: "\033[35m",
+		ERROR:    "\033[31m",
+		WARNING:  "\033[33m",
+		NOTICE:   "\033[32m",
+		DEBUG:    "\033[36m",
+	}
+}
+
+// Color256 returns the escape sequence of the foreground color n of the 256
+// colors palette.
+func Color256(n uint8) string {
+	return fmt.Sprintf("\033[38;5;%dm", n)
+}
+
+// TrueColor returns the escape sequence of the 24 bits foreground color r, g,
+// b.
+func TrueColor(r, g, b uint8) string {
+	return fmt.Sprintf("\033[38;2;%d;%d;%dm", r, g, b)
+}
+
+// TrueColorSupported reports whether the terminal advertises 24 bits colors
+// in the COLORTERM environment variable.
+func TrueColorSupported() bool {
+	v := os.Getenv("COLORTERM")
+	return v == "truecolor" || v == "24bit"
+}
+
+// SelectColorScheme returns truecolor if TrueColorSupported, otherwise
+// fallback. If fallback is nil, DefaultColorScheme is used.
+func SelectColorScheme(truecolor, fallback ColorScheme) ColorScheme {
+	if TrueColorSupported() {
+		return truecolor
+	}
+	if fallback == nil {
+		fallback = DefaultColorScheme()
+	}
+	return fallback
+}
+
+// write writes the sequence of the level, or of the layout of the %{color}
+// verb: "bold" adds the bold attribute and "reset" resets all attributes.
+func (s ColorScheme) write(layout string, level Level, output io.Writer) {
+	switch layout {
+	case "reset":
+		io.WriteString(output, AttrReset)
+	case "bold":
+		io.WriteString(output, s[level]+AttrBold)
+	default:
+		io.WriteString(output, s[level])
+	}
+}
+
+// isTerminal reports whether w is a file connected to a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(interface {
+		Stat() (os.FileInfo, error)
+	})
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ColorBackend writes the formatted records, one per line, into a writer.
+// If Color is false, the ANSI color sequences, like the ones written by the
+// %{color} verb, are removed, so redirected output is plain.
+type ColorBackend struct {
+	Writer io.Writer
+	Color  bool
+	mu     sync.Mutex
+}
+
+// NewColorBackend creates a ColorBackend which keeps the colors only if
+// ColorEnabled(w) on construction.
+func NewColorBackend(w io.Writer) *ColorBackend {
+	return &ColorBackend{Writer: w, Color: ColorEnabled(w)}
+}
+
+func (b *ColorBackend) write(line string) (err error) {
+	if !b.Color {
+		line = colorSeqRe.ReplaceAllString(line, "")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, err = io.WriteString(b.Writer, line+"\n")
+	return
+}
+
+// Log implements the Backend interface.
+func (b *ColorBackend) Log(level Level, calldepth int, rec *Record) error {
+	return b.write(rec.Formatted(calldepth + 1))
+}
+
+// Print implements the Printer interface.
+func (b *ColorBackend) Print(args ...interface{}) error {
+	return b.write(fmt.Sprint(args...))
+}
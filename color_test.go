@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestColorBackend(t *testing.T) {
+	InitForTesting(DEBUG)
+	buf := &bytes.Buffer{}
+	SetBackend(NewBackendFormatter(NewColorBackend(buf), MustStringFormatter("%{color}%{level:.1s}%{color:reset} %{message}")))
+
+	log := GetOrCreateLogger("test")
+	log.Error("plain")
+
+	ForceColor = true
+	defer func() { ForceColor = false }()
+	SetBackend(NewBackendFormatter(NewColorBackend(buf), MustStringFormatter("%{color}%{level:.1s}%{color:reset} %{message}")))
+	log.Error("colored")
+
+	if expected := "E plain\n\033[31mE\033[0m colored\n"; buf.String() != expected {
+		t.Errorf("%q != %q", buf.String(), expected)
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	f, err := ioutil.TempFile("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if ColorEnabled(f) || ColorEnabled(&bytes.Buffer{}) {
+		t.Errorf("color enabled for non terminal writers")
+	}
+
+	ForceColor = true
+	defer func() { ForceColor = false }()
+	if !ColorEnabled(f) {
+		t.Errorf("ForceColor ignored")
+	}
+	NoColor = true
+	defer func() { NoColor = false }()
+	if ColorEnabled(f) {
+		t.Errorf("NoColor ignored")
+	}
+}
+
+func TestColorFormatter(t *testing.T) {
+	scheme := ColorScheme{
+		ERROR:   TrueColor(255, 0, 0) + AttrUnderline,
+		WARNING: Color256(208),
+	}
+	f, err := NewColorFormatter("%{color}%{message}%{color:reset} %{color:bold}x", scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := InitForTesting(DEBUG)
+	SetFormatter(f)
+	log := GetOrCreateLogger("test")
+	log.Error("error")
+	log.Warning("warning")
+	log.Info("info")
+
+	expected := []string{
+		"\033[38;2;255;0;0m\033[4merror\033[0m \033[38;2;255;0;0m\033[4m\033[1mx",
+		"\033[38;5;208mwarning\033[0m \033[38;5;208m\033[1mx",
+		"info\033[0m \033[1mx",
+	}
+	for i, e := range expected {
+		if line := MemoryRecordN(backend, i).Formatted(0); line != e {
+			t.Errorf("%d: %q != %q", i, line, e)
+		}
+	}
+}
+
+func TestSelectColorScheme(t *testing.T) {
+	defer os.Setenv("COLORTERM", os.Getenv("COLORTERM"))
+	truecolor := ColorScheme{ERROR: TrueColor(1, 2, 3)}
+
+	os.Setenv("COLORTERM", "truecolor")
+	if scheme := SelectColorScheme(truecolor, nil); scheme[ERROR] != truecolor[ERROR] {
+		t.Errorf("truecolor scheme not selected")
+	}
+	os.Setenv("COLORTERM", "")
+	if scheme := SelectColorScheme(truecolor, nil); scheme[ERROR] != "\033[31m" {
+		t.Errorf("fallback scheme not selected")
+	}
+}
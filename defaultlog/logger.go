@@ -1,7 +1,9 @@
 package defaultlog
 
 import (
+	"io"
 	"os"
+	"sync"
 
 	"github.com/moisespsena-go/logging"
 )
@@ -12,8 +14,58 @@ var (
 	)
 
 	GetOrCreateLogger = logging.GetOrCreateLogger
+
+	mu      sync.RWMutex
+	output  io.Writer = os.Stderr
+	current logging.Backend
 )
 
+// backend is the default backend. It delegates to the current formatted
+// backend, which is replaced by SetFormat and SetOutput, so the module levels
+// set into the default backend are kept.
+type backend struct{}
+
+func (backend) get() logging.Backend {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Log implements the logging.Backend interface.
+func (b backend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	return b.get().Log(level, calldepth+1, rec)
+}
+
+// Flush implements the logging.Flusher interface.
+func (b backend) Flush() error {
+	return logging.FlushBackend(b.get())
+}
+
+// rebuild replaces the current backend, using the Format and the output. It
+// must be called with the lock held.
+func rebuild() {
+	current = logging.NewBackendFormatter(logging.NewColorBackend(output), Format)
+}
+
+// SetFormat replaces the Format of the default backend. The records logged
+// afterwards use it.
+func SetFormat(f logging.Formatter) {
+	mu.Lock()
+	defer mu.Unlock()
+	Format = f
+	rebuild()
+}
+
+// SetOutput replaces the writer of the default backend, os.Stderr by default.
+// The colors are kept only if w is a terminal, see logging.ColorEnabled.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	output = w
+	rebuild()
+}
+
 func init() {
-	logging.SetBackend(logging.NewBackendFormatter(logging.NewLogBackend(os.Stderr, "", 0), Format))
+	rebuild()
+	logging.SetBackend(backend{})
 }
@@ -0,0 +1,30 @@
+package defaultlog
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/moisespsena-go/logging"
+)
+
+func TestSetFormat(t *testing.T) {
+	format := Format
+	defer func() {
+		SetFormat(format)
+		SetOutput(os.Stderr)
+	}()
+
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	SetFormat(logging.MustStringFormatter("%{level} %{message}"))
+	log := GetOrCreateLogger("defaultlog")
+	log.Info("first")
+
+	SetFormat(logging.MustStringFormatter("[%{module}] %{message}"))
+	log.Warning("second")
+
+	if expected := "INFO first\n[defaultlog] second\n"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+// StackTracer is implemented by the errors which carry the stack where they
+// were created, as the program counters returned by runtime.Callers. The
+// formatters expanding the errors output it.
+type StackTracer interface {
+	StackTrace() []uintptr
+}
+
+// maxErrorChain limits the unwrapped errors, in case of a cyclic chain.
+const maxErrorChain = 100
+
+// expandedError is an error of the chain of an expanded error.
+type expandedError struct {
+	Message string          `json:"message"`
+	Stack   []string        `json:"stack,omitempty"`
+	Causes  []expandedError `json:"causes,omitempty"`
+}
+
+// expandError returns err with the errors wrapped by it, unwrapped by their
+// Unwrap method, as causes.
+func expandError(err error) expandedError {
+	e := expandedError{Message: err.Error(), Stack: errorStack(err)}
+	for i := 0; i < maxErrorChain; i++ {
+		if err = unwrapError(err); err == nil {
+			break
+		}
+		e.Causes = append(e.Causes, expandedError{Message: err.Error(), Stack: errorStack(err)})
+	}
+	return e
+}
+
+// unwrapError returns the error wrapped by err, eg. by fmt.Errorf with %w, or
+// nil.
+func unwrapError(err error) error {
+	if u, ok := err.(interface{ Unwrap() error }); ok {
+		if err = u.Unwrap(); !isNilError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// isNilError reports whether err is nil, or a nil pointer.
+func isNilError(err error) bool {
+	if err == nil {
+		return true
+	}
+	v := reflect.ValueOf(err)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// errorStack returns the frames of the stack of err, if it's a StackTracer.
+func errorStack(err error) (stack []string) {
+	st, ok := err.(StackTracer)
+	if !ok {
+		return
+	}
+	pcs := st.StackTrace()
+	if len(pcs) == 0 {
+		return
+	}
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line))
+		if !more {
+			return
+		}
+	}
+}
+
+// errors returns the errors of the args of r, followed by the error added by
+// Logger.WithError, if any. The nil errors are skipped.
+func (r *Record) errors() (errs []error) {
+	for _, arg := range r.Args {
+		if err, ok := arg.(error); ok && !isNilError(err) {
+			errs = append(errs, err)
+		}
+	}
+	if err, ok := r.Err(); ok && !isNilError(err) {
+		errs = append(errs, err)
+	}
+	return
+}
+
+// expandedErrorsText returns the causes and stacks of the errors of r, each on
+// its own indented line, eg:
+//
+//	open config: file not found
+//		caused by: file not found
+//			at main.load (/app/main.go:10)
+func (r *Record) expandedErrorsText() string {
+	var buf bytes.Buffer
+	var write func(e expandedError, cause bool)
+	write = func(e expandedError, cause bool) {
+		if cause {
+			buf.WriteString("\n\tcaused by: " + e.Message)
+		}
+		for _, frame := range e.Stack {
+			buf.WriteString("\n\t\tat " + frame)
+		}
+		for _, c := range e.Causes {
+			write(c, true)
+		}
+	}
+	for _, err := range r.errors() {
+		write(expandError(err), false)
+	}
+	return buf.String()
+}
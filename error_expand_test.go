@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// stackError is an error which carries the stack where it was created.
+type stackError struct {
+	msg string
+	pcs []uintptr
+}
+
+func newStackError(msg string) *stackError {
+	pcs := make([]uintptr, 1)
+	return &stackError{msg, pcs[:runtime.Callers(2, pcs)]}
+}
+
+func (e *stackError) Error() string         { return e.msg }
+func (e *stackError) StackTrace() []uintptr { return e.pcs }
+
+func TestStringFormatterExpandErrors(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	f, err := NewStringFormatterOptions("%{message}", StringFormatterOptions{ExpandErrors: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetFormatter(f)
+
+	root := newStackError("not found")
+	log := NewLogger("test")
+	log.Error("failed:", fmt.Errorf("load: %w", fmt.Errorf("open: %w", root)))
+	log.Error("plain", 1, error(nil), (*stackError)(nil))
+	log.WithError(errors.New("closed")).Error("write")
+
+	expected := "failed: load: open: not found" +
+		"\n\tcaused by: open: not found" +
+		"\n\tcaused by: not found" +
+		"\n\t\tat github.com/moisespsena-go/logging.TestStringFormatterExpandErrors ("
+	if line := MemoryRecordN(backend, 0).Formatted(0); !strings.HasPrefix(line, expected) || !strings.HasSuffix(line, "error_expand_test.go:33)") {
+		t.Errorf("unexpected expanded errors %q", line)
+	}
+	if line := MemoryRecordN(backend, 1).Formatted(0); line != "plain 1 <nil> <nil>" {
+		t.Errorf("unexpected message %q", line)
+	}
+	if line := MemoryRecordN(backend, 2).Formatted(0); line != "write: closed" {
+		t.Errorf("unexpected message %q", line)
+	}
+}
+
+func TestJSONFormatterExpandErrors(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	SetFormatter(NewJSONFormatter(JSONFormatterOptions{IDKey: "-", TimeKey: "-", CallerKey: "-", ExpandErrors: true}))
+
+	log := NewLogger("test")
+	log.Error("failed:", fmt.Errorf("load: %w", errors.New("not found")))
+	log.Error("plain")
+
+	expected := `{"module":"test","level":"error","message":"failed: load: not found",` +
+		`"error":{"message":"load: not found","causes":[{"message":"not found"}]}}`
+	if line := MemoryRecordN(backend, 0).Formatted(0); line != expected {
+		t.Errorf("unexpected format %s", line)
+	}
+	if line := MemoryRecordN(backend, 1).Formatted(0); line != `{"module":"test","level":"error","message":"plain"}` {
+		t.Errorf("unexpected format %s", line)
+	}
+}
@@ -0,0 +1,41 @@
+package logging
+
+import "sync"
+
+// ErrorHandler is called with the errors returned by the backends.
+type ErrorHandler func(err error)
+
+var errorHandler struct {
+	sync.RWMutex
+	h ErrorHandler
+}
+
+// SetErrorHandler sets the default handler of the errors returned by the
+// backends. Loggers with their own ErrorHandler don't use it. A nil handler
+// discards the errors.
+func SetErrorHandler(h ErrorHandler) {
+	errorHandler.Lock()
+	defer errorHandler.Unlock()
+	errorHandler.h = h
+}
+
+// GetErrorHandler returns the default error handler.
+func GetErrorHandler() ErrorHandler {
+	errorHandler.RLock()
+	defer errorHandler.RUnlock()
+	return errorHandler.h
+}
+
+// HandleError calls the default error handler with err. It returns false if
+// err is nil or there isn't a default handler. Async backends use it to report
+// the errors from their worker goroutines.
+func HandleError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if h := GetErrorHandler(); h != nil {
+		h(err)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package logging
+
+import (
+	"errors"
+	"testing"
+)
+
+type errorBackend struct{ err error }
+
+func (b errorBackend) Log(Level, int, *Record) error { return b.err }
+
+func TestErrorHandler(t *testing.T) {
+	InitForTesting(DEBUG)
+	backendErr := errors.New("disk full")
+	SetBackend(errorBackend{backendErr})
+
+	var handled []error
+	SetErrorHandler(func(err error) { handled = append(handled, err) })
+	defer SetErrorHandler(nil)
+
+	GetOrCreateLogger("test").Info("default handler")
+
+	var own []error
+	log := NewLogger("own")
+	log.ErrorHandler = func(err error) { own = append(own, err) }
+	log.Info("own handler")
+
+	if len(handled) != 1 || handled[0] != backendErr {
+		t.Errorf("unexpected default handler errors: %v", handled)
+	}
+	if len(own) != 1 || own[0] != backendErr {
+		t.Errorf("unexpected logger handler errors: %v", own)
+	}
+}
@@ -0,0 +1,130 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Field is a structured key/value pair attached to a log record.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Fields is an ordered list of structured key/value pairs.
+type Fields []Field
+
+// NewFields creates Fields from alternating keys and values. Keys that aren't
+// strings are converted using fmt.Sprint. A trailing value without key is
+// stored under the "!BADKEY" key.
+func NewFields(keyvals ...interface{}) (fields Fields) {
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 == len(keyvals) {
+			fields = append(fields, Field{"!BADKEY", keyvals[i]})
+			break
+		}
+		key, ok := keyvals[i].(string)
+		if !ok {
+			key = fmt.Sprint(keyvals[i])
+		}
+		fields = append(fields, Field{key, keyvals[i+1]})
+	}
+	return
+}
+
+// Copy returns a copy of the fields which doesn't share the underlying array.
+func (f Fields) Copy() Fields {
+	if f == nil {
+		return nil
+	}
+	return append(make(Fields, 0, len(f)), f...)
+}
+
+// Get returns the last value stored for key.
+func (f Fields) Get(key string) (value interface{}, ok bool) {
+	for i := len(f) - 1; i >= 0; i-- {
+		if f[i].Key == key {
+			return f[i].Value, true
+		}
+	}
+	return
+}
+
+// orderFields returns a copy of f with the fields of the keys in order first,
+// in that order, followed by the others. If sortOthers is set, the others are
+// sorted by key, else they keep their order. The repeated keys keep their
+// order.
+func orderFields(f Fields, order []string, sortOthers bool) Fields {
+	rank := func(key string) int {
+		for i, k := range order {
+			if k == key {
+				return i
+			}
+		}
+		return len(order)
+	}
+	f = f.Copy()
+	sort.SliceStable(f, func(i, j int) bool {
+		ri, rj := rank(f[i].Key), rank(f[j].Key)
+		if ri != rj {
+			return ri < rj
+		}
+		return sortOthers && ri == len(order) && f[i].Key < f[j].Key
+	})
+	return f
+}
+
+// ErrorKey is the key of the field added by Logger.WithError.
+var ErrorKey = "error"
+
+// redactedValue returns the value of the field, redacted if it implements the
+// Redactor interface.
+func (f Field) redactedValue() interface{} {
+	if redactor, ok := f.Value.(Redactor); ok {
+		return redactor.Redacted()
+	}
+	return f.Value
+}
+
+// String returns the fields as space separated key=value pairs. Values
+// containing spaces, quotes, equals signs or control characters are quoted.
+func (f Fields) String() string {
+	var buf bytes.Buffer
+	for i, field := range f {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		writeLogfmt(&buf, field.Key, field.redactedValue())
+	}
+	return buf.String()
+}
+
+// MarshalJSON implements the json.Marshaler interface. The fields are encoded
+// as a JSON object keeping their order.
+func (f Fields) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range f {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.Key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		v := field.redactedValue()
+		if e, ok := v.(error); ok {
+			if _, ok = v.(json.Marshaler); !ok {
+				v = e.Error()
+			}
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			value, _ = json.Marshal(fmt.Sprint(field.redactedValue()))
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// HostKey is the key of the global field of the host name, see
+	// SetGlobalFields.
+	HostKey = "host"
+	// AppKey is the key of the global field of the program name, see
+	// SetGlobalFields.
+	AppKey = "app"
+
+	hostname = func() string {
+		if name, err := os.Hostname(); err == nil {
+			return name
+		}
+		return "???"
+	}()
+
+	// globalFields stores the Fields added into all records.
+	globalFields atomic.Value
+	// globalFieldsMu serializes the changes of the global fields.
+	globalFieldsMu sync.Mutex
+)
+
+// SetGlobalFields sets the fields added into all records, eg. for the
+// aggregated logs of many hosts. The HostKey field, with the host name, and
+// the AppKey field, with the base name of os.Args[0], are added unless fields
+// overrides them. A nil value removes the field, eg:
+//
+//	logging.SetGlobalFields(map[string]interface{}{"env": "prod", logging.AppKey: nil})
+//
+// The fields are sorted by key, and added before the fields of the records.
+// The record fields of the same key replace them. The structured formatters
+// output them, and the %{host} and %{app} verbs of the string formatters
+// output the host and app ones.
+func SetGlobalFields(fields map[string]interface{}) {
+	all := map[string]interface{}{HostKey: hostname, AppKey: program}
+	for key, value := range fields {
+		all[key] = value
+	}
+	globalFieldsMu.Lock()
+	defer globalFieldsMu.Unlock()
+	storeGlobalFields(all)
+}
+
+// SetGlobalField sets the global field of key, keeping the others. A nil value
+// removes it.
+func SetGlobalField(key string, value interface{}) {
+	globalFieldsMu.Lock()
+	defer globalFieldsMu.Unlock()
+	all := map[string]interface{}{}
+	for _, field := range getGlobalFields() {
+		all[field.Key] = field.Value
+	}
+	all[key] = value
+	storeGlobalFields(all)
+}
+
+// ClearGlobalFields removes all global fields, including the host and app
+// ones.
+func ClearGlobalFields() {
+	globalFieldsMu.Lock()
+	defer globalFieldsMu.Unlock()
+	globalFields.Store(Fields(nil))
+}
+
+// GlobalFields returns a copy of the global fields.
+func GlobalFields() Fields {
+	return getGlobalFields().Copy()
+}
+
+// storeGlobalFields stores the fields of all without the nil values, sorted by
+// key.
+func storeGlobalFields(all map[string]interface{}) {
+	var fields Fields
+	for key, value := range all {
+		if value != nil {
+			fields = append(fields, Field{key, value})
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Key < fields[j].Key
+	})
+	globalFields.Store(fields)
+}
+
+func getGlobalFields() Fields {
+	fields, _ := globalFields.Load().(Fields)
+	return fields
+}
+
+// withGlobalFields returns the global fields followed by fields. The global
+// fields of the keys in fields are skipped.
+func withGlobalFields(fields Fields) Fields {
+	globals := getGlobalFields()
+	if len(globals) == 0 {
+		return fields
+	}
+	all := make(Fields, 0, len(globals)+len(fields))
+	for _, global := range globals {
+		if _, ok := fields.Get(global.Key); !ok {
+			all = append(all, global)
+		}
+	}
+	return append(all, fields...)
+}
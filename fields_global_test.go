@@ -0,0 +1,54 @@
+package logging
+
+import "testing"
+
+func TestGlobalFields(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	defer ClearGlobalFields()
+	SetFormatter(NewLogfmtFormatter(LogfmtFormatterOptions{TimeKey: "-", CallerKey: "-"}))
+
+	SetGlobalFields(map[string]interface{}{"env": "prod", AppKey: "api"})
+	log := NewLogger("test")
+	log.Info("first")
+	log.WithFields("env", "dev").Info("second")
+
+	SetGlobalField(HostKey, nil)
+	SetGlobalField("region", "eu")
+	log.Info("third")
+
+	expected := []string{
+		"level=info module=test msg=first app=api env=prod host=" + hostname,
+		"level=info module=test msg=second app=api host=" + hostname + " env=dev",
+		"level=info module=test msg=third app=api env=prod region=eu",
+	}
+	lines := memoryRecords(backend)
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected records %q", lines)
+	}
+	for i, line := range lines {
+		if line != expected[i] {
+			t.Errorf("%d: unexpected record %q, expected %q", i, line, expected[i])
+		}
+	}
+
+	ClearGlobalFields()
+	log.Info("cleared")
+	if rec := MemoryRecordN(backend, 3); len(rec.Fields) != 0 {
+		t.Errorf("unexpected fields %v", rec.Fields)
+	}
+}
+
+func TestFormatHostApp(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	defer ClearGlobalFields()
+	SetFormatter(MustStringFormatter("%{host} %{app} %{message}"))
+
+	log := NewLogger("test")
+	log.Info("default")
+	SetGlobalFields(map[string]interface{}{HostKey: "web1", AppKey: "api"})
+	log.Info("global")
+
+	if lines := memoryRecords(backend); len(lines) != 2 || lines[0] != hostname+" "+program+" default" || lines[1] != "web1 api global" {
+		t.Errorf("unexpected records %q", lines)
+	}
+}
@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestNewFields(t *testing.T) {
+	fields := NewFields("a", 1, 2, "b", "c")
+	if `a=1 2=b !BADKEY=c` != fields.String() {
+		t.Errorf("unexpected fields: %s", fields)
+	}
+	if v, ok := fields.Get("2"); !ok || v != "b" {
+		t.Errorf("unexpected value: %v", v)
+	}
+}
+
+func TestFieldsString(t *testing.T) {
+	fields := NewFields("msg", "hello world", "empty", "", "pass", Password("123"))
+	if `msg="hello world" empty="" pass=***` != fields.String() {
+		t.Errorf("unexpected fields: %s", fields)
+	}
+}
+
+func TestFieldsMarshalJSON(t *testing.T) {
+	data, err := json.Marshal(NewFields("z", 1, "a", "b", "pass", Password("123")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if `{"z":1,"a":"b","pass":"***"}` != string(data) {
+		t.Errorf("unexpected json: %s", data)
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	SetFormatter(MustStringFormatter("%{message} %{fields}"))
+
+	log := GetOrCreateLogger("test")
+	child := log.WithFields("request_id", 10)
+	child.WithFields("user", "joe").Info("done")
+	child.Infof("%d", 1)
+	log.Info("parent")
+
+	expected := []string{
+		"done request_id=10 user=joe",
+		"1 request_id=10",
+		"parent ",
+	}
+	for i, e := range expected {
+		if line := MemoryRecordN(backend, i).Formatted(0); e != line {
+			t.Errorf("%d: %q != %q", i, line, e)
+		}
+	}
+}
+
+func TestWithFieldsPrefix(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	SetFormatter(MustStringFormatter("%{message} %{fields}"))
+
+	log := WithPrefix(GetOrCreateLogger("test"), "prefix").WithFields("a", 1)
+	log.Info("msg")
+
+	if line := MemoryRecordN(backend, 0).Formatted(0); "prefix -> msg a=1" != line {
+		t.Errorf("unexpected line: %q", line)
+	}
+}
+
+func TestWithFieldsTee(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	SetFormatter(MustStringFormatter("%{module} %{message} %{fields}"))
+
+	log := Tee(GetOrCreateLogger("a"), GetOrCreateLogger("b")).WithFields("k", "v")
+	log.Info("tee")
+
+	for i, e := range []string{"a tee k=v", "b tee k=v"} {
+		if line := MemoryRecordN(backend, i).Formatted(0); e != line {
+			t.Errorf("%d: %q != %q", i, line, e)
+		}
+	}
+}
+
+func TestWithError(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	SetFormatter(MustStringFormatter("%{message}"))
+
+	log := GetOrCreateLogger("test")
+	if log.WithError(nil) != log {
+		t.Errorf("nil error didn't return the same logger")
+	}
+	log.WithError(errors.New("timeout")).Error("operation failed")
+	WithPrefix(log, "db").WithError(errors.New("closed")).Warning("query failed")
+
+	for i, e := range []string{"operation failed: timeout", "db -> query failed: closed"} {
+		if line := MemoryRecordN(backend, i).Formatted(0); e != line {
+			t.Errorf("%d: %q != %q", i, line, e)
+		}
+	}
+
+	data, err := json.Marshal(MemoryRecordN(backend, 0).Fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if `{"error":"timeout"}` != string(data) {
+		t.Errorf("unexpected json: %s", data)
+	}
+}
+
+func TestWithErrorFields(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	SetFormatter(MustStringFormatter("%{message} %{fields}"))
+
+	GetOrCreateLogger("test").WithError(errors.New("timeout")).Error("failed")
+	if line := MemoryRecordN(backend, 0).Formatted(0); "failed error=timeout" != line {
+		t.Errorf("unexpected line: %q", line)
+	}
+}
+
+func TestRecordClone(t *testing.T) {
+	r := &Record{Args: []interface{}{"a"}, Fields: NewFields("k", "v")}
+	r2 := r.Clone()
+	r2.Args[0] = "b"
+	r2.Fields[0].Value = "x"
+	if r.Args[0] != "a" || r.Fields[0].Value != "v" {
+		t.Errorf("clone shares data with the original record")
+	}
+}
@@ -0,0 +1,175 @@
+package logging
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// filterBackend drops the records which doesn't pass the predicate.
+type filterBackend struct {
+	inner     Backend
+	predicate func(*Record) bool
+}
+
+// NewFilterBackend creates a backend which passes to inner only the records
+// for which predicate returns true. For example, to silence a group of
+// modules:
+//
+//	NewFilterBackend(backend, Not(ModuleMatches("github.com/noisy/*")))
+//
+// The returned backend implements Printer if inner does. Printed values have
+// no record, so they are never filtered.
+func NewFilterBackend(inner Backend, predicate func(*Record) bool) Backend {
+	b := &filterBackend{inner, predicate}
+	if _, ok := inner.(Printer); ok {
+		return &filterPrinter{b}
+	}
+	return b
+}
+
+// Log implements the Backend interface.
+func (b *filterBackend) Log(level Level, calldepth int, rec *Record) error {
+	if !b.predicate(rec) {
+		return nil
+	}
+	return b.inner.Log(level, calldepth+1, rec)
+}
+
+// Flush implements the Flusher interface.
+func (b *filterBackend) Flush() error {
+	return FlushBackend(b.inner)
+}
+
+func (b *filterBackend) wrappedBackends() []Backend {
+	return []Backend{b.inner}
+}
+
+type filterPrinter struct {
+	*filterBackend
+}
+
+// Print implements the Printer interface.
+func (b *filterPrinter) Print(args ...interface{}) error {
+	return b.inner.(Printer).Print(args...)
+}
+
+// ModuleMatches returns a predicate which matches the module of the records
+// against the glob pattern. The '*' wildcard matches any sequence of
+// characters, including '/' and '.', and '?' matches any single character.
+func ModuleMatches(pattern string) func(*Record) bool {
+	var expr strings.Builder
+	expr.WriteByte('^')
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteByte('.')
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteByte('$')
+	return ModuleRegexp(expr.String())
+}
+
+// ModuleRegexp returns a predicate which matches the module of the records
+// against the regular expression expr. It panics if expr can't be compiled.
+func ModuleRegexp(expr string) func(*Record) bool {
+	re := regexp.MustCompile(expr)
+	return func(rec *Record) bool {
+		return re.MatchString(rec.Module)
+	}
+}
+
+// Not returns a predicate which negates predicate.
+func Not(predicate func(*Record) bool) func(*Record) bool {
+	return func(rec *Record) bool {
+		return !predicate(rec)
+	}
+}
+
+// minLevelBackend drops the records less severe than its level.
+type minLevelBackend struct {
+	inner Backend
+	min   int32
+}
+
+// NewMinLevelBackend creates a backend which passes to inner only the records
+// of min or more severe levels, eg. WARNING, ERROR and CRITICAL for WARNING.
+// It's a LeveledBackend without module levels: SetLevel changes min for all
+// modules, ignoring the module, and GetLevel returns min. So it's simpler and
+// cheaper than AddModuleLevel, eg. for the thresholds of each destination of
+// a multi logger:
+//
+//	MultiLogger(fileBackend, NewMinLevelBackend(stderrBackend, WARNING))
+//
+// Close closes inner if it implements io.Closer. The returned backend
+// implements Printer if inner does. Printed values have no level, so they are
+// never filtered.
+func NewMinLevelBackend(inner Backend, min Level) LeveledBackend {
+	b := &minLevelBackend{inner, int32(min)}
+	if _, ok := inner.(Printer); ok {
+		return &minLevelPrinter{b}
+	}
+	return b
+}
+
+// Log implements the Backend interface.
+func (b *minLevelBackend) Log(level Level, calldepth int, rec *Record) error {
+	if !b.IsEnabledFor(level, rec.Module) {
+		return nil
+	}
+	if rec.formatter == nil {
+		rec.formatter = GetFormatter()
+	}
+	return b.inner.Log(level, calldepth+1, rec)
+}
+
+// GetLevel returns the minimum level for all modules.
+func (b *minLevelBackend) GetLevel(string) Level {
+	return Level(atomic.LoadInt32(&b.min))
+}
+
+// SetLevel sets the minimum level for all modules. The module is ignored.
+func (b *minLevelBackend) SetLevel(level Level, _ string) {
+	atomic.StoreInt32(&b.min, int32(level))
+	invalidateLevels()
+}
+
+// IsEnabledFor returns true if level is min or more severe.
+func (b *minLevelBackend) IsEnabledFor(level Level, _ string) bool {
+	return level <= Level(atomic.LoadInt32(&b.min))
+}
+
+func (b *minLevelBackend) cachesLevels() bool {
+	return true
+}
+
+// Flush implements the Flusher interface.
+func (b *minLevelBackend) Flush() error {
+	return FlushBackend(b.inner)
+}
+
+func (b *minLevelBackend) wrappedBackends() []Backend {
+	return []Backend{b.inner}
+}
+
+// Close implements the io.Closer interface, closing inner if it's a closer.
+func (b *minLevelBackend) Close() error {
+	if c, ok := b.inner.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+type minLevelPrinter struct {
+	*minLevelBackend
+}
+
+// Print implements the Printer interface.
+func (b *minLevelPrinter) Print(args ...interface{}) error {
+	return b.inner.(Printer).Print(args...)
+}
@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"io"
+	"testing"
+)
+
+func TestFilterBackend(t *testing.T) {
+	InitForTesting(DEBUG)
+	quiet := NewMemoryBackend(8)
+	all := NewMemoryBackend(8)
+	SetBackend(MultiLogger(NewFilterBackend(quiet, Not(ModuleMatches("github.com/noisy/*"))), all))
+
+	GetOrCreateLogger("github.com/noisy/pkg").Error("noisy")
+	GetOrCreateLogger("github.com/quiet/pkg").Info("quiet")
+
+	if lines := memoryRecords(quiet); len(lines) != 1 || lines[0] != "quiet" {
+		t.Errorf("unexpected filtered records: %q", lines)
+	}
+	if lines := memoryRecords(all); len(lines) != 2 {
+		t.Errorf("unexpected records: %q", lines)
+	}
+}
+
+func TestModuleMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		module  string
+		match   bool
+	}{
+		{"github.com/noisy/*", "github.com/noisy/a/b", true},
+		{"github.com/noisy/*", "github.com/noisy", false},
+		{"github.com/noisy*", "github.com/noisy", true},
+		{"server.?b", "server.db", true},
+		{"server.?b", "serverxdb", false},
+	}
+	for _, test := range tests {
+		if ModuleMatches(test.pattern)(&Record{Module: test.module}) != test.match {
+			t.Errorf("%q match %q != %v", test.pattern, test.module, test.match)
+		}
+	}
+	if !ModuleRegexp(`^app/(http|grpc)`)(&Record{Module: "app/grpc/server"}) {
+		t.Errorf("regexp doesn't match")
+	}
+}
+
+func TestMinLevelBackend(t *testing.T) {
+	InitForTesting(DEBUG)
+	all := NewMemoryBackend(8)
+	warnings := NewMemoryBackend(8)
+	min := NewMinLevelBackend(warnings, WARNING)
+	SetBackend(MultiLogger(all, min))
+
+	log := NewLogger("min")
+	log.Info("info")
+	log.Warning("warning")
+	log.Critical("critical")
+
+	if lines := memoryRecords(warnings); len(lines) != 2 || lines[0] != "warning" || lines[1] != "critical" {
+		t.Errorf("unexpected filtered records: %q", lines)
+	}
+	if lines := memoryRecords(all); len(lines) != 3 {
+		t.Errorf("unexpected records: %q", lines)
+	}
+
+	min.SetLevel(ERROR, "ignored")
+	if min.GetLevel("any") != ERROR || min.IsEnabledFor(WARNING, "") {
+		t.Errorf("level not changed")
+	}
+	if _, ok := min.(Printer); ok {
+		t.Errorf("min level backend of a non printer is a Printer")
+	}
+	if _, ok := NewMinLevelBackend(NewNullBackend(), INFO).(Printer); !ok {
+		t.Errorf("min level backend of a printer isn't a Printer")
+	}
+
+	var closed closeCounter
+	if err := NewMinLevelBackend(NewBackendClose(all, &closed), INFO).(io.Closer).Close(); err != nil || closed != 1 {
+		t.Errorf("inner not closed: %v", err)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -42,6 +43,11 @@ const (
 	fmtVerbShortfunc
 	fmtVerbCallpath
 	fmtVerbLevelColor
+	fmtVerbFields
+	fmtVerbGoroutine
+	fmtVerbReltime
+	fmtVerbHost
+	fmtVerbApp
 
 	// Keep last, there are no match for these below.
 	fmtVerbUnknown
@@ -64,6 +70,11 @@ var fmtVerbs = []string{
 	"shortfunc",
 	"callpath",
 	"color",
+	"fields",
+	"goroutine",
+	"reltime",
+	"host",
+	"app",
 }
 
 const rfc3339Milli = "2006-01-02T15:04:05.999Z07:00"
@@ -71,7 +82,7 @@ const rfc3339Milli = "2006-01-02T15:04:05.999Z07:00"
 var defaultVerbsLayout = []string{
 	rfc3339Milli,
 	"s",
-	"d",
+	"v",
 	"d",
 	"s",
 	"s",
@@ -84,13 +95,38 @@ var defaultVerbsLayout = []string{
 	"s",
 	"0",
 	"",
+	"s",
+	"d",
+	"3",
+	"s",
+	"s",
 }
 
 var (
 	pid     = os.Getpid()
 	program = filepath.Base(os.Args[0])
+
+	// startTime stores the time.Time the %{reltime} verb is relative to,
+	// set by Reset.
+	startTime atomic.Value
+
+	// goroutineVerbUsed is set once a formatter with the %{goroutine} verb is
+	// created, so the loggers capture the goroutine of the new records.
+	goroutineVerbUsed int32
 )
 
+// formatReltime returns the duration of t since the startTime in seconds with
+// prec decimals, eg. "+0.123s".
+func formatReltime(t time.Time, prec int) string {
+	start, _ := startTime.Load().(time.Time)
+	d := t.Sub(start).Seconds()
+	s := strconv.FormatFloat(d, 'f', prec, 64) + "s"
+	if d >= 0 {
+		s = "+" + s
+	}
+	return s
+}
+
 func getFmtVerbByName(name string) fmtVerb {
 	for i, verb := range fmtVerbs {
 		if name == verb {
@@ -111,7 +147,8 @@ var formatter struct {
 	def Formatter
 }
 
-func getFormatter() Formatter {
+// GetFormatter returns the default formatter set by SetFormatter.
+func GetFormatter() Formatter {
 	formatter.RLock()
 	defer formatter.RUnlock()
 	return formatter.def
@@ -123,8 +160,19 @@ var (
 
 	// GlogFormatter mimics the glog format
 	GlogFormatter = MustStringFormatter("%{level:.1s}%{time:0102 15:04:05.999999} %{pid} %{shortfile}] %{message}")
+
+	// RawFormatter writes only the message followed by a newline, without any
+	// metadata, for the backends which add their own framing.
+	RawFormatter Formatter = rawFormatter{}
 )
 
+type rawFormatter struct{}
+
+func (rawFormatter) Format(calldepth int, r *Record, w io.Writer) error {
+	_, err := io.WriteString(w, r.Message()+"\n")
+	return err
+}
+
 // SetFormatter sets the default formatter for all new backends. A backend will
 // fetch this value once it is needed to format a record. Note that backends
 // will cache the formatter after the first point. For now, make sure to set
@@ -145,7 +193,10 @@ type part struct {
 // stringFormatter contains a list of parts which explains how to build the
 // formatted string passed on to the logging backend.
 type stringFormatter struct {
-	parts []part
+	parts        []part
+	hasFields    bool
+	scheme       ColorScheme
+	expandErrors bool
 }
 
 // NewStringFormatter returns a new Formatter which outputs the log record as a
@@ -154,7 +205,8 @@ type stringFormatter struct {
 // The verbs:
 //
 // General:
-//     %{id}        Sequence number for log message (uint64).
+//     %{id}        Sequence number for log message (uint64), or the
+//                  Record.IDString if set (see SetIDStringGenerator).
 //     %{pid}       Process id (int)
 //     %{time}      Time when log occurred (time.Time)
 //     %{level}     Log level (Level)
@@ -165,6 +217,13 @@ type stringFormatter struct {
 //     %{shortfile} Final file name element and line number: d.go:23
 //     %{callpath}  Callpath like main.a.b.c...c  "..." meaning recursive call ~. meaning truncated path
 //     %{color}     ANSI color based on log level
+//     %{fields}    Structured fields as key=value pairs (Fields)
+//                  If not used, the error of Logger.WithError is appended to
+//                  the message.
+//     %{goroutine} Id of the goroutine logging the record (uint64)
+//     %{reltime}   Time since the process start, or the last Reset: +0.123s
+//     %{host}      The host field, or the host name (see SetGlobalFields)
+//     %{app}       The app field, or the program (see SetGlobalFields)
 //
 // For normal types, the output can be customized by using the 'verbs' defined
 // in the fmt package, eg. '%{id:04d}' to make the id output be '%04d' as the
@@ -180,9 +239,18 @@ type stringFormatter struct {
 // "%{color:bold}%{time:15:04:05} %{level:-8s}%{color:reset} %{message}" will
 // just colorize the time and level, leaving the message uncolored.
 //
+// The 'goroutine' verb parses the id from the stack trace of the goroutine,
+// which costs about a microsecond. Once a formatter using it is created, the
+// loggers do it for each new record, so the async backends report the
+// goroutine of the caller instead of their worker. Without formatters using
+// it, it costs nothing.
+//
 // For the 'callpath' verb, the output can be adjusted to limit the printing
 // the stack depth. i.e. '%{callpath:3}' will print '~.a.b.c'
 //
+// For the 'reltime' verb, the layout is the number of decimals of the seconds,
+// 3 by default, i.e. '%{reltime:6}' will print '+0.123456s'.
+//
 // Colors on Windows is unfortunately not supported right now and is currently
 // a no-op.
 //
@@ -196,6 +264,12 @@ type stringFormatter struct {
 //     %{longfunc}  Full function name, eg. littleEndian.PutUint32
 //     %{shortfunc} Base function name, eg. PutUint32
 //     %{callpath}  Call function path, eg. main.a.b.c
+//
+// The function and file verbs are resolved from the stack when the record is
+// formatted, taking into account the ExtraCalldepth of the logger. Backends
+// which format the records later, such as the async ones, report their own
+// goroutine instead of the caller: use Record.Caller, as the JSON and logfmt
+// formatters do, to get the caller taken when the record was created.
 func NewStringFormatter(format string) (Formatter, error) {
 	var fmter = &stringFormatter{}
 
@@ -225,7 +299,14 @@ func NewStringFormatter(format string) (Formatter, error) {
 		if m[4] != -1 {
 			layout = format[m[4]:m[5]]
 		}
-		if verb != fmtVerbTime && verb != fmtVerbLevelColor && verb != fmtVerbCallpath {
+		if verb == fmtVerbGoroutine {
+			atomic.StoreInt32(&goroutineVerbUsed, 1)
+		}
+		if verb == fmtVerbReltime {
+			if prec, err := strconv.Atoi(layout); err != nil || prec < 0 {
+				return nil, errors.New("logger: invalid reltime precision: " + layout)
+			}
+		} else if verb != fmtVerbTime && verb != fmtVerbLevelColor && verb != fmtVerbCallpath {
 			layout = "%" + layout
 		}
 
@@ -257,6 +338,37 @@ func NewStringFormatter(format string) (Formatter, error) {
 	return fmter, nil
 }
 
+// NewColorFormatter is like NewStringFormatter, but the %{color} verb writes
+// the sequences of scheme instead of the default ones.
+func NewColorFormatter(format string, scheme ColorScheme) (Formatter, error) {
+	f, err := NewStringFormatter(format)
+	if err != nil {
+		return nil, err
+	}
+	f.(*stringFormatter).scheme = scheme
+	return f, nil
+}
+
+// StringFormatterOptions configures NewStringFormatterOptions.
+type StringFormatterOptions struct {
+	// ExpandErrors appends to the %{message} the errors wrapped by the error
+	// args, and by the error of Logger.WithError, each on its own line, with
+	// the stacks of the StackTracer ones. The wrapped errors are the ones
+	// returned by their Unwrap method, eg. the %w of fmt.Errorf.
+	ExpandErrors bool
+}
+
+// NewStringFormatterOptions is like NewStringFormatter, configured by
+// options.
+func NewStringFormatterOptions(format string, options StringFormatterOptions) (Formatter, error) {
+	f, err := NewStringFormatter(format)
+	if err != nil {
+		return nil, err
+	}
+	f.(*stringFormatter).expandErrors = options.ExpandErrors
+	return f, nil
+}
+
 // MustStringFormatter is equivalent to NewStringFormatter with a call to panic
 // on error.
 func MustStringFormatter(format string) Formatter {
@@ -269,6 +381,9 @@ func MustStringFormatter(format string) Formatter {
 
 func (f *stringFormatter) add(verb fmtVerb, layout string) {
 	f.parts = append(f.parts, part{verb, layout})
+	if verb == fmtVerbFields {
+		f.hasFields = true
+	}
 }
 
 func (f *stringFormatter) Format(calldepth int, r *Record, output io.Writer) error {
@@ -278,7 +393,14 @@ func (f *stringFormatter) Format(calldepth int, r *Record, output io.Writer) err
 		} else if part.verb == fmtVerbTime {
 			output.Write([]byte(r.Time.Format(part.layout)))
 		} else if part.verb == fmtVerbLevelColor {
-			doFmtVerbLevelColor(part.layout, r.Level, output)
+			if f.scheme != nil {
+				f.scheme.write(part.layout, r.Level, output)
+			} else {
+				doFmtVerbLevelColor(part.layout, r.Level, output)
+			}
+		} else if part.verb == fmtVerbReltime {
+			prec, _ := strconv.Atoi(part.layout)
+			output.Write([]byte(formatReltime(r.Time, prec)))
 		} else if part.verb == fmtVerbCallpath {
 			depth, err := strconv.Atoi(part.layout)
 			if err != nil {
@@ -292,7 +414,11 @@ func (f *stringFormatter) Format(calldepth int, r *Record, output io.Writer) err
 				v = r.Level
 				break
 			case fmtVerbID:
-				v = r.ID
+				if r.IDString != "" {
+					v = r.IDString
+				} else {
+					v = r.ID
+				}
 				break
 			case fmtVerbPid:
 				v = pid
@@ -300,11 +426,36 @@ func (f *stringFormatter) Format(calldepth int, r *Record, output io.Writer) err
 			case fmtVerbProgram:
 				v = program
 				break
+			case fmtVerbHost:
+				if v, _ = r.Fields.Get(HostKey); v == nil {
+					v = hostname
+				}
+			case fmtVerbApp:
+				if v, _ = r.Fields.Get(AppKey); v == nil {
+					v = program
+				}
 			case fmtVerbModule:
 				v = r.Module
 				break
 			case fmtVerbMessage:
 				v = r.Message()
+				if !f.hasFields {
+					if err, ok := r.Err(); ok {
+						v = r.Message() + ": " + err.Error()
+					}
+				}
+				if f.expandErrors {
+					v = v.(string) + r.expandedErrorsText()
+				}
+				break
+			case fmtVerbFields:
+				v = r.Fields
+				break
+			case fmtVerbGoroutine:
+				if r.goroutine == 0 {
+					r.goroutine = goroutineID()
+				}
+				v = r.goroutine
 				break
 			case fmtVerbLongfile, fmtVerbShortfile:
 				_, file, line, ok := runtime.Caller(calldepth + 1)
@@ -317,12 +468,9 @@ func (f *stringFormatter) Format(calldepth int, r *Record, output io.Writer) err
 				v = fmt.Sprintf("%s:%d", file, line)
 			case fmtVerbLongfunc, fmtVerbShortfunc,
 				fmtVerbLongpkg, fmtVerbShortpkg:
-				// TODO cache pc
 				v = "???"
-				if pc, _, _, ok := runtime.Caller(calldepth + 1); ok {
-					if f := runtime.FuncForPC(pc); f != nil {
-						v = formatFuncName(part.verb, f.Name())
-					}
+				if f := callerFunc(calldepth + 1); f != "" {
+					v = formatFuncName(part.verb, f)
 				}
 			default:
 				panic("unhandled format part")
@@ -359,11 +507,48 @@ func formatFuncName(v fmtVerb, f string) string {
 	panic("unexpected func formatter")
 }
 
+// goroutineID returns the id of the current goroutine, parsed from the
+// "goroutine N [running]:" header of its stack trace.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// callerFunc returns the full name of the function calldepth frames above its
+// caller. Unlike runtime.FuncForPC, inlined functions are reported instead of
+// the function they were inlined into.
+func callerFunc(calldepth int) string {
+	var pcs [1]uintptr
+	if runtime.Callers(calldepth+2, pcs[:]) == 0 {
+		return ""
+	}
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	return frame.Function
+}
+
 func formatCallpath(calldepth int, depth int) string {
 	v := ""
 	callers := make([]uintptr, 64)
 	n := runtime.Callers(calldepth+2, callers)
-	oldPc := callers[n-1]
+
+	// Expand the program counters into frames so inlined calls are kept.
+	var frames []runtime.Frame
+	it := runtime.CallersFrames(callers[:n])
+	for {
+		frame, more := it.Next()
+		frames = append(frames, frame)
+		if !more {
+			break
+		}
+	}
+	n = len(frames)
+	old := frames[n-1]
 
 	start := n - 3
 	if depth > 0 && start >= depth {
@@ -372,12 +557,12 @@ func formatCallpath(calldepth int, depth int) string {
 	}
 	recursiveCall := false
 	for i := start; i >= 0; i-- {
-		pc := callers[i]
-		if oldPc == pc {
+		frame := frames[i]
+		if old.Function == frame.Function && old.Line == frame.Line {
 			recursiveCall = true
 			continue
 		}
-		oldPc = pc
+		old = frame
 		if recursiveCall {
 			recursiveCall = false
 			v += ".."
@@ -385,8 +570,8 @@ func formatCallpath(calldepth int, depth int) string {
 		if i < start {
 			v += "."
 		}
-		if f := runtime.FuncForPC(pc); f != nil {
-			v += formatFuncName(fmtVerbShortfunc, f.Name())
+		if frame.Function != "" {
+			v += formatFuncName(fmtVerbShortfunc, frame.Function)
 		}
 	}
 	return v
@@ -407,8 +592,20 @@ func NewBackendFormatter(b Backend, f Formatter) Backend {
 
 // Log implements the Log function required by the Backend interface.
 func (bf *backendFormatter) Log(level Level, calldepth int, r *Record) error {
-	// Make a shallow copy of the record and replace any formatter
+	// Make a shallow copy of the record and replace any formatter. The
+	// formatted cache is cleared, since the record may have been formatted
+	// by another formatter before, eg. in a hook or other backend.
 	r2 := *r
 	r2.formatter = bf.f
+	r2.formatted = ""
 	return bf.b.Log(level, calldepth+1, &r2)
 }
+
+// Flush implements the Flusher interface.
+func (bf *backendFormatter) Flush() error {
+	return FlushBackend(bf.b)
+}
+
+func (bf *backendFormatter) wrappedBackends() []Backend {
+	return []Backend{bf.b}
+}
@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+)
+
+// RecordTransform changes a record before it's formatted by a ChainFormatter,
+// eg. to mask or add fields.
+type RecordTransform func(r *Record)
+
+// chainFormatter formats the records changed by the transforms.
+type chainFormatter struct {
+	final      Formatter
+	transforms []RecordTransform
+}
+
+// ChainFormatter returns a Formatter which passes the record through the
+// transforms, in order, before formatting it with final, eg. to mask the
+// secrets and add the host of the records formatted as JSON:
+//
+//	ChainFormatter(NewJSONFormatter(JSONFormatterOptions{}), MaskFields("password"), AddFields("host", host))
+//
+// The transforms change a clone of the record, so the record passed to the
+// other backends isn't changed. Changing the Args of the clone changes the
+// message.
+func ChainFormatter(final Formatter, transforms ...RecordTransform) Formatter {
+	return &chainFormatter{final, transforms}
+}
+
+// Format implements the Formatter interface.
+func (f *chainFormatter) Format(calldepth int, r *Record, output io.Writer) error {
+	r = r.Clone()
+	// the message is built again, from the Args changed by the transforms
+	r.message = nil
+	for _, transform := range f.transforms {
+		transform(r)
+	}
+	return f.final.Format(calldepth+1, r, output)
+}
+
+// MaskFields returns a RecordTransform which replaces the values of the fields
+// of keys by asterisks, see Redact.
+func MaskFields(keys ...string) RecordTransform {
+	return func(r *Record) {
+		for i, field := range r.Fields {
+			for _, key := range keys {
+				if field.Key == key {
+					r.Fields[i].Value = Redact(fmt.Sprint(field.Value))
+					break
+				}
+			}
+		}
+	}
+}
+
+// AddFields returns a RecordTransform which appends the key/value pairs, as
+// in NewFields, to the fields of the records.
+func AddFields(keyvals ...interface{}) RecordTransform {
+	fields := NewFields(keyvals...)
+	return func(r *Record) {
+		r.Fields = append(r.Fields, fields...)
+	}
+}
@@ -0,0 +1,26 @@
+package logging
+
+import "testing"
+
+func TestChainFormatter(t *testing.T) {
+	InitForTesting(DEBUG)
+	plain := NewMemoryBackend(8)
+	chained := NewMemoryBackend(8)
+	chain := ChainFormatter(NewLogfmtFormatter(LogfmtFormatterOptions{TimeKey: "-", CallerKey: "-"}),
+		MaskFields("password"),
+		AddFields("host", "web1"),
+		func(r *Record) { r.Args = append(r.Args, "(chained)") },
+	)
+	SetBackend(MultiLogger(plain, NewBackendFormatter(chained, chain)))
+
+	NewLogger("auth").WithFields("user", "bob", "password", "secret").Info("login")
+
+	expected := `level=info module=auth msg="login (chained)" user=bob password=****** host=web1`
+	if lines := memoryRecords(chained); len(lines) != 1 || lines[0] != expected {
+		t.Errorf("unexpected chained records %q", lines)
+	}
+	rec := MemoryRecordN(plain, 0)
+	if password, _ := rec.Fields.Get("password"); password != "secret" || len(rec.Fields) != 2 || rec.Message() != "login" {
+		t.Errorf("record changed by the transforms: %v %q", rec.Fields, rec.Message())
+	}
+}
@@ -0,0 +1,163 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// JSONFormatterOptions configures the JSONFormatter. Empty keys use the
+// default name, and a key set to "-" omits the field from the output.
+type JSONFormatterOptions struct {
+	IDKey      string
+	TimeKey    string
+	ModuleKey  string
+	LevelKey   string
+	MessageKey string
+	FieldsKey  string
+	CallerKey  string
+	// ErrorKey is the key of the expanded error, see ExpandErrors.
+	ErrorKey string
+	// PrefixKey is the key of the Record.Prefix, which is output only when
+	// set. The message is output without it.
+	PrefixKey string
+
+	// TimeLayout is the layout used to format the time. Defaults to
+	// time.RFC3339Nano.
+	TimeLayout string
+
+	// NumericLevel outputs the level as number instead of lowercase string.
+	NumericLevel bool
+
+	// ExpandErrors adds the first error of the args, or the error of
+	// Logger.WithError, as the ErrorKey object, with the errors wrapped by it,
+	// returned by their Unwrap method, in its causes, eg:
+	//
+	//	"error":{"message":"load: not found","causes":[{"message":"not found"}]}
+	//
+	// The errors implementing StackTracer have their stack too.
+	ExpandErrors bool
+
+	// FieldOrder, if set, pins the keys listed into the front of the object,
+	// in that order, and sorts the structured fields by key, so the output
+	// is deterministic. The keys are the output ones, eg. the TimeKey, and
+	// the keys of the structured fields, pinned into the front of the fields
+	// object. An empty, not nil, FieldOrder only sorts the structured fields.
+	FieldOrder []string
+}
+
+// JSONFormatter is a Formatter which outputs the record as a single line JSON
+// object.
+type JSONFormatter struct {
+	Options JSONFormatterOptions
+}
+
+// NewJSONFormatter returns a new JSONFormatter with options. Empty options
+// are filled with the default values.
+func NewJSONFormatter(options JSONFormatterOptions) *JSONFormatter {
+	defaults := []struct {
+		v   *string
+		def string
+	}{
+		{&options.IDKey, "id"},
+		{&options.TimeKey, "time"},
+		{&options.ModuleKey, "module"},
+		{&options.LevelKey, "level"},
+		{&options.MessageKey, "message"},
+		{&options.FieldsKey, "fields"},
+		{&options.CallerKey, "caller"},
+		{&options.ErrorKey, "error"},
+		{&options.PrefixKey, "prefix"},
+		{&options.TimeLayout, time.RFC3339Nano},
+	}
+	for _, d := range defaults {
+		if *d.v == "" {
+			*d.v = d.def
+		}
+	}
+	return &JSONFormatter{options}
+}
+
+// Format implements the Formatter interface.
+func (f *JSONFormatter) Format(calldepth int, r *Record, output io.Writer) (err error) {
+	var (
+		buf   bytes.Buffer
+		level interface{} = strings.ToLower(r.Level.String())
+	)
+	if f.Options.NumericLevel {
+		level = int(r.Level)
+	}
+
+	var entries Fields
+	add := func(key string, value interface{}) {
+		if key != "-" {
+			entries = append(entries, Field{key, value})
+		}
+	}
+
+	if r.IDString != "" {
+		add(f.Options.IDKey, r.IDString)
+	} else {
+		add(f.Options.IDKey, r.ID)
+	}
+	add(f.Options.TimeKey, r.Time.Format(f.Options.TimeLayout))
+	add(f.Options.ModuleKey, r.Module)
+	add(f.Options.LevelKey, level)
+	if r.Prefix != "" {
+		add(f.Options.PrefixKey, r.Prefix)
+	}
+	add(f.Options.MessageKey, r.text())
+	if f.Options.ExpandErrors {
+		if errs := r.errors(); len(errs) > 0 {
+			add(f.Options.ErrorKey, expandError(errs[0]))
+		}
+	}
+	if len(r.Fields) > 0 {
+		fields := r.Fields
+		if f.Options.FieldOrder != nil {
+			fields = orderFields(fields, f.Options.FieldOrder, true)
+		}
+		add(f.Options.FieldsKey, fields)
+	}
+	if f.Options.CallerKey != "-" {
+		caller := "???:0"
+		file, line, _, ok := r.Caller()
+		if !ok {
+			_, file, line, ok = runtime.Caller(calldepth + 1)
+		}
+		if ok {
+			caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+		}
+		add(f.Options.CallerKey, caller)
+	}
+	if f.Options.FieldOrder != nil {
+		entries = orderFields(entries, f.Options.FieldOrder, false)
+	}
+
+	for _, entry := range entries {
+		var data []byte
+		if data, err = json.Marshal(entry.Value); err != nil {
+			return
+		}
+		if buf.Len() == 0 {
+			buf.WriteByte('{')
+		} else {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(entry.Key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(data)
+	}
+	if buf.Len() == 0 {
+		buf.WriteByte('{')
+	}
+	buf.WriteByte('}')
+	_, err = output.Write(buf.Bytes())
+	return
+}
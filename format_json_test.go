@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestJSONFormatter(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	SetFormatter(NewJSONFormatter(JSONFormatterOptions{}))
+
+	log := GetOrCreateLogger("module")
+	log.Debugf("hello %q", "world")
+
+	line := MemoryRecordN(backend, 0).Formatted(0)
+	expected := `{"id":1,"time":"1970-01-01T00:00:00Z","module":"module","level":"debug","message":"hello \"world\"","caller":"format_json_test.go:13"}`
+	if expected != line {
+		t.Errorf("Unexpected format: %s", line)
+	}
+}
+
+func TestJSONFormatterOptions(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	SetFormatter(NewJSONFormatter(JSONFormatterOptions{
+		IDKey:        "-",
+		TimeKey:      "ts",
+		TimeLayout:   "2006-01-02",
+		ModuleKey:    "-",
+		LevelKey:     "lvl",
+		MessageKey:   "msg",
+		CallerKey:    "-",
+		NumericLevel: true,
+	}))
+
+	log := GetOrCreateLogger("module")
+	log.Error("hello")
+
+	line := MemoryRecordN(backend, 0).Formatted(0)
+	if `{"ts":"1970-01-01","lvl":1,"msg":"hello"}` != line {
+		t.Errorf("Unexpected format: %s", line)
+	}
+}
+
+func TestJSONFormatterBackend(t *testing.T) {
+	InitForTesting(DEBUG)
+	buf := &bytes.Buffer{}
+	SetBackend(NewBackendFormatter(NewLogBackend(buf, "", 0), NewJSONFormatter(JSONFormatterOptions{
+		IDKey:     "-",
+		TimeKey:   "-",
+		CallerKey: "-",
+	})))
+
+	GetOrCreateLogger("module").Info("a", "b")
+	GetOrCreateLogger("module").Info("c")
+
+	expected := `{"module":"module","level":"info","message":"a b"}` + "\n" +
+		`{"module":"module","level":"info","message":"c"}` + "\n"
+	if expected != buf.String() {
+		t.Errorf("Unexpected output: %s", buf.String())
+	}
+}
+
+func TestJSONFormatterFields(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	SetFormatter(NewJSONFormatter(JSONFormatterOptions{
+		IDKey:     "-",
+		TimeKey:   "-",
+		CallerKey: "-",
+	}))
+
+	GetOrCreateLogger("module").WithFields("a", 1, "b", "c").Info("hello")
+
+	line := MemoryRecordN(backend, 0).Formatted(0)
+	if `{"module":"module","level":"info","message":"hello","fields":{"a":1,"b":"c"}}` != line {
+		t.Errorf("Unexpected format: %s", line)
+	}
+}
+
+func TestJSONFormatterPrefix(t *testing.T) {
+	InitForTesting(DEBUG)
+	buf := &bytes.Buffer{}
+	SetBackend(NewBackendFormatter(NewLogBackend(buf, "", 0), NewJSONFormatter(JSONFormatterOptions{
+		IDKey:     "-",
+		TimeKey:   "-",
+		CallerKey: "-",
+	})))
+
+	log := WithPrefix(WithPrefix(GetOrCreateLogger("module"), "a"), "100%")
+	log.Infof("%[2]s %[1]s", "world", "hello")
+	log.WithFields("k", "v").Info("fields")
+
+	expected := `{"module":"module","level":"info","prefix":"a -\u003e 100% -\u003e","message":"hello world"}` + "\n" +
+		`{"module":"module","level":"info","prefix":"a -\u003e 100% -\u003e","message":"fields","fields":{"k":"v"}}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected format: %s", buf.String())
+	}
+}
+
+func TestJSONFormatterFieldOrder(t *testing.T) {
+	InitForTesting(DEBUG)
+	buf := &bytes.Buffer{}
+	SetBackend(NewBackendFormatter(NewLogBackend(buf, "", 0), NewJSONFormatter(JSONFormatterOptions{
+		IDKey:      "-",
+		TimeKey:    "-",
+		CallerKey:  "-",
+		FieldOrder: []string{"level", "message", "request"},
+	})))
+
+	GetOrCreateLogger("module").WithFields("z", 1, "request", "r1", "a", 2).Info("hello")
+
+	expected := `{"level":"info","message":"hello","module":"module","fields":{"request":"r1","a":2,"z":1}}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected format: %s", buf.String())
+	}
+}
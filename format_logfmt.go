@@ -0,0 +1,156 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// LogfmtFormatterOptions configures the LogfmtFormatter. Empty keys use the
+// default name, and a key set to "-" omits the field from the output.
+type LogfmtFormatterOptions struct {
+	TimeKey    string
+	LevelKey   string
+	ModuleKey  string
+	MessageKey string
+	CallerKey  string
+	// PrefixKey is the key of the Record.Prefix, which is output only when
+	// set. The message is output without it.
+	PrefixKey string
+
+	// TimeLayout is the layout used to format the time. Defaults to
+	// time.RFC3339Nano.
+	TimeLayout string
+
+	// FieldOrder, if set, pins the keys listed into the front of the line,
+	// in that order, and sorts the structured fields by key, so the output
+	// is deterministic. The keys are the output ones, eg. the TimeKey, or
+	// the keys of the structured fields. An empty, not nil, FieldOrder only
+	// sorts the structured fields.
+	FieldOrder []string
+}
+
+// LogfmtFormatter is a Formatter which outputs the record as a single line of
+// logfmt key=value pairs, eg:
+//
+//	ts=2006-01-02T15:04:05Z level=info module=foo msg="hello world" caller=main.go:10
+//
+// The structured fields of the record are appended as additional pairs.
+type LogfmtFormatter struct {
+	Options LogfmtFormatterOptions
+}
+
+// NewLogfmtFormatter returns a new LogfmtFormatter with options. Empty options
+// are filled with the default values.
+func NewLogfmtFormatter(options LogfmtFormatterOptions) *LogfmtFormatter {
+	defaults := []struct {
+		v   *string
+		def string
+	}{
+		{&options.TimeKey, "ts"},
+		{&options.LevelKey, "level"},
+		{&options.ModuleKey, "module"},
+		{&options.MessageKey, "msg"},
+		{&options.CallerKey, "caller"},
+		{&options.PrefixKey, "prefix"},
+		{&options.TimeLayout, time.RFC3339Nano},
+	}
+	for _, d := range defaults {
+		if *d.v == "" {
+			*d.v = d.def
+		}
+	}
+	return &LogfmtFormatter{options}
+}
+
+// Format implements the Formatter interface.
+func (f *LogfmtFormatter) Format(calldepth int, r *Record, output io.Writer) (err error) {
+	var (
+		buf     bytes.Buffer
+		entries Fields
+	)
+	add := func(key string, value interface{}) {
+		if key != "-" {
+			entries = append(entries, Field{key, value})
+		}
+	}
+
+	add(f.Options.TimeKey, r.Time.Format(f.Options.TimeLayout))
+	add(f.Options.LevelKey, strings.ToLower(r.Level.String()))
+	add(f.Options.ModuleKey, r.Module)
+	if r.Prefix != "" {
+		add(f.Options.PrefixKey, r.Prefix)
+	}
+	add(f.Options.MessageKey, r.text())
+	fields := r.Fields
+	if f.Options.FieldOrder != nil {
+		fields = orderFields(fields, nil, true)
+	}
+	for _, field := range fields {
+		add(field.Key, field.redactedValue())
+	}
+	if f.Options.CallerKey != "-" {
+		caller := "???:0"
+		file, line, _, ok := r.Caller()
+		if !ok {
+			_, file, line, ok = runtime.Caller(calldepth + 1)
+		}
+		if ok {
+			caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+		}
+		add(f.Options.CallerKey, caller)
+	}
+	if f.Options.FieldOrder != nil {
+		entries = orderFields(entries, f.Options.FieldOrder, false)
+	}
+
+	for i, entry := range entries {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		writeLogfmt(&buf, entry.Key, entry.Value)
+	}
+	_, err = output.Write(buf.Bytes())
+	return
+}
+
+// writeLogfmt writes key=value into buf. Values which are empty or contain
+// spaces, quotes, equals signs or control characters are quoted, so the pair
+// never spans multiple lines. Keys can't be quoted, so these characters, and
+// the invalid UTF-8 bytes, are replaced by '_' in them.
+func writeLogfmt(buf *bytes.Buffer, key string, value interface{}) {
+	buf.WriteString(logfmtKey(key))
+	buf.WriteByte('=')
+	v := fmt.Sprint(value)
+	if v == "" || strings.IndexFunc(v, needsLogfmtQuote) >= 0 {
+		v = strconv.Quote(v)
+	}
+	buf.WriteString(v)
+}
+
+func needsLogfmtQuote(r rune) bool {
+	return r <= ' ' || r == '=' || r == '"' || r == 0x7f
+}
+
+// logfmtKey returns key with the characters which would need quoting, and
+// the invalid UTF-8 bytes, replaced by '_'. An empty key is returned as "_".
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	if utf8.ValidString(key) && strings.IndexFunc(key, needsLogfmtQuote) < 0 {
+		return key
+	}
+	return strings.Map(func(r rune) rune {
+		if r == utf8.RuneError || needsLogfmtQuote(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
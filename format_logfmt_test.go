@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLogfmtFormatter(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	SetFormatter(NewLogfmtFormatter(LogfmtFormatterOptions{}))
+
+	log := GetOrCreateLogger("module")
+	log.Debugf("hello %q", "world")
+
+	line := MemoryRecordN(backend, 0).Formatted(0)
+	expected := `ts=1970-01-01T00:00:00Z level=debug module=module msg="hello \"world\"" caller=format_logfmt_test.go:13`
+	if expected != line {
+		t.Errorf("Unexpected format: %s", line)
+	}
+}
+
+func TestLogfmtFormatterBackend(t *testing.T) {
+	InitForTesting(DEBUG)
+	buf := &bytes.Buffer{}
+	SetBackend(NewBackendFormatter(NewLogBackend(buf, "", 0), NewLogfmtFormatter(LogfmtFormatterOptions{
+		TimeKey:   "-",
+		CallerKey: "-",
+	})))
+
+	log := GetOrCreateLogger("module")
+	log.WithFields("user", "john doe", "expr", "a=b", "empty", "", "n", 1).Info("line1\nline2")
+
+	expected := `level=info module=module msg="line1\nline2" user="john doe" expr="a=b" empty="" n=1` + "\n"
+	if expected != buf.String() {
+		t.Errorf("Unexpected output: %s", buf.String())
+	}
+}
+
+func TestLogfmtFormatterKeys(t *testing.T) {
+	InitForTesting(DEBUG)
+	buf := &bytes.Buffer{}
+	SetBackend(NewBackendFormatter(NewLogBackend(buf, "", 0), NewLogfmtFormatter(LogfmtFormatterOptions{
+		TimeKey:   "-",
+		CallerKey: "-",
+	})))
+
+	log := GetOrCreateLogger("module")
+	log.WithFields("user name", 1, "a=b", 2, `"q"`, 3, "new\nline", 4, "bad\xffutf8", 5, "", 6, "ação", 7).Info("keys")
+
+	expected := `level=info module=module msg=keys user_name=1 a_b=2 _q_=3 new_line=4 bad_utf8=5 _=6 ação=7` + "\n"
+	if expected != buf.String() {
+		t.Errorf("Unexpected output: %s", buf.String())
+	}
+}
+
+func TestLogfmtFormatterPrefix(t *testing.T) {
+	InitForTesting(DEBUG)
+	buf := &bytes.Buffer{}
+	SetBackend(NewBackendFormatter(NewLogBackend(buf, "", 0), NewLogfmtFormatter(LogfmtFormatterOptions{
+		TimeKey:   "-",
+		CallerKey: "-",
+	})))
+
+	WithPrefix(GetOrCreateLogger("module"), "db").Info("connected")
+
+	if expected := "level=info module=module prefix=\"db ->\" msg=connected\n"; buf.String() != expected {
+		t.Errorf("Unexpected format: %q", buf.String())
+	}
+}
+
+func TestLogfmtFormatterFieldOrder(t *testing.T) {
+	InitForTesting(DEBUG)
+	buf := &bytes.Buffer{}
+	SetBackend(NewBackendFormatter(NewLogBackend(buf, "", 0), NewLogfmtFormatter(LogfmtFormatterOptions{
+		TimeKey:    "-",
+		CallerKey:  "-",
+		FieldOrder: []string{"level", "msg", "request"},
+	})))
+
+	log := GetOrCreateLogger("module")
+	log.WithFields("z", 1, "request", "r1", "a", 2).Info("hello")
+	log.WithFields("a", 2, "z", 1, "request", "r1").Info("hello")
+
+	line := "level=info msg=hello request=r1 module=module a=2 z=1\n"
+	if buf.String() != line+line {
+		t.Errorf("Unexpected format: %q", buf.String())
+	}
+}
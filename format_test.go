@@ -6,7 +6,12 @@ package logging
 
 import (
 	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestFormat(t *testing.T) {
@@ -22,7 +27,7 @@ func TestFormat(t *testing.T) {
 	log.Debug("hello")
 
 	line := MemoryRecordN(backend, 0).Formatted(0)
-	if "format_test.go:24 1970-01-01T00:00:00 D 0001 module hello" != line {
+	if "format_test.go:29 1970-01-01T00:00:00 D 0001 module hello" != line {
 		t.Errorf("Unexpected format: %s", line)
 	}
 }
@@ -111,12 +116,12 @@ func TestFormatFuncName(t *testing.T) {
 			"main"},
 		{"github.com/moisespsena-go/logging.func·001",
 			"github.com/moisespsena-go/logging",
-			"go-logging",
+			"logging",
 			"func·001",
 			"func·001"},
 		{"github.com/moisespsena-go/logging.stringFormatter.Format",
 			"github.com/moisespsena-go/logging",
-			"go-logging",
+			"logging",
 			"stringFormatter.Format",
 			"Format"},
 	}
@@ -164,6 +169,27 @@ func TestBackendFormatter(t *testing.T) {
 	}
 }
 
+func TestBackendFormatterFormattedRecord(t *testing.T) {
+	InitForTesting(DEBUG)
+
+	b := NewMemoryBackend(1)
+	bf := NewBackendFormatter(b, MustStringFormatter("%{level} %{message}"))
+
+	rec := &Record{Level: INFO, Args: []interface{}{"foo"}, formatter: DefaultFormatter}
+	if line := rec.Formatted(0); line != "foo" {
+		t.Fatalf("Unexpected line: %s", line)
+	}
+	if err := bf.Log(INFO, 0, rec); err != nil {
+		t.Fatal(err)
+	}
+	if "INFO foo" != getLastLine(b) {
+		t.Errorf("Unexpected line: %s", getLastLine(b))
+	}
+	if line := rec.Formatted(0); line != "foo" {
+		t.Errorf("Record changed: %s", line)
+	}
+}
+
 func BenchmarkStringFormatter(b *testing.B) {
 	fmt := "%{time:2006-01-02T15:04:05} %{level:.1s} %{id:04d} %{module} %{message}"
 	f := MustStringFormatter(fmt)
@@ -182,3 +208,158 @@ func BenchmarkStringFormatter(b *testing.B) {
 		}
 	}
 }
+
+func TestPrefixFuncFormat(t *testing.T) {
+	InitForTesting(DEBUG)
+	buf := &bytes.Buffer{}
+	SetBackend(NewBackendFormatter(NewLogBackend(buf, "", 0), MustStringFormatter("%{shortfunc} %{message}")))
+
+	log := WithPrefix(WithPrefix(GetOrCreateLogger("test"), "a"), "b")
+	log.Info("hello")
+	log.WithFields("k", "v").Info("fields")
+
+	expected := "TestPrefixFuncFormat a -> b -> hello\nTestPrefixFuncFormat a -> b -> fields\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected format: %q", buf.String())
+	}
+}
+
+func TestGoroutineFormat(t *testing.T) {
+	InitForTesting(DEBUG)
+	buf := &bytes.Buffer{}
+	SetBackend(NewBackendFormatter(NewLogBackend(buf, "", 0), MustStringFormatter("%{goroutine} %{message}")))
+
+	id := goroutineID()
+	if id == 0 {
+		t.Fatal("failed to parse the goroutine id")
+	}
+	GetOrCreateLogger("test").Info("hello")
+
+	done := make(chan uint64)
+	go func() {
+		GetOrCreateLogger("test").Info("other")
+		done <- goroutineID()
+	}()
+	other := <-done
+
+	itoa := func(v uint64) string {
+		return (&Record{Args: []interface{}{v}}).Message()
+	}
+	expected := itoa(id) + " hello\n" + itoa(other) + " other\n"
+	if id == other || buf.String() != expected {
+		t.Errorf("Unexpected format: %q", buf.String())
+	}
+}
+
+func TestGoroutineFormatAsync(t *testing.T) {
+	InitForTesting(DEBUG)
+	buf := &bytes.Buffer{}
+	async := AsyncMultiLogger(AsyncMultiOptions{}, NewBackendFormatter(NewLogBackend(buf, "", 0), MustStringFormatter("%{goroutine} %{message}")))
+	defer async.Close()
+	SetBackend(async)
+
+	GetOrCreateLogger("test").Info("hello")
+	if err := async.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := fmt.Sprintf("%d hello\n", goroutineID())
+	if buf.String() != expected {
+		t.Errorf("the goroutine of the worker was formatted: %q", buf.String())
+	}
+}
+
+func TestRawFormatter(t *testing.T) {
+	InitForTesting(DEBUG)
+	defer SetFormatter(DefaultFormatter)
+
+	SetFormatter(RawFormatter)
+	if GetFormatter() != RawFormatter {
+		t.Error("GetFormatter doesn't return the formatter set")
+	}
+
+	buf := &bytes.Buffer{}
+	SetBackend(NewBackendFormatter(NewLogBackend(buf, "", 0), RawFormatter))
+	GetOrCreateLogger("test").WithFields("k", "v").Info("hello")
+	if buf.String() != "hello\n" {
+		t.Errorf("Unexpected format: %q", buf.String())
+	}
+}
+
+func TestFormattedByFormatter(t *testing.T) {
+	InitForTesting(DEBUG)
+
+	rec := &Record{Level: INFO, Args: []interface{}{"foo"}, formatter: DefaultFormatter}
+	if line := rec.Formatted(0); line != "foo" {
+		t.Fatalf("Unexpected line: %s", line)
+	}
+	rec.formatter = MustStringFormatter("%{level} %{message}")
+	if line := rec.Formatted(0); line != "INFO foo" {
+		t.Errorf("Unexpected line: %s", line)
+	}
+}
+
+func TestBackendFormatterFiles(t *testing.T) {
+	InitForTesting(DEBUG)
+
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	formats := map[string]string{
+		"level.log":  "%{level} %{message}",
+		"module.log": "%{module}: %{message}",
+	}
+	var backends []Backend
+	for name, format := range formats {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		backends = append(backends, NewBackendFormatter(NewLogBackend(f, "", 0), MustStringFormatter(format)))
+	}
+	SetBackend(backends...)
+
+	GetOrCreateLogger("module").Info("foo")
+
+	for name, expected := range map[string]string{"level.log": "INFO foo\n", "module.log": "module: foo\n"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Errorf("%s: %q != %q", name, data, expected)
+		}
+	}
+}
+
+func TestFormatReltime(t *testing.T) {
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	Reset(ResetOptions{TimeNow: func() time.Time { return start }})
+	defer Reset()
+
+	rec := &Record{Time: start.Add(1234567 * time.Microsecond), Args: []interface{}{"foo"}}
+	for format, expected := range map[string]string{
+		"%{reltime} %{message}": "+1.235s foo",
+		"%{reltime:6}":          "+1.234567s",
+		"%{reltime:0}":          "+1s",
+	} {
+		rec.formatter = MustStringFormatter(format)
+		rec.formatted = ""
+		if line := rec.Formatted(0); line != expected {
+			t.Errorf("%q: expected %q, got %q", format, expected, line)
+		}
+	}
+
+	rec.Time = start.Add(-time.Second)
+	rec.formatter, rec.formatted = MustStringFormatter("%{reltime:1}"), ""
+	if line := rec.Formatted(0); line != "-1.0s" {
+		t.Errorf("unexpected line: %q", line)
+	}
+
+	if _, err := NewStringFormatter("%{reltime:x}"); err == nil {
+		t.Error("invalid precision accepted")
+	}
+}
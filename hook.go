@@ -0,0 +1,86 @@
+package logging
+
+// Hook is called with the records logged through a hook backend, eg. to
+// count the errors or to send alerts.
+type Hook interface {
+	// Levels returns the levels which fire the hook.
+	Levels() []Level
+	// Fire is called with each record of one of the hook levels.
+	Fire(*Record) error
+}
+
+// AllLevels contains all levels, from the most to the least severe.
+var AllLevels = []Level{CRITICAL, ERROR, WARNING, NOTICE, INFO, DEBUG}
+
+// LevelsFrom returns the levels at or above level, eg. LevelsFrom(ERROR)
+// returns CRITICAL and ERROR.
+func LevelsFrom(level Level) []Level {
+	if level < 0 {
+		return nil
+	}
+	if int(level) >= len(AllLevels) {
+		level = DEBUG
+	}
+	return append([]Level(nil), AllLevels[:level+1]...)
+}
+
+// hookBackend fires the hooks before passing the records to the inner
+// backend.
+type hookBackend struct {
+	inner Backend
+	hooks [DEBUG + 1][]Hook
+}
+
+// NewHookBackend creates a backend which fires the hooks of the record level
+// and then passes the record to inner. The hooks are fired in the given order,
+// before inner. An error returned by a hook doesn't abort the log: it's passed
+// to the default error handler (see SetErrorHandler).
+//
+// The hooks are fired only for the records which pass the level filter of the
+// backend, so it can be used with SetBackend and AddModuleLevel. The returned
+// backend implements Printer if inner does.
+func NewHookBackend(inner Backend, hooks ...Hook) Backend {
+	b := &hookBackend{inner: inner}
+	for _, hook := range hooks {
+		for _, level := range hook.Levels() {
+			if level >= 0 && int(level) < len(b.hooks) {
+				b.hooks[level] = append(b.hooks[level], hook)
+			}
+		}
+	}
+	if _, ok := inner.(Printer); ok {
+		return &hookPrinter{b}
+	}
+	return b
+}
+
+// Log implements the Backend interface.
+func (b *hookBackend) Log(level Level, calldepth int, rec *Record) error {
+	if level >= 0 && int(level) < len(b.hooks) {
+		for _, hook := range b.hooks[level] {
+			if err := hook.Fire(rec); err != nil {
+				HandleError(err)
+			}
+		}
+	}
+	return b.inner.Log(level, calldepth+1, rec)
+}
+
+// Flush implements the Flusher interface.
+func (b *hookBackend) Flush() error {
+	return FlushBackend(b.inner)
+}
+
+func (b *hookBackend) wrappedBackends() []Backend {
+	return []Backend{b.inner}
+}
+
+type hookPrinter struct {
+	*hookBackend
+}
+
+// Print implements the Printer interface. Printed values have no level, so no
+// hook is fired.
+func (b *hookPrinter) Print(args ...interface{}) error {
+	return b.inner.(Printer).Print(args...)
+}
@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"errors"
+	"testing"
+)
+
+type countHook struct {
+	levels []Level
+	count  int
+	err    error
+}
+
+func (h *countHook) Levels() []Level {
+	return h.levels
+}
+
+func (h *countHook) Fire(*Record) error {
+	h.count++
+	return h.err
+}
+
+func TestHookBackend(t *testing.T) {
+	InitForTesting(DEBUG)
+	memory := NewMemoryBackend(8)
+	errs := &countHook{levels: LevelsFrom(ERROR)}
+	all := &countHook{levels: AllLevels, err: errors.New("hook failed")}
+	SetBackend(NewHookBackend(memory, errs, all)).SetLevel(INFO, "")
+
+	var handled []error
+	SetErrorHandler(func(err error) { handled = append(handled, err) })
+	defer SetErrorHandler(nil)
+
+	log := GetOrCreateLogger("test")
+	log.Critical("critical")
+	log.Error("error")
+	log.Warning("warning")
+	log.Debug("filtered")
+
+	if errs.count != 2 {
+		t.Errorf("expected 2 errors, got %d", errs.count)
+	}
+	if all.count != 3 {
+		t.Errorf("expected 3 records, got %d", all.count)
+	}
+	if len(handled) != 3 {
+		t.Errorf("expected 3 hook errors, got %d", len(handled))
+	}
+	if lines := memoryRecords(memory); len(lines) != 3 {
+		t.Errorf("hook errors aborted the log: %q", lines)
+	}
+}
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"sync/atomic"
+)
+
+var (
+	// idGenerator stores the func() uint64 set by SetIDGenerator.
+	idGenerator atomic.Value
+	// idStringGenerator stores the func() string set by
+	// SetIDStringGenerator.
+	idStringGenerator atomic.Value
+)
+
+// SetIDGenerator sets the function returning the ID of the new records, eg. a
+// snowflake generator, so the IDs are unique across the processes. Nil
+// restores the default, the process sequence starting from SetSequence.
+func SetIDGenerator(f func() uint64) {
+	idGenerator.Store(f)
+}
+
+// SetIDStringGenerator sets the function returning the Record.IDString of the
+// new records, eg. a ULID generator. The %{id} verb and the JSONFormatter
+// output the IDString instead of the ID when it isn't empty. Nil disables it.
+func SetIDStringGenerator(f func() string) {
+	idStringGenerator.Store(f)
+}
+
+// nextID returns the ID of a new record.
+func nextID() uint64 {
+	if f, ok := idGenerator.Load().(func() uint64); ok && f != nil {
+		return f()
+	}
+	return atomic.AddUint64(&sequenceNo, 1)
+}
+
+// nextIDString returns the IDString of a new record, or an empty string
+// without generator.
+func nextIDString() string {
+	if f, ok := idStringGenerator.Load().(func() string); ok && f != nil {
+		return f()
+	}
+	return ""
+}
+
+// setID sets the ID and the IDString of a new record.
+func (r *Record) setID() {
+	r.ID, r.IDString = nextID(), nextIDString()
+}
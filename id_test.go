@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSetIDGenerator(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	defer Reset()
+
+	SetIDGenerator(func() uint64 { return 42 })
+	log := NewLogger("id")
+	log.Info("generated")
+	if id := MemoryRecordN(backend, 0).ID; id != 42 {
+		t.Errorf("unexpected ID: %d", id)
+	}
+
+	SetIDGenerator(nil)
+	SetSequence(7)
+	log.Info("sequence")
+	if id := MemoryRecordN(backend, 1).ID; id != 8 {
+		t.Errorf("unexpected ID: %d", id)
+	}
+}
+
+func TestSetIDStringGenerator(t *testing.T) {
+	InitForTesting(DEBUG)
+	defer Reset()
+	SetIDStringGenerator(func() string { return "01ARZ3NDEKTSV4RRFFQ69G5FAV" })
+
+	var buf bytes.Buffer
+	backend := NewLogBackend(&buf, "", 0)
+	leveled := AddModuleLevel(NewBackendFormatter(backend, MustStringFormatter("%{id} %{message}")))
+	log := NewLogger("id")
+	log.SetBackend(leveled)
+	log.Info("ulid")
+	if buf.String() != "01ARZ3NDEKTSV4RRFFQ69G5FAV ulid\n" {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+
+	buf.Reset()
+	log.SetBackend(AddModuleLevel(NewBackendFormatter(backend, NewJSONFormatter(JSONFormatterOptions{}))))
+	log.Info("json")
+	if !strings.Contains(buf.String(), `"id":"01ARZ3NDEKTSV4RRFFQ69G5FAV"`) {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+
+	SetIDStringGenerator(nil)
+	if r := PrintRecord(INFO, "print"); r.IDString != "" || r.Data().IDString != "" {
+		t.Errorf("unexpected IDString: %q", r.IDString)
+	}
+}
@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ioWriter logs each line written into it.
+type ioWriter struct {
+	log   Logger
+	level Level
+	// trim removes the unwanted parts of the line, like the stdlib log header.
+	trim func(line []byte) []byte
+	// parse returns the level and the message of the line, replacing level.
+	parse func(line string) (Level, string)
+	mu    sync.Mutex
+	buf   []byte
+}
+
+// IOWriter returns an io.Writer which logs each written line, without the
+// trailing newline, using level. A partial line is kept until its newline is
+// written. It allows the libraries accepting an io.Writer or a *log.Logger to
+// log into this logger:
+//
+//	server := &http.Server{ErrorLog: log.New(logger.IOWriter(logging.ERROR), "", 0)}
+func (l *Log) IOWriter(level Level) io.Writer {
+	// Skip the frames of Write and logLine.
+	return &ioWriter{log: addCalldepth(l, 2), level: level}
+}
+
+// ParsingWriter returns an io.Writer which logs each written line with the
+// level and the message returned by parse, eg. to ingest the output of a
+// subprocess which prints "level=INFO message" lines:
+//
+//	cmd.Stderr = logger.ParsingWriter(logging.LevelFieldParser(logging.INFO))
+//
+// A partial line is kept until its newline is written. The lines parsed as
+// OFF are dropped.
+func (l *Log) ParsingWriter(parse func(line string) (Level, string)) io.Writer {
+	return &ioWriter{log: addCalldepth(l, 2), parse: parse}
+}
+
+// LevelFieldParser returns a parser for ParsingWriter which takes the level
+// from the "level=NAME" prefix of the lines, with the names accepted by
+// ParseLevel, optionally quoted. The lines without a valid level prefix are
+// logged whole using def.
+func LevelFieldParser(def Level) func(line string) (Level, string) {
+	return func(line string) (Level, string) {
+		rest := strings.TrimLeft(line, " \t")
+		if !strings.HasPrefix(rest, "level=") {
+			return def, line
+		}
+		rest = rest[len("level="):]
+		name := rest
+		if i := strings.IndexAny(rest, " \t"); i >= 0 {
+			name, rest = rest[:i], strings.TrimLeft(rest[i:], " \t")
+		} else {
+			rest = ""
+		}
+		level, err := ParseLevel(strings.Trim(name, `"`))
+		if err != nil || level == OFF {
+			return def, line
+		}
+		return level, rest
+	}
+}
+
+// Write implements io.Writer.
+func (w *ioWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.logLine(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+func (w *ioWriter) logLine(line []byte) {
+	if w.trim != nil {
+		line = w.trim(line)
+	}
+	msg, level := string(line), w.level
+	if w.parse != nil {
+		if level, msg = w.parse(msg); level == OFF {
+			return
+		}
+	}
+	switch level {
+	case CRITICAL:
+		w.log.Critical(msg)
+	case ERROR:
+		w.log.Error(msg)
+	case WARNING:
+		w.log.Warning(msg)
+	case NOTICE:
+		w.log.Notice(msg)
+	case INFO:
+		w.log.Info(msg)
+	default:
+		w.log.Debug(msg)
+	}
+}
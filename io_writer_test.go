@@ -0,0 +1,61 @@
+package logging
+
+import (
+	stdlog "log"
+	"path/filepath"
+	"testing"
+)
+
+func TestIOWriter(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	w := NewLogger("test").IOWriter(WARNING)
+
+	w.Write([]byte("first\nsec"))
+	if rec := MemoryRecordN(backend, 1); rec != nil {
+		t.Errorf("partial line logged: %s", rec.Message())
+	}
+	w.Write([]byte("ond\n"))
+	stdlog.New(w, "std: ", 0).Printf("third")
+
+	for i, expected := range []string{"first", "second", "std: third"} {
+		rec := MemoryRecordN(backend, i)
+		if rec == nil || rec.Level != WARNING || rec.Message() != expected {
+			t.Errorf("%d: unexpected record %v, expected %q", i, rec, expected)
+		} else if file, _, _, _ := rec.Caller(); filepath.Base(file) != "io_writer_test.go" && i < 2 {
+			t.Errorf("%d: unexpected caller %s", i, file)
+		}
+	}
+}
+
+func TestParsingWriter(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	w := NewLogger("test").ParsingWriter(LevelFieldParser(NOTICE))
+
+	w.Write([]byte("level=INFO started\nlevel=\"warn"))
+	w.Write([]byte("ing\" disk almost full\nplain line\nlevel=off dropped?\nlevel=E\n"))
+
+	tests := []struct {
+		level Level
+		msg   string
+	}{
+		{INFO, "started"},
+		{WARNING, "disk almost full"},
+		{NOTICE, "plain line"},
+		{NOTICE, "level=off dropped?"},
+		{ERROR, ""},
+	}
+	for i, test := range tests {
+		rec := MemoryRecordN(backend, i)
+		if rec == nil || rec.Level != test.level || rec.Message() != test.msg {
+			t.Errorf("%d: unexpected record %v, expected %s %q", i, rec, test.level, test.msg)
+		}
+	}
+
+	w = NewLogger("test").ParsingWriter(func(line string) (Level, string) {
+		return OFF, line
+	})
+	w.Write([]byte("dropped\n"))
+	if rec := MemoryRecordN(backend, len(tests)); rec != nil {
+		t.Errorf("OFF line logged: %s", rec.Message())
+	}
+}
@@ -5,7 +5,10 @@
 package logging
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -26,6 +29,11 @@ const (
 	DEBUG
 )
 
+// OFF is the level which disables all records, even the CRITICAL ones, eg.
+// SetLevel(OFF, "noisy") silences the "noisy" module. It's only used to set
+// the levels, the records are never logged with it.
+const OFF Level = -1
+
 var levelNames = []string{
 	"CRITICAL",
 	"ERROR",
@@ -35,18 +43,119 @@ var levelNames = []string{
 	"DEBUG",
 }
 
+// levelAliases are the other names of the levels, lowercase, used by the
+// configurations of other loggers. There's no TRACE level, so "trace" is the
+// most verbose one, DEBUG.
+var levelAliases = map[string]Level{
+	"crit":  CRITICAL,
+	"fatal": CRITICAL,
+	"err":   ERROR,
+	"warn":  WARNING,
+	"trace": DEBUG,
+}
+
 // String returns the string representation of a logging level.
 func (p Level) String() string {
+	if p == OFF {
+		return "OFF"
+	}
+	if p < 0 || int(p) >= len(levelNames) {
+		return "Level(" + strconv.Itoa(int(p)) + ")"
+	}
 	return levelNames[p]
 }
 
-// LogLevel returns the log level from a string representation.
-func LogLevel(level string) (Level, error) {
+// ParseLevel returns the level from its name or alias, case insensitive, or
+// from the first letter of the name, eg. "E" or "w". The aliases are "warn"
+// for WARNING, "err" for ERROR, "fatal" and "crit" for CRITICAL, and "trace"
+// for DEBUG. "OFF" and "NONE" are parsed as OFF. ErrInvalidLogLevel is
+// returned for any other name.
+func ParseLevel(level string) (Level, error) {
+	if l, ok := lookupLevel(level); ok {
+		return l, nil
+	}
+	if len(level) == 1 {
+		for i, name := range levelNames {
+			if strings.EqualFold(name[:1], level) {
+				return Level(i), nil
+			}
+		}
+	}
+	return ERROR, ErrInvalidLogLevel
+}
+
+// lookupLevel returns the level of the name or alias level, case insensitive.
+func lookupLevel(level string) (Level, bool) {
+	if isOffLevel(level) {
+		return OFF, true
+	}
 	for i, name := range levelNames {
 		if strings.EqualFold(name, level) {
-			return Level(i), nil
+			return Level(i), true
+		}
+	}
+	l, ok := levelAliases[strings.ToLower(level)]
+	return l, ok
+}
+
+// isOffLevel returns true if level is a name of the OFF level.
+func isOffLevel(level string) bool {
+	return strings.EqualFold(level, "OFF") || strings.EqualFold(level, "NONE")
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (p Level) MarshalText() ([]byte, error) {
+	if p == OFF {
+		return []byte("OFF"), nil
+	}
+	if p < 0 || int(p) >= len(levelNames) {
+		return nil, fmt.Errorf("logger: invalid log level %d", int(p))
+	}
+	return []byte(levelNames[p]), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. It accepts
+// the same values as ParseLevel.
+func (p *Level) UnmarshalText(text []byte) (err error) {
+	*p, err = ParseLevel(string(text))
+	return
+}
+
+// MarshalJSON implements the json.Marshaler interface. The level is encoded as
+// its name, eg. "INFO".
+func (p Level) MarshalJSON() ([]byte, error) {
+	text, err := p.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Besides the values
+// accepted by ParseLevel, the numeric levels are accepted too.
+func (p *Level) UnmarshalJSON(data []byte) (err error) {
+	var v interface{}
+	if err = json.Unmarshal(data, &v); err != nil {
+		return
+	}
+	switch v := v.(type) {
+	case string:
+		return p.UnmarshalText([]byte(v))
+	case float64:
+		if level := Level(v); float64(level) == v && level >= OFF && level <= DEBUG {
+			*p = level
+			return
 		}
 	}
+	return fmt.Errorf("logger: invalid log level %s", data)
+}
+
+// LogLevel returns the log level from a string representation, its name or
+// alias, as accepted by ParseLevel, but not the first letter.
+func LogLevel(level string) (Level, error) {
+	if l, ok := lookupLevel(level); ok {
+		return l, nil
+	}
 	return ERROR, ErrInvalidLogLevel
 }
 
@@ -65,12 +174,21 @@ type LeveledBackend interface {
 	Leveled
 }
 
+// LevelMapper is implemented by the leveled backends which can list their
+// configured module levels.
+type LevelMapper interface {
+	// LevelMap returns a copy of the configured module levels. The default
+	// level is stored under the empty module.
+	LevelMap() map[string]Level
+}
+
 type PrinterLeveledBackend interface {
 	BackendPrinter
 	Leveled
 }
 
 type moduleLeveled struct {
+	mu        sync.RWMutex
 	levels    map[string]Level
 	backend   Backend
 	formatter Formatter
@@ -81,12 +199,13 @@ type moduleLeveledPrinter struct {
 	moduleLeveled
 }
 
-func (this moduleLeveledPrinter) Print(args ...interface{}) (err error) {
+func (this *moduleLeveledPrinter) Print(args ...interface{}) (err error) {
 	return this.backend.(Printer).Print(args...)
 }
 
 // AddModuleLevel wraps a log backend with knobs to have different log levels
-// for different modules.
+// for different modules. The levels are guarded by a mutex, so they can be
+// changed, eg. from an admin endpoint, while other goroutines log.
 func AddModuleLevel(backend Backend) LeveledBackend {
 	var leveled LeveledBackend
 	var ok bool
@@ -107,22 +226,48 @@ func AddModuleLevel(backend Backend) LeveledBackend {
 	return leveled
 }
 
-// GetLevel returns the log level for the given module.
+// GetLevel returns the log level for the given module. If the module has no
+// level, the level of its longest parent module is used, where the modules are
+// separated by '/' or '.', eg. "app/http" is the parent of "app/http/router".
+// Then the default level, set for the empty module, is used.
 func (l *moduleLeveled) GetLevel(module string) Level {
-	level, exists := l.levels[module]
-	if exists == false {
-		level, exists = l.levels[""]
-		// no configuration exists, default to debug
-		if exists == false {
-			level = DEBUG
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for module != "" {
+		if level, exists := l.levels[module]; exists {
+			return level
 		}
+		i := strings.LastIndexAny(module, "/.")
+		if i < 0 {
+			break
+		}
+		module = module[:i]
+	}
+	level, exists := l.levels[""]
+	// no configuration exists, default to debug
+	if exists == false {
+		level = DEBUG
 	}
 	return level
 }
 
 // SetLevel sets the log level for the given module.
 func (l *moduleLeveled) SetLevel(level Level, module string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.levels[module] = level
+	invalidateLevels()
+}
+
+// LevelMap implements the LevelMapper interface.
+func (l *moduleLeveled) LevelMap() map[string]Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	levels := make(map[string]Level, len(l.levels))
+	for module, level := range l.levels {
+		levels[module] = level
+	}
+	return levels
 }
 
 // IsEnabledFor will return true if logging is enabled for the given module.
@@ -139,10 +284,24 @@ func (l *moduleLeveled) Log(level Level, calldepth int, rec *Record) (err error)
 	return
 }
 
+// PrintLevel implements the LevelPrinter interface.
+func (l *moduleLeveled) PrintLevel(level Level, args ...interface{}) error {
+	return l.Log(level, 1, PrintRecord(level, args...))
+}
+
+// Flush implements the Flusher interface.
+func (l *moduleLeveled) Flush() error {
+	return FlushBackend(l.backend)
+}
+
+func (l *moduleLeveled) wrappedBackends() []Backend {
+	return []Backend{l.backend}
+}
+
 func (l *moduleLeveled) getFormatterAndCacheCurrent() Formatter {
 	l.once.Do(func() {
 		if l.formatter == nil {
-			l.formatter = getFormatter()
+			l.formatter = GetFormatter()
 		}
 	})
 	return l.formatter
@@ -0,0 +1,81 @@
+package logging
+
+import "sync/atomic"
+
+// levelsVersion is incremented whenever a module level or the default backend
+// changes, invalidating the levels cached by the loggers. It starts at 1, so
+// the zero levelCache is invalid.
+var levelsVersion uint32 = 1
+
+// invalidateLevels invalidates the levels cached by all loggers.
+func invalidateLevels() {
+	atomic.AddUint32(&levelsVersion, 1)
+}
+
+// levelCache caches the level of the module of a Log, so checking whether a
+// level is enabled is a single atomic load instead of the map lookups of the
+// backend. The state holds the version of the levels in the high 32 bits and
+// the level in the low ones.
+type levelCache struct {
+	state uint64
+}
+
+// get returns the cached level and true, or false if the levels changed since
+// it was cached.
+func (c *levelCache) get() (Level, bool) {
+	state := atomic.LoadUint64(&c.state)
+	if uint32(state>>32) != atomic.LoadUint32(&levelsVersion) {
+		return 0, false
+	}
+	return Level(int32(uint32(state))), true
+}
+
+// load returns the level of module in backend, caching it. The version is
+// loaded before the level, so a concurrent change isn't hidden by the cache.
+func (c *levelCache) load(backend Leveled, module string) Level {
+	version := atomic.LoadUint32(&levelsVersion)
+	level := backend.GetLevel(module)
+	atomic.StoreUint64(&c.state, uint64(version)<<32|uint64(uint32(int32(level))))
+	return level
+}
+
+// levelCacher is implemented by the leveled backends which invalidate the
+// cached levels when their levels change, so their levels can be cached.
+type levelCacher interface {
+	cachesLevels() bool
+}
+
+// cachesLevels returns true if the levels of backend can be cached.
+func cachesLevels(backend Leveled) bool {
+	c, ok := backend.(levelCacher)
+	return ok && c.cachesLevels()
+}
+
+func (l *moduleLeveled) cachesLevels() bool {
+	return true
+}
+
+func (nullBackend) cachesLevels() bool {
+	return true
+}
+
+func (b *multiLogger) cachesLevels() bool {
+	for _, backend := range b.leveled() {
+		if !cachesLevels(backend) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *routingBackend) cachesLevels() bool {
+	if !cachesLevels(b.def) {
+		return false
+	}
+	for _, backend := range b.routes {
+		if !cachesLevels(backend) {
+			return false
+		}
+	}
+	return true
+}
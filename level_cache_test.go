@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestLevelCache(t *testing.T) {
+	InitForTesting(INFO)
+	log := NewLogger("cache")
+	if log.IsEnabledFor(DEBUG) || !log.IsEnabledFor(INFO) {
+		t.Fatalf("unexpected levels")
+	}
+	if level, ok := log.levels.get(); !ok || level != INFO {
+		t.Errorf("level not cached: %s %v", level, ok)
+	}
+
+	SetLevel(DEBUG, "cache")
+	if !log.IsEnabledFor(DEBUG) {
+		t.Errorf("cached level not invalidated by SetLevel")
+	}
+
+	SetBackend(NewNullBackend())
+	if log.IsEnabledFor(CRITICAL) {
+		t.Errorf("cached level not invalidated by SetBackend")
+	}
+
+	leveled := AddModuleLevel(NewMemoryBackend(8))
+	leveled.SetLevel(ERROR, "")
+	log.SetBackend(leveled)
+	if log.IsEnabledFor(WARNING) || !log.IsEnabledFor(ERROR) {
+		t.Errorf("cached level not invalidated by Log.SetBackend")
+	}
+
+	proxied := leveled
+	log.SetBackend(NewLeveledBackendProxy(func() LeveledBackend { return proxied }))
+	log.IsEnabledFor(ERROR)
+	if _, ok := log.levels.get(); ok {
+		t.Errorf("level of proxy cached")
+	}
+	proxied = NewNullBackend()
+	if log.IsEnabledFor(ERROR) {
+		t.Errorf("proxied backend not used")
+	}
+}
+
+func BenchmarkLogDisabledDebug(b *testing.B) {
+	backend := SetBackend(NewLogBackend(ioutil.Discard, "", 0))
+	backend.SetLevel(INFO, "")
+	log := NewLogger("bench/disabled/debug")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		log.Debug("disabled")
+	}
+}
+
+// BenchmarkLogDisabledDebugUncached is BenchmarkLogDisabledDebug with a
+// backend whose levels can't be cached, so each call looks up the level.
+func BenchmarkLogDisabledDebugUncached(b *testing.B) {
+	backend := SetBackend(NewLogBackend(ioutil.Discard, "", 0))
+	backend.SetLevel(INFO, "")
+	log := NewLogger("bench/disabled/debug")
+	log.SetBackend(NewLeveledBackendProxy(GetBackend))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		log.Debug("disabled")
+	}
+}
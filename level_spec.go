@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ParseLevelSpec parses a comma separated list of module:level pairs, eg.
+// "*:INFO,github.com/foo/bar:DEBUG", into a map of module levels. The "*"
+// module is returned as the empty module, which is the default level of the
+// backends.
+func ParseLevelSpec(spec string) (levels map[string]Level, err error) {
+	levels = make(map[string]Level)
+	for _, token := range strings.Split(spec, ",") {
+		if token = strings.TrimSpace(token); token == "" {
+			continue
+		}
+		i := strings.LastIndexByte(token, ':')
+		if i <= 0 {
+			return nil, fmt.Errorf("logger: invalid level spec %q: expected module:level", token)
+		}
+		module, name := strings.TrimSpace(token[:i]), strings.TrimSpace(token[i+1:])
+		level, err := ParseLevel(name)
+		if err != nil {
+			return nil, fmt.Errorf("logger: invalid level spec %q: unknown level %q", token, name)
+		}
+		if module == "*" {
+			module = ""
+		}
+		levels[module] = level
+	}
+	return
+}
+
+// ConfigureFromEnv sets the module levels of the default backend from the
+// level spec in the envVar environment variable. See ParseLevelSpec for the
+// format. An unset or empty variable changes nothing.
+func ConfigureFromEnv(envVar string) error {
+	spec := os.Getenv(envVar)
+	if spec == "" {
+		return nil
+	}
+	levels, err := ParseLevelSpec(spec)
+	if err != nil {
+		return fmt.Errorf("%s (from $%s)", err, envVar)
+	}
+	for module, level := range levels {
+		SetLevel(level, module)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseLevelSpec(t *testing.T) {
+	levels, err := ParseLevelSpec(" *:INFO, github.com/foo/bar:debug,,baz:ERROR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]Level{"": INFO, "github.com/foo/bar": DEBUG, "baz": ERROR}
+	if len(levels) != len(expected) {
+		t.Errorf("unexpected levels: %v", levels)
+	}
+	for module, level := range expected {
+		if levels[module] != level {
+			t.Errorf("unexpected level of %q: %s", module, levels[module])
+		}
+	}
+
+	for _, spec := range []string{"INFO", ":INFO", "foo:LOUD", "foo:"} {
+		if _, err := ParseLevelSpec(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestConfigureFromEnv(t *testing.T) {
+	InitForTesting(DEBUG)
+	os.Setenv("TEST_LOG_LEVELS", "*:WARNING,foo:DEBUG")
+	defer os.Unsetenv("TEST_LOG_LEVELS")
+
+	if err := ConfigureFromEnv("TEST_LOG_LEVELS"); err != nil {
+		t.Fatal(err)
+	}
+	if level := GetLevel("bar"); level != WARNING {
+		t.Errorf("unexpected default level: %s", level)
+	}
+	if level := GetLevel("foo"); level != DEBUG {
+		t.Errorf("unexpected foo level: %s", level)
+	}
+
+	os.Setenv("TEST_LOG_LEVELS", "foo=DEBUG")
+	if err := ConfigureFromEnv("TEST_LOG_LEVELS"); err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -4,7 +4,13 @@
 
 package logging
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
 
 func TestLevelString(t *testing.T) {
 	// Make sure all levels can be converted from string -> constant -> string
@@ -74,3 +80,263 @@ func TestLevelModuleLevel(t *testing.T) {
 		}
 	}
 }
+
+func TestLevelModuleLevelPrefix(t *testing.T) {
+	leveled := AddModuleLevel(NewMemoryBackend(128))
+	if level := leveled.GetLevel("app/http"); level != DEBUG {
+		t.Errorf("unexpected level without configuration: %s", level)
+	}
+
+	leveled.SetLevel(NOTICE, "")
+	leveled.SetLevel(ERROR, "app/http")
+	leveled.SetLevel(INFO, "app/http/router/debug")
+	leveled.SetLevel(WARNING, "server.db")
+
+	expected := []struct {
+		level  Level
+		module string
+	}{
+		{NOTICE, ""},
+		{NOTICE, "app"},
+		{NOTICE, "app/httpd"},
+		{ERROR, "app/http"},
+		{ERROR, "app/http/router"},
+		{INFO, "app/http/router/debug"},
+		{INFO, "app/http/router/debug/x"},
+		{WARNING, "server.db.pool"},
+	}
+	for _, e := range expected {
+		if actual := leveled.GetLevel(e.module); e.level != actual {
+			t.Errorf("unexpected level in %s: %s != %s", e.module, e.level, actual)
+		}
+	}
+	if leveled.IsEnabledFor(WARNING, "app/http/router") {
+		t.Errorf("WARNING enabled for app/http/router")
+	}
+}
+
+func TestLevelOff(t *testing.T) {
+	backend := NewMemoryBackend(8)
+	leveled := AddModuleLevel(backend)
+	leveled.SetLevel(OFF, "noisy")
+
+	for level := CRITICAL; level <= DEBUG; level++ {
+		if leveled.IsEnabledFor(level, "noisy.sub") {
+			t.Errorf("%s enabled for noisy.sub", level)
+		}
+	}
+	if !leveled.IsEnabledFor(CRITICAL, "other") {
+		t.Errorf("CRITICAL disabled for other")
+	}
+
+	log := NewLogger("noisy")
+	log.SetBackend(leveled)
+	log.Critical("muted")
+	if lines := memoryRecords(backend); len(lines) != 0 {
+		t.Errorf("unexpected records: %q", lines)
+	}
+
+	text, err := OFF.MarshalText()
+	if err != nil || string(text) != "OFF" || OFF.String() != "OFF" {
+		t.Errorf("unexpected text of OFF: %s %v", text, err)
+	}
+	levels, err := ParseLevelSpec("*:INFO,noisy:none")
+	if err != nil || levels["noisy"] != OFF {
+		t.Errorf("unexpected levels: %v %v", levels, err)
+	}
+	if multi := MultiLogger(leveled); multi.GetLevel("noisy") != OFF {
+		t.Errorf("unexpected multi logger level: %s", multi.GetLevel("noisy"))
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		expected Level
+		level    string
+	}{
+		{CRITICAL, "critical"},
+		{ERROR, "E"},
+		{WARNING, "w"},
+		{NOTICE, "Notice"},
+		{INFO, "i"},
+		{DEBUG, "DEBUG"},
+		{OFF, "off"},
+		{OFF, "NONE"},
+		{NOTICE, "n"},
+	}
+	for _, test := range tests {
+		level, err := ParseLevel(test.level)
+		if err != nil {
+			t.Errorf("failed to parse %s: %s", test.level, err)
+		} else if test.expected != level {
+			t.Errorf("failed to parse %s: %s != %s", test.level, test.expected, level)
+		}
+	}
+	for _, level := range []string{"", "X", "bla"} {
+		if _, err := ParseLevel(level); err != ErrInvalidLogLevel {
+			t.Errorf("expected ErrInvalidLogLevel for %q, got %v", level, err)
+		}
+	}
+}
+
+func TestParseLevelAliases(t *testing.T) {
+	tests := []struct {
+		alias    string
+		expected Level
+	}{
+		{"warn", WARNING},
+		{"WARN", WARNING},
+		{"err", ERROR},
+		{"Err", ERROR},
+		{"fatal", CRITICAL},
+		{"FATAL", CRITICAL},
+		{"crit", CRITICAL},
+		{"CRIT", CRITICAL},
+		{"trace", DEBUG},
+		{"Trace", DEBUG},
+	}
+	for _, test := range tests {
+		if level, err := ParseLevel(test.alias); err != nil || level != test.expected {
+			t.Errorf("ParseLevel(%q) = %s, %v, expected %s", test.alias, level, err, test.expected)
+		}
+		if level, err := LogLevel(test.alias); err != nil || level != test.expected {
+			t.Errorf("LogLevel(%q) = %s, %v, expected %s", test.alias, level, err, test.expected)
+		}
+	}
+	for _, level := range []string{"warnings", "errr", "verbose"} {
+		if _, err := ParseLevel(level); err != ErrInvalidLogLevel {
+			t.Errorf("expected ErrInvalidLogLevel for %q, got %v", level, err)
+		}
+		if _, err := LogLevel(level); err != ErrInvalidLogLevel {
+			t.Errorf("expected ErrInvalidLogLevel for %q, got %v", level, err)
+		}
+	}
+}
+
+func TestLevelText(t *testing.T) {
+	for _, name := range levelNames {
+		level, _ := ParseLevel(name)
+		text, err := level.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var level2 Level
+		if err = level2.UnmarshalText(text); err != nil {
+			t.Fatal(err)
+		}
+		if level2 != level || string(text) != name {
+			t.Errorf("invalid text conversion: %s != %s", text, name)
+		}
+	}
+	if _, err := Level(42).MarshalText(); err == nil {
+		t.Errorf("expected error for invalid level")
+	}
+	if Level(42).String() != "Level(42)" {
+		t.Errorf("unexpected string of invalid level: %s", Level(42))
+	}
+}
+
+func TestLevelJSON(t *testing.T) {
+	var config struct {
+		Level Level
+	}
+	data, err := json.Marshal(struct{ Level Level }{NOTICE})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"Level":"NOTICE"}` {
+		t.Errorf("unexpected json: %s", data)
+	}
+	for data, expected := range map[string]Level{
+		`{"Level":"warning"}`: WARNING,
+		`{"Level":"D"}`:       DEBUG,
+		`{"Level":1}`:         ERROR,
+	} {
+		if err = json.Unmarshal([]byte(data), &config); err != nil {
+			t.Errorf("failed to unmarshal %s: %s", data, err)
+		} else if config.Level != expected {
+			t.Errorf("unexpected level of %s: %s", data, config.Level)
+		}
+	}
+	for _, data := range []string{`{"Level":"LOUD"}`, `{"Level":9}`, `{"Level":1.5}`, `{"Level":true}`} {
+		if err = json.Unmarshal([]byte(data), &config); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
+
+func TestLevelMap(t *testing.T) {
+	InitForTesting(DEBUG)
+	SetLevel(ERROR, "foo")
+	SetLevel(INFO, "bar")
+
+	levels := LevelMap()
+	if len(levels) != 3 || levels[""] != DEBUG || levels["foo"] != ERROR || levels["bar"] != INFO {
+		t.Errorf("unexpected levels: %v", levels)
+	}
+	levels["foo"] = DEBUG
+	if GetLevel("foo") != ERROR {
+		t.Errorf("level map isn't a copy")
+	}
+}
+
+func TestLevelPrintLevel(t *testing.T) {
+	InitForTesting(DEBUG)
+	defer SetFormatter(DefaultFormatter)
+	SetFormatter(MustStringFormatter("%{level} %{message}"))
+
+	buf := &bytes.Buffer{}
+	leveled := AddModuleLevel(NewLogBackend(buf, "", 0))
+	leveled.SetLevel(WARNING, "")
+
+	p := leveled.(LevelPrinter)
+	if err := p.PrintLevel(INFO, "dropped"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PrintLevel(ERROR, "printed", 1); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "ERROR printed 1\n" {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+// TestLevelConcurrentSetLevel must be run with -race.
+func TestLevelConcurrentSetLevel(t *testing.T) {
+	leveled := AddModuleLevel(NewLogBackend(ioutil.Discard, "", 0))
+	log := NewLogger("race.module")
+	log.SetBackend(leveled)
+
+	var (
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				log.Info("message")
+				leveled.GetLevel("race")
+				leveled.(LevelMapper).LevelMap()
+			}
+		}()
+	}
+
+	levels := []Level{DEBUG, ERROR, INFO, WARNING}
+	for i := 0; i < 1000; i++ {
+		leveled.SetLevel(levels[i%len(levels)], "race")
+		leveled.SetLevel(levels[(i+1)%len(levels)], "race.module")
+	}
+	close(done)
+	wg.Wait()
+
+	if level := leveled.GetLevel("race.module"); level != levels[1000%len(levels)] {
+		t.Errorf("unexpected level: %s", level)
+	}
+}
@@ -49,7 +49,7 @@ func testCallpath(t *testing.T, format string, expect string) {
 	SetBackend(NewLogBackend(buf, "", log.Lshortfile))
 	SetFormatter(MustStringFormatter(format))
 
-	logger := GetOrCreateLogger("test")
+	logger := GetOrCreateLogger("test").(*Log)
 	rec(logger, 6)
 
 	parts := strings.SplitN(buf.String(), " ", 3)
@@ -110,6 +110,14 @@ func BenchmarkLogLogBackend(b *testing.B) {
 	RunLogBenchmark(b)
 }
 
+func BenchmarkLogLogBackendPooled(b *testing.B) {
+	SetRecordPooling(true)
+	defer SetRecordPooling(false)
+	backend := SetBackend(NewLogBackend(ioutil.Discard, "", 0))
+	backend.SetLevel(DEBUG, "")
+	RunLogBenchmark(b)
+}
+
 func BenchmarkLogLogBackendColor(b *testing.B) {
 	colorizer := NewLogBackend(ioutil.Discard, "", 0)
 	colorizer.Color = true
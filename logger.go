@@ -10,11 +10,16 @@ package logging
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -34,11 +39,15 @@ var (
 	// Sequence number is incremented and utilized for all log records created.
 	sequenceNo uint64
 
-	// timeNow is a customizable for testing purposes.
-	timeNow = time.Now
+	// timeNowFunc stores the func() time.Time returning the time of the
+	// records. It's customizable for testing purposes, see SetTimeNow.
+	timeNowFunc atomic.Value
 
 	// loggers stores Log objects by module name
 	loggers SyncedLoggers
+
+	// DefaultOutput is the writer of the default backend created by Reset.
+	DefaultOutput io.Writer = os.Stderr
 )
 
 // SyncedLoggers represents a parallel by module Logger registrator
@@ -57,16 +66,53 @@ func (this *SyncedLoggers) Get(module string) Logger {
 	return this.loggers[module]
 }
 
+// Names returns the sorted module names of the registered loggers.
+func (this *SyncedLoggers) Names() (names []string) {
+	this.mu.RLock()
+	names = make([]string, 0, len(this.loggers))
+	for module := range this.loggers {
+		names = append(names, module)
+	}
+	this.mu.RUnlock()
+	sort.Strings(names)
+	return
+}
+
 // GetOrCreate returns a Logger object is has be registered, other wise, creates and registry new object
 func (this *SyncedLoggers) GetOrCreate(module string) (log Logger) {
+	return this.getOrCreate(module, func() Logger {
+		return NewLogger(module)
+	})
+}
+
+// Remove unregisters the Logger of module. The backend of the logger isn't
+// closed, as it's usually shared with other loggers.
+func (this *SyncedLoggers) Remove(module string) {
+	this.mu.Lock()
+	delete(this.loggers, module)
+	this.mu.Unlock()
+}
+
+// Reset unregisters all loggers. Their backends aren't closed.
+func (this *SyncedLoggers) Reset() {
+	this.mu.Lock()
+	this.loggers = nil
+	this.mu.Unlock()
+}
+
+// getOrCreate returns the Logger registered for module, or registers the one
+// returned by create.
+func (this *SyncedLoggers) getOrCreate(module string, create func() Logger) (log Logger) {
 	if log = this.Get(module); log == nil {
 		this.mu.Lock()
 		defer this.mu.Unlock()
 		if this.loggers == nil {
 			this.loggers = map[string]Logger{}
 		}
-		log = NewLogger(module)
-		this.loggers[module] = log
+		if log = this.loggers[module]; log == nil {
+			log = create()
+			this.loggers[module] = log
+		}
 	}
 	return
 }
@@ -77,11 +123,17 @@ var MustGetLogger = GetOrCreateLogger
 // was created, an increasing id, filename and line and finally the actual
 // formatted log line.
 type RecordData struct {
-	ID      uint64
-	Time    time.Time
-	Module  string
-	Level   Level
-	Message string
+	ID       uint64
+	IDString string `json:",omitempty"`
+	Time     time.Time
+	Module   string
+	Level    Level
+	Message  string
+	Fields   Fields `json:",omitempty"`
+	// File, Line and Func are the caller, set only by DataWithCaller.
+	File string `json:",omitempty"`
+	Line int    `json:",omitempty"`
+	Func string `json:",omitempty"`
 }
 
 // Record represents a log record and contains the timestamp when the record
@@ -93,6 +145,13 @@ type Record struct {
 	Module string
 	Level  Level
 	Args   []interface{}
+	Fields Fields
+	// Prefix is the prefix added by WithPrefix, eg. "a -> b ->". Message
+	// returns it before the message.
+	Prefix string
+	// IDString is the ID returned by the generator of SetIDStringGenerator,
+	// if set.
+	IDString string
 
 	// message is kept as a pointer to have shallow copies update this once
 	// needed.
@@ -100,25 +159,54 @@ type Record struct {
 	fmt       *string
 	formatter Formatter
 	formatted string
+	// formattedBy is the formatter of the formatted cache.
+	formattedBy Formatter
+	goroutine   uint64
+	pc          uintptr
+	caller      *runtime.Frame
+	pooled      bool
+	retained    int32
+	// sync is set for the records of a SyncLogger.
+	sync bool
 }
 
-// Formatted returns the formatted log record string.
+// Formatted returns the formatted log record string. The result is cached
+// until the record formatter changes, so the backends with other formatters
+// don't get the output of the first one.
 func (r *Record) Formatted(calldepth int) string {
-	if r.formatted == "" {
+	if r.formatted == "" || !sameFormatter(r.formattedBy, r.formatter) {
 		var buf bytes.Buffer
 		r.formatter.Format(calldepth+1, r, &buf)
-		r.formatted = buf.String()
+		r.formatted, r.formattedBy = buf.String(), r.formatter
 	}
 	return r.formatted
 }
 
-// Message returns the log record message.
+// sameFormatter reports whether a and b are the same formatter. Formatters of
+// not comparable types are never the same.
+func sameFormatter(a, b Formatter) bool {
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && (t == nil || t.Comparable() && a == b)
+}
+
+// Message returns the log record message, after the Prefix if set.
 func (r *Record) Message() string {
+	if r.Prefix != "" {
+		return r.Prefix + " " + r.text()
+	}
+	return r.text()
+}
+
+// text returns the log record message without the Prefix.
+func (r *Record) text() string {
 	if r.message == nil {
 		// Redact the arguments that implements the Redactor interface
+		deep := atomic.LoadInt32(&deepRedaction) != 0
 		for i, arg := range r.Args {
 			if redactor, ok := arg.(Redactor); ok == true {
 				r.Args[i] = redactor.Redacted()
+			} else if deep {
+				r.Args[i] = RedactDeep(arg)
 			}
 		}
 		var buf bytes.Buffer
@@ -139,11 +227,48 @@ func (r *Record) Message() string {
 func (r *Record) Data() RecordData {
 	return RecordData{
 		r.ID,
+		r.IDString,
 		r.Time,
 		r.Module,
 		r.Level,
 		r.Message(),
+		r.Fields,
+		"",
+		0,
+		"",
+	}
+}
+
+// DataWithCaller returns the RecordData object with the caller, see Caller.
+// Resolving the caller has a cost, so Data doesn't do it.
+func (r *Record) DataWithCaller() RecordData {
+	data := r.Data()
+	if file, line, fn, ok := r.Caller(); ok {
+		data.File, data.Line, data.Func = file, line, fn
+	}
+	return data
+}
+
+// Err returns the error added by Logger.WithError.
+func (r *Record) Err() (err error, ok bool) {
+	var v interface{}
+	if v, ok = r.Fields.Get(ErrorKey); ok {
+		err, ok = v.(error)
+	}
+	return
+}
+
+// Clone returns a copy of the record which doesn't share the Args and Fields
+// with r. It should be used when the record is kept after the Log call
+// returns, eg. on async backends.
+func (r *Record) Clone() *Record {
+	r2 := *r
+	r2.pooled, r2.retained = false, 0
+	if r.Args != nil {
+		r2.Args = append(make([]interface{}, 0, len(r.Args)), r.Args...)
 	}
+	r2.Fields = r.Fields.Copy()
+	return &r2
 }
 
 // Log is the actual logger which creates log records based on the functions
@@ -153,19 +278,57 @@ type Log struct {
 	Module      string
 	backend     LeveledBackend
 	haveBackend bool
+	// levels caches the level of the module, it's shared with the children
+	// of the same backend.
+	levels *levelCache
 }
 
-// NewLogger crates new Log object with module name
+// NewLogger crates new Log object with module name. The logger isn't
+// registered in the global loggers, so it's released once unused, unlike the
+// loggers of GetOrCreateLogger and Named, which stay registered. Use it for
+// short lived loggers, eg. one for each request, specially if the module
+// names have high cardinality.
 func NewLogger(module string) *Log {
-	l := &Log{Module: module}
+	l := &Log{Module: module, levels: &levelCache{}}
 	l.writer = DefaultWriter(l, l.Module)
 	return l
 }
 
-// SetBackend overrides any previously defined backend for this logger.
+// SetBackend overrides any previously defined backend for this logger. The
+// backend must be leveled, eg. wrapped with AddModuleLevel, see UseBackend to
+// set a plain Backend. A nil backend, or a nil pointer, restores the default
+// backend.
 func (l *Log) SetBackend(backend LeveledBackend) {
+	if isNilBackend(backend) {
+		backend = nil
+	}
 	l.backend = backend
-	l.haveBackend = true
+	l.haveBackend = backend != nil
+	l.levels = &levelCache{}
+}
+
+// UseBackend is like SetBackend, but accepts a plain Backend, eg. the one of
+// NewLogBackend, which is wrapped with AddModuleLevel unless it's already
+// leveled. The levels of the wrapper are independent of the default backend
+// ones, so the logger logs all levels until they're set on l.Backend():
+//
+//	log.UseBackend(logging.NewLogBackend(os.Stdout, "", 0))
+//	log.Backend().SetLevel(logging.INFO, "")
+func (l *Log) UseBackend(backend Backend) {
+	if isNilBackend(backend) {
+		l.SetBackend(nil)
+		return
+	}
+	l.SetBackend(AddModuleLevel(backend))
+}
+
+// isNilBackend reports whether backend is nil, or a nil pointer.
+func isNilBackend(backend Backend) bool {
+	if backend == nil {
+		return true
+	}
+	v := reflect.ValueOf(backend)
+	return v.Kind() == reflect.Ptr && v.IsNil()
 }
 
 // Backend return current backend if has be defined
@@ -173,36 +336,260 @@ func (l *Log) Backend() LeveledBackend {
 	return l.backend
 }
 
-// IsEnabledFor returns true if the logger is enabled for the given level.
+// WithFields returns a child logger which adds the key/value pairs as
+// structured fields into all records.
+func (l *Log) WithFields(keyvals ...interface{}) Logger {
+	child := *l
+	child.writer = WithFieldsWriter(l.writer, NewFields(keyvals...))
+	return &child
+}
+
+// withPrefix implements the prefixLogger interface. The prefix is passed as
+// a field to the writer, which moves it into Record.Prefix.
+func (l *Log) withPrefix(prefix string) (Logger, bool) {
+	if _, ok := l.writer.(FieldsLogWriter); !ok {
+		return nil, false
+	}
+	child := *l
+	child.writer = WithFieldsWriter(l.writer, Fields{{Value: prefixValue(prefix)}})
+	return &child, true
+}
+
+// WithError returns a child logger which adds err as the ErrorKey field into
+// all records. If err is nil, the logger itself is returned.
+func (l *Log) WithError(err error) Logger {
+	if err == nil {
+		return l
+	}
+	return l.WithFields(ErrorKey, err)
+}
+
+// Named returns the child logger of the module Module + "." + suffix,
+// registering it in the global loggers like GetOrCreateLogger. A new child
+// inherits the backend, clock and error handler of the parent, but not its
+// fields.
+func (l *Log) Named(suffix string) Logger {
+	module := suffix
+	if l.Module != "" {
+		module = l.Module + "." + suffix
+	}
+	return loggers.getOrCreate(module, func() Logger {
+		child := NewLogger(module)
+		child.backend, child.haveBackend = l.backend, l.haveBackend
+		child.Calldepth, child.ExtraCalldepth = l.Calldepth, l.ExtraCalldepth
+		child.Clock = l.Clock
+		child.ErrorHandler = l.ErrorHandler
+		return child
+	})
+}
+
+// WithModule returns an unregistered child logger which logs the records as
+// module, eg. for a shared helper logging on behalf of the module of its
+// caller. The levels are checked for module. The child shares the backend,
+// clock, error handler and fields of l.
+func (l *Log) WithModule(module string) Logger {
+	child := *l
+	child.Module = module
+	child.levels = &levelCache{}
+	child.writer = rebaseWriter(l.writer, DefaultWriter(&child, module))
+	return &child
+}
+
+// Clone returns an unregistered logger of the same module, which inherits the
+// backend, clock and error handler of l, but not its fields. Setting the
+// backend of the clone doesn't change l.
+func (l *Log) Clone() Logger {
+	child := NewLogger(l.Module)
+	child.backend, child.haveBackend = l.backend, l.haveBackend
+	child.Calldepth, child.ExtraCalldepth = l.Calldepth, l.ExtraCalldepth
+	child.Clock = l.Clock
+	child.ErrorHandler = l.ErrorHandler
+	return child
+}
+
+// Flush flushes the backend of the logger, or the default backend, if it
+// implements Flusher. Call it before os.Exit, so the records kept by the
+// buffered and async backends aren't lost:
+//
+//	if err != nil {
+//		log.Error(err)
+//		log.Flush()
+//		os.Exit(1)
+//	}
+func (l *Log) Flush() error {
+	if l.backend != nil {
+		return FlushBackend(l.backend)
+	}
+	return FlushBackend(getDefaultBackend())
+}
+
+// withCalldepth returns a copy of the logger which skips n more frames when
+// getting the calling function.
+func (l *Log) withCalldepth(n int) Logger {
+	child := *l
+	child.ExtraCalldepth += n
+	return &child
+}
+
+// IsEnabledFor returns true if the logger is enabled for the given level. The
+// level of the module is cached until some level or the default backend
+// changes, if the backend supports it, like the backends of AddModuleLevel.
 func (l *Log) IsEnabledFor(level Level) bool {
-	return defaultBackend.IsEnabledFor(level, l.Module)
+	if _, ok := innerWriter(l.writer).(multiWriter); ok {
+		// the loggers of Tee are enabled if some of their loggers is
+		return l.Basic.IsEnabledFor(level)
+	}
+	if l.levels != nil {
+		if max, ok := l.levels.get(); ok {
+			return level <= max
+		}
+	}
+	backend := l.backend
+	if backend == nil {
+		backend = getDefaultBackend()
+	}
+	if l.levels != nil && cachesLevels(backend) {
+		return level <= l.levels.load(backend, l.Module)
+	}
+	return backend.IsEnabledFor(level, l.Module)
 }
 
-// GetOrCreateLogger returns a Logger object is has be registered in Loggers, other wise, creates and registry new object
+// GetOrCreateLogger returns a Logger object is has be registered in Loggers, other wise, creates and registry new object.
+// The registered loggers are kept until RemoveLogger or ResetLoggers, see
+// NewLogger for the unregistered ones.
 func GetOrCreateLogger(module string) Logger {
 	return loggers.GetOrCreate(module)
 }
 
+// Loggers returns the sorted module names of the loggers created by
+// GetOrCreateLogger and Named.
+func Loggers() []string {
+	return loggers.Names()
+}
+
 // GetLogger returns a Logger object based on the module name registered in Loggers.
 func GetLogger(module string) Logger {
 	return loggers.Get(module)
 }
 
+// RemoveLogger unregisters the Logger of module, so GetOrCreateLogger creates
+// a new one. Use it to release the loggers of high cardinality modules, eg.
+// one for each tenant. The backend of the logger isn't closed.
+func RemoveLogger(module string) {
+	loggers.Remove(module)
+}
+
+// ResetLoggers unregisters all loggers. Their backends aren't closed.
+func ResetLoggers() {
+	loggers.Reset()
+}
+
 // MainLogger returns a Logger object based on the sys.Argv[0].
 func MainLogger() Logger {
 	return GetOrCreateLogger(filepath.Base(os.Args[0]))
 }
 
-// Reset restores the internal state of the logging library.
-func Reset() {
+// timeNow returns the time of a new record.
+func timeNow() time.Time {
+	if f, ok := timeNowFunc.Load().(func() time.Time); ok {
+		return f()
+	}
+	return time.Now()
+}
+
+// SetTimeNow sets the function returning the time of the new records, so the
+// tests can freeze the time. Nil restores time.Now. The loggers with a Clock
+// use it instead.
+func SetTimeNow(f func() time.Time) {
+	if f == nil {
+		f = time.Now
+	}
+	timeNowFunc.Store(f)
+}
+
+// SetSequence sets the ID of the last record, so the next record has the ID
+// n+1. It's meant for the tests asserting the record IDs.
+func SetSequence(n uint64) {
+	atomic.StoreUint64(&sequenceNo, n)
+}
+
+// ResetOptions configures Reset.
+type ResetOptions struct {
+	// Sequence is the ID of the last record, see SetSequence.
+	Sequence uint64
+	// TimeNow returns the time of the records, see SetTimeNow. Defaults to
+	// time.Now.
+	TimeNow func() time.Time
+	// Output is the writer of the default backend. Defaults to
+	// DefaultOutput.
+	Output io.Writer
+}
+
+// Reset restores the internal state of the logging library. The sequence and
+// the time of the records, and the output of the default backend can be set
+// by options. The %{reltime} verb is relative to the time of the last Reset.
+func Reset(options ...ResetOptions) {
 	// TODO make a global Init() method to be less magic? or make it such that
 	// if there's no backends at all configured, we could use some tricks to
 	// automatically setup backends based if we have a TTY or not.
-	sequenceNo = 0
-	b := SetBackend(NewLogBackend(os.Stderr, "", log.LstdFlags))
-	b.SetLevel(DEBUG, "")
+	var opts ResetOptions
+	for _, opts = range options {
+	}
+	SetSequence(opts.Sequence)
+	SetIDGenerator(nil)
+	SetIDStringGenerator(nil)
+	SetDefaultLogger(nil)
+	ClearGlobalFields()
+	if opts.Output == nil {
+		opts.Output = DefaultOutput
+	}
+	setOutput(opts.Output)
 	SetFormatter(DefaultFormatter)
-	timeNow = time.Now
+	SetTimeNow(opts.TimeNow)
+	startTime.Store(timeNow())
+}
+
+// SaveGlobals returns a function which restores the global state changed by
+// Reset and InitForTesting: the default backend, with its levels, the default
+// formatter and logger, the global fields, the ID generators, the sequence
+// number and the time of the records. It's meant for the test helpers, like
+// logtest.Capture.
+func SaveGlobals() (restore func()) {
+	values := map[*atomic.Value]interface{}{}
+	for _, v := range []*atomic.Value{&defaultBackend, &defaultLogger, &globalFields,
+		&idGenerator, &idStringGenerator, &timeNowFunc, &startTime} {
+		values[v] = v.Load()
+	}
+	f := GetFormatter()
+	sequence := atomic.LoadUint64(&sequenceNo)
+	return func() {
+		globalFieldsMu.Lock()
+		defer globalFieldsMu.Unlock()
+		for v, value := range values {
+			if value != nil {
+				v.Store(value)
+			}
+		}
+		SetFormatter(f)
+		SetSequence(sequence)
+		invalidateLevels()
+	}
+}
+
+// setOutput sets the default backend writing into w, with all modules at the
+// DEBUG level. The levels are colored if ColorEnabled(w).
+func setOutput(w io.Writer) {
+	backend := NewLogBackend(w, "", log.LstdFlags)
+	backend.Color = ColorEnabled(w)
+	SetBackend(backend).SetLevel(DEBUG, "")
+}
+
+// UseStdout sets the DefaultOutput to os.Stdout, as preferred by the
+// containerized applications, and replaces the default backend by one writing
+// into it. Like Reset, the levels of the modules are restored to DEBUG.
+func UseStdout() {
+	DefaultOutput = os.Stdout
+	setOutput(os.Stdout)
 }
 
 func init() {
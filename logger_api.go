@@ -37,11 +37,39 @@ type Logger interface {
 	Debugf(format string, args ...interface{})
 	// Writer returns the log writer.
 	Writer() LogWriter
+	// WithFields returns a child logger which adds the key/value pairs as
+	// structured fields into all records.
+	WithFields(keyvals ...interface{}) Logger
+	// WithError returns a child logger which adds err as the "error" field
+	// into all records. If err is nil, the logger itself is returned.
+	WithError(err error) Logger
+	// WithModule returns an unregistered child logger which logs the records
+	// as module, checking the levels of module.
+	WithModule(module string) Logger
+	// Named returns the registered child logger of the module
+	// parent + "." + suffix.
+	Named(suffix string) Logger
+	// Clone returns an unregistered copy of the logger, with the same module
+	// and backend, but without the fields. Setting the backend of the clone
+	// doesn't change the logger.
+	Clone() Logger
+	// Flush flushes the backend if it implements Flusher, otherwise it
+	// returns nil. Call it before os.Exit, which doesn't run the deferred
+	// functions, so the buffered and queued records aren't lost.
+	Flush() error
 }
 
 // LogPrefixer is an interface for types that creates log records with prefix.
 type LogPrefixer interface {
 	Logger
 	Prefix() string
+	// SetPrefix replaces the prefix of the records logged afterwards.
+	SetPrefix(prefix string)
 	Parent() Logger
+	// Parents returns the parent loggers, from the nearest to the first one
+	// which isn't a LogPrefixer.
+	Parents() []Logger
+	// FullPrefix returns the prefixes of the parents and the prefix of the
+	// logger joined, as added before the messages.
+	FullPrefix() string
 }
@@ -3,14 +3,48 @@ package logging
 import (
 	"fmt"
 	"os"
+	"time"
 )
 
+// Clock provides the time of the log records.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc is a function which implements the Clock interface.
+type ClockFunc func() time.Time
+
+// Now returns the current time.
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// BasicCalldepth is the default base call depth of Basic: the frames of the
+// level method, eg. Info, and of Basic.write, between the caller and the
+// LogWriter.
+const BasicCalldepth = 2
+
 type Basic struct {
 	writer LogWriter
 
+	// Calldepth is the base call depth passed to the LogWriter, the number of
+	// frames between the caller of the logger and the writer. Zero means
+	// BasicCalldepth. The writer receives Calldepth + ExtraCalldepth, so a
+	// type which embeds Basic and calls its level methods from its own ones
+	// sets Calldepth to BasicCalldepth + 1.
+	Calldepth int
+
 	// ExtraCallDepth can be used to add additional call depth when getting the
 	// calling function. This is normally used when wrapping a logger.
 	ExtraCalldepth int
+
+	// Clock is used to get the time of the records. If nil, the package clock
+	// is used.
+	Clock Clock
+
+	// ErrorHandler is called with the errors returned by the backend. If nil,
+	// the default error handler is used.
+	ErrorHandler ErrorHandler
 }
 
 // NewBasic creates Basic with writer
@@ -18,31 +52,99 @@ func NewBasic(writer LogWriter) Basic {
 	return Basic{writer: writer}
 }
 
+// IsEnabledFor returns true if the writer writes the records of level, eg.
+// the writer of a Log checks the level of its module. The writers which
+// don't know their levels, like the ones of NewWriter, are always enabled.
+func (l Basic) IsEnabledFor(level Level) bool {
+	return writerEnabledFor(l.writer, level)
+}
+
+// now returns the current time of the Clock.
+func (l Basic) now() time.Time {
+	if l.Clock != nil {
+		return l.Clock.Now()
+	}
+	return timeNow()
+}
+
+// handleError calls the ErrorHandler, or the default one, with err.
+func (l Basic) handleError(err error) {
+	if l.ErrorHandler != nil {
+		l.ErrorHandler(err)
+		return
+	}
+	HandleError(err)
+}
+
+// calldepth returns the call depth passed to the writer.
+func (l Basic) calldepth() int {
+	if l.Calldepth == 0 {
+		return BasicCalldepth + l.ExtraCalldepth
+	}
+	return l.Calldepth + l.ExtraCalldepth
+}
+
 func (l Basic) write(lvl Level, format *string, args ...interface{}) {
-	l.writer.Write(lvl, 2+l.ExtraCalldepth, format, args...)
+	l.writer.Write(lvl, l.calldepth(), format, args...)
+}
+
+// FatalFlushTimeout is the maximum time Fatal and Panic wait for the flush of
+// the backend before exiting or panicking.
+var FatalFlushTimeout = 5 * time.Second
+
+// fatalWait is the time given by Fatal and Panic to the async backends which
+// can't be flushed.
+const fatalWait = 100 * time.Millisecond
+
+// flush flushes the backends of the writer, waiting up to FatalFlushTimeout,
+// so the records queued by async backends aren't lost. If an async backend
+// can't be flushed, it also waits fatalWait.
+func (l Basic) flush() {
+	if asyncUnflushedWriter(l.writer) {
+		defer time.Sleep(fatalWait)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := flushWriter(l.writer); err != nil {
+			l.handleError(err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(FatalFlushTimeout):
+	}
 }
 
 // Fatal is equivalent to l.Critical(fmt.Sprint()) followed by a call to os.Exit(1).
+// The backend is flushed before exiting.
 func (l Basic) Fatal(args ...interface{}) {
 	l.write(CRITICAL, nil, args...)
+	l.flush()
 	os.Exit(1)
 }
 
-// Fatalf is equivalent to l.Critical followed by a call to os.Exit(1).
+// Fatalf is equivalent to l.Critical followed by a call to os.Exit(1). The
+// backend is flushed before exiting.
 func (l Basic) Fatalf(format string, args ...interface{}) {
 	l.write(CRITICAL, &format, args...)
+	l.flush()
 	os.Exit(1)
 }
 
 // Panic is equivalent to l.Critical(fmt.Sprint()) followed by a call to panic().
+// The backend is flushed before panicking.
 func (l Basic) Panic(args ...interface{}) {
 	l.write(CRITICAL, nil, args...)
+	l.flush()
 	panic(fmt.Sprint(args...))
 }
 
-// Panicf is equivalent to l.Critical followed by a call to panic().
+// Panicf is equivalent to l.Critical followed by a call to panic(). The
+// backend is flushed before panicking.
 func (l Basic) Panicf(format string, args ...interface{}) {
 	l.write(CRITICAL, &format, args...)
+	l.flush()
 	panic(fmt.Sprintf(format, args...))
 }
 
@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestIsEnabledFor(t *testing.T) {
+	InitForTesting(DEBUG)
+	SetLevel(WARNING, "a")
+	SetLevel(ERROR, "b")
+
+	a, b := GetOrCreateLogger("a"), GetOrCreateLogger("b")
+	tests := []struct {
+		name   string
+		logger interface{ IsEnabledFor(Level) bool }
+		level  Level
+		want   bool
+	}{
+		{"basic", NewBasic(a.Writer()), WARNING, true},
+		{"basic", NewBasic(a.Writer()), INFO, false},
+		{"basic writer func", NewBasic(NewWriter(func(Level, int, *string, ...interface{}) {})), DEBUG, true},
+		{"prefix", WithPrefix(b, "p"), ERROR, true},
+		{"prefix", WithPrefix(b, "p"), WARNING, false},
+		{"tee", Tee(a, b), WARNING, true},
+		{"tee", Tee(a, b), NOTICE, false},
+		{"tee fields", Tee(a, b).WithFields("k", "v"), WARNING, true},
+		{"tee fields", Tee(a, b).WithFields("k", "v"), NOTICE, false},
+	}
+	for _, test := range tests {
+		if got := test.logger.IsEnabledFor(test.level); got != test.want {
+			t.Errorf("%s: IsEnabledFor(%s) = %v, expected %v", test.name, test.level, got, test.want)
+		}
+	}
+}
+
+// wrappedBasic logs through the level methods of the embedded Basic.
+type wrappedBasic struct {
+	Basic
+}
+
+func (w wrappedBasic) Info(args ...interface{}) {
+	w.Basic.Info(args...)
+}
+
+func TestBasicCalldepth(t *testing.T) {
+	InitForTesting(DEBUG)
+	buf := &bytes.Buffer{}
+	SetBackend(NewLogBackend(buf, "", 0))
+	SetFormatter(MustStringFormatter("%{shortfile}"))
+	log := NewLogger("test")
+	w := wrappedBasic{NewBasic(DefaultWriter(log, "test"))}
+	w.Calldepth = BasicCalldepth + 1
+
+	_, _, line, _ := runtime.Caller(0)
+	w.Info("wrapped")
+	w.ExtraCalldepth = 1
+	func() {
+		w.Info("helper")
+	}()
+
+	expected := "logger_basic_test.go:" + strconv.Itoa(line+1) + "\n" +
+		"logger_basic_test.go:" + strconv.Itoa(line+5) + "\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected callers %q, expected %q", buf.String(), expected)
+	}
+}
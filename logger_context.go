@@ -0,0 +1,139 @@
+package logging
+
+import (
+	"context"
+	"sync"
+)
+
+type loggerContextKey struct{}
+
+// ContextExtractor returns key/value pairs from ctx which are added as
+// structured fields into records logged by the *Context methods.
+type ContextExtractor func(ctx context.Context) []interface{}
+
+var contextExtractors struct {
+	sync.RWMutex
+	list []ContextExtractor
+}
+
+// RegisterContextExtractor registers a ContextExtractor used by all *Context
+// logging methods.
+func RegisterContextExtractor(extractor ContextExtractor) {
+	contextExtractors.Lock()
+	defer contextExtractors.Unlock()
+	contextExtractors.list = append(contextExtractors.list, extractor)
+}
+
+// ContextFields returns the fields extracted from ctx by the registered
+// context extractors.
+func ContextFields(ctx context.Context) (fields Fields) {
+	if ctx == nil {
+		return
+	}
+	contextExtractors.RLock()
+	defer contextExtractors.RUnlock()
+	for _, extractor := range contextExtractors.list {
+		if keyvals := extractor(ctx); len(keyvals) > 0 {
+			fields = append(fields, NewFields(keyvals...)...)
+		}
+	}
+	return
+}
+
+// NewContext returns a copy of ctx which carries the logger.
+func NewContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx by NewContext. If ctx doesn't
+// have a logger, returns the MainLogger.
+func FromContext(ctx context.Context) Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerContextKey{}).(Logger); ok {
+			return logger
+		}
+	}
+	return MainLogger()
+}
+
+func (l Basic) writeContext(ctx context.Context, lvl Level, format *string, args ...interface{}) {
+	if fields := ContextFields(ctx); len(fields) > 0 {
+		if fw, ok := l.writer.(FieldsLogWriter); ok {
+			fw.WriteFields(lvl, l.calldepth(), fields, format, args...)
+			return
+		}
+	}
+	l.writer.Write(lvl, l.calldepth(), format, args...)
+}
+
+// CriticalContext logs a message using CRITICAL as log level, adding the
+// fields extracted from ctx.
+func (l Basic) CriticalContext(ctx context.Context, args ...interface{}) {
+	l.writeContext(ctx, CRITICAL, nil, args...)
+}
+
+// CriticalfContext logs a message using CRITICAL as log level, adding the
+// fields extracted from ctx.
+func (l Basic) CriticalfContext(ctx context.Context, format string, args ...interface{}) {
+	l.writeContext(ctx, CRITICAL, &format, args...)
+}
+
+// ErrorContext logs a message using ERROR as log level, adding the fields
+// extracted from ctx.
+func (l Basic) ErrorContext(ctx context.Context, args ...interface{}) {
+	l.writeContext(ctx, ERROR, nil, args...)
+}
+
+// ErrorfContext logs a message using ERROR as log level, adding the fields
+// extracted from ctx.
+func (l Basic) ErrorfContext(ctx context.Context, format string, args ...interface{}) {
+	l.writeContext(ctx, ERROR, &format, args...)
+}
+
+// WarningContext logs a message using WARNING as log level, adding the fields
+// extracted from ctx.
+func (l Basic) WarningContext(ctx context.Context, args ...interface{}) {
+	l.writeContext(ctx, WARNING, nil, args...)
+}
+
+// WarningfContext logs a message using WARNING as log level, adding the
+// fields extracted from ctx.
+func (l Basic) WarningfContext(ctx context.Context, format string, args ...interface{}) {
+	l.writeContext(ctx, WARNING, &format, args...)
+}
+
+// NoticeContext logs a message using NOTICE as log level, adding the fields
+// extracted from ctx.
+func (l Basic) NoticeContext(ctx context.Context, args ...interface{}) {
+	l.writeContext(ctx, NOTICE, nil, args...)
+}
+
+// NoticefContext logs a message using NOTICE as log level, adding the fields
+// extracted from ctx.
+func (l Basic) NoticefContext(ctx context.Context, format string, args ...interface{}) {
+	l.writeContext(ctx, NOTICE, &format, args...)
+}
+
+// InfoContext logs a message using INFO as log level, adding the fields
+// extracted from ctx.
+func (l Basic) InfoContext(ctx context.Context, args ...interface{}) {
+	l.writeContext(ctx, INFO, nil, args...)
+}
+
+// InfofContext logs a message using INFO as log level, adding the fields
+// extracted from ctx.
+func (l Basic) InfofContext(ctx context.Context, format string, args ...interface{}) {
+	l.writeContext(ctx, INFO, &format, args...)
+}
+
+// DebugContext logs a message using DEBUG as log level, adding the fields
+// extracted from ctx.
+func (l Basic) DebugContext(ctx context.Context, args ...interface{}) {
+	l.writeContext(ctx, DEBUG, nil, args...)
+}
+
+// DebugfContext logs a message using DEBUG as log level, adding the fields
+// extracted from ctx.
+func (l Basic) DebugfContext(ctx context.Context, format string, args ...interface{}) {
+	l.writeContext(ctx, DEBUG, &format, args...)
+}
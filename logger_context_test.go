@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"context"
+	"testing"
+)
+
+type requestIDKey struct{}
+
+func TestContextLogging(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	SetFormatter(MustStringFormatter("%{message} %{fields}"))
+
+	defer func(list []ContextExtractor) {
+		contextExtractors.list = list
+	}(contextExtractors.list)
+	RegisterContextExtractor(func(ctx context.Context) []interface{} {
+		if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+			return []interface{}{"request_id", id}
+		}
+		return nil
+	})
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "abc")
+	log := GetOrCreateLogger("test").(*Log)
+	log.InfoContext(ctx, "done")
+	log.ErrorfContext(context.Background(), "%d", 1)
+
+	for i, e := range []string{"done request_id=abc", "1 "} {
+		if line := MemoryRecordN(backend, i).Formatted(0); e != line {
+			t.Errorf("%d: %q != %q", i, line, e)
+		}
+	}
+}
+
+func TestLoggerContext(t *testing.T) {
+	log := GetOrCreateLogger("test")
+	ctx := NewContext(context.Background(), log)
+	if FromContext(ctx) != log {
+		t.Errorf("logger not found in context")
+	}
+	if FromContext(context.Background()) != MainLogger() {
+		t.Errorf("expected main logger")
+	}
+}
@@ -0,0 +1,125 @@
+package logging
+
+import "sync"
+
+// onceKeys stores the keys already logged by the *Once methods.
+var onceKeys sync.Map
+
+// once reports whether key is logged for the first time.
+func once(key string) bool {
+	if _, ok := onceKeys.Load(key); ok {
+		return false
+	}
+	_, loaded := onceKeys.LoadOrStore(key, struct{}{})
+	return !loaded
+}
+
+// once reports whether the record of key is logged for the first time at
+// level. The key isn't consumed while level is disabled.
+func (l Basic) once(level Level, key string) bool {
+	return l.IsEnabledFor(level) && once(key)
+}
+
+// ResetOnce forgets the keys logged by the *Once methods.
+func ResetOnce() {
+	onceKeys.Range(func(key, _ interface{}) bool {
+		onceKeys.Delete(key)
+		return true
+	})
+}
+
+// CriticalOnce logs a message using CRITICAL as log level only the first time
+// it's called with key.
+func (l Basic) CriticalOnce(key string, args ...interface{}) {
+	if l.once(CRITICAL, key) {
+		l.write(CRITICAL, nil, args...)
+	}
+}
+
+// CriticalfOnce logs a message using CRITICAL as log level only the first time
+// it's called with key.
+func (l Basic) CriticalfOnce(key string, format string, args ...interface{}) {
+	if l.once(CRITICAL, key) {
+		l.write(CRITICAL, &format, args...)
+	}
+}
+
+// ErrorOnce logs a message using ERROR as log level only the first time it's
+// called with key.
+func (l Basic) ErrorOnce(key string, args ...interface{}) {
+	if l.once(ERROR, key) {
+		l.write(ERROR, nil, args...)
+	}
+}
+
+// ErrorfOnce logs a message using ERROR as log level only the first time it's
+// called with key.
+func (l Basic) ErrorfOnce(key string, format string, args ...interface{}) {
+	if l.once(ERROR, key) {
+		l.write(ERROR, &format, args...)
+	}
+}
+
+// WarningOnce logs a message using WARNING as log level only the first time
+// it's called with key.
+func (l Basic) WarningOnce(key string, args ...interface{}) {
+	if l.once(WARNING, key) {
+		l.write(WARNING, nil, args...)
+	}
+}
+
+// WarningfOnce logs a message using WARNING as log level only the first time
+// it's called with key.
+func (l Basic) WarningfOnce(key string, format string, args ...interface{}) {
+	if l.once(WARNING, key) {
+		l.write(WARNING, &format, args...)
+	}
+}
+
+// NoticeOnce logs a message using NOTICE as log level only the first time it's
+// called with key.
+func (l Basic) NoticeOnce(key string, args ...interface{}) {
+	if l.once(NOTICE, key) {
+		l.write(NOTICE, nil, args...)
+	}
+}
+
+// NoticefOnce logs a message using NOTICE as log level only the first time
+// it's called with key.
+func (l Basic) NoticefOnce(key string, format string, args ...interface{}) {
+	if l.once(NOTICE, key) {
+		l.write(NOTICE, &format, args...)
+	}
+}
+
+// InfoOnce logs a message using INFO as log level only the first time it's
+// called with key.
+func (l Basic) InfoOnce(key string, args ...interface{}) {
+	if l.once(INFO, key) {
+		l.write(INFO, nil, args...)
+	}
+}
+
+// InfofOnce logs a message using INFO as log level only the first time it's
+// called with key.
+func (l Basic) InfofOnce(key string, format string, args ...interface{}) {
+	if l.once(INFO, key) {
+		l.write(INFO, &format, args...)
+	}
+}
+
+// DebugOnce logs a message using DEBUG as log level only the first time it's
+// called with key.
+func (l Basic) DebugOnce(key string, args ...interface{}) {
+	if l.once(DEBUG, key) {
+		l.write(DEBUG, nil, args...)
+	}
+}
+
+// DebugfOnce logs a message using DEBUG as log level only the first time it's
+// called with key.
+func (l Basic) DebugfOnce(key string, format string, args ...interface{}) {
+	if l.once(DEBUG, key) {
+		l.write(DEBUG, &format, args...)
+	}
+}
@@ -0,0 +1,55 @@
+package logging
+
+import "testing"
+
+func TestLogOnce(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	ResetOnce()
+
+	log := GetOrCreateLogger("test").(*Log)
+	for i := 0; i < 3; i++ {
+		log.WarningOnce("init", "first", i)
+		log.ErrorfOnce("other", "second %d", i)
+	}
+
+	records := backend.Records()
+	if len(records) != 2 {
+		t.Fatalf("unexpected records: %d", len(records))
+	}
+	if records[0].Formatted(0) != "first 0" || records[1].Formatted(0) != "second 0" {
+		t.Errorf("unexpected records: %q %q", records[0].Formatted(0), records[1].Formatted(0))
+	}
+}
+
+func TestLogOnceSuppressedAllocs(t *testing.T) {
+	InitForTesting(DEBUG)
+	ResetOnce()
+
+	log := GetOrCreateLogger("test").(*Log)
+	key := "allocs"
+	log.InfoOnce(key)
+	allocs := testing.AllocsPerRun(100, func() {
+		log.InfoOnce(key)
+	})
+	if allocs != 0 {
+		t.Errorf("suppressed path allocates: %v", allocs)
+	}
+}
+
+func TestLogOnceDisabledLevel(t *testing.T) {
+	backend := InitForTesting(INFO)
+	ResetOnce()
+
+	log := GetOrCreateLogger("test").(*Log)
+	log.DebugOnce("debug", "hidden")
+	if records := backend.Records(); len(records) != 0 {
+		t.Fatalf("unexpected records: %d", len(records))
+	}
+
+	SetLevel(DEBUG, "")
+	log.DebugOnce("debug", "shown")
+	records := backend.Records()
+	if len(records) != 1 || records[0].Formatted(0) != "shown" {
+		t.Fatalf("the key was consumed while DEBUG was disabled: %d records", len(records))
+	}
+}
@@ -0,0 +1,112 @@
+package logging
+
+import "sync/atomic"
+
+// defaultLogger stores the loggerBox set by SetDefaultLogger.
+var defaultLogger atomic.Value
+
+// loggerBox keeps the stored loggers of the same type, as required by
+// atomic.Value.
+type loggerBox struct {
+	logger Logger
+}
+
+// SetDefaultLogger sets the logger used by the package level functions, like
+// Info and Errorf. Nil restores the default, MainLogger.
+func SetDefaultLogger(logger Logger) {
+	defaultLogger.Store(loggerBox{logger})
+}
+
+// DefaultLogger returns the logger used by the package level functions.
+func DefaultLogger() Logger {
+	if box, ok := defaultLogger.Load().(loggerBox); ok && box.logger != nil {
+		return box.logger
+	}
+	return MainLogger()
+}
+
+// packageLogger returns the DefaultLogger skipping the frame of the package
+// level function, so the caller is the function calling it.
+func packageLogger() Logger {
+	return addCalldepth(DefaultLogger(), 1)
+}
+
+// Fatal logs using the DefaultLogger, see Logger.Fatal.
+func Fatal(args ...interface{}) {
+	packageLogger().Fatal(args...)
+}
+
+// Fatalf logs using the DefaultLogger, see Logger.Fatalf.
+func Fatalf(format string, args ...interface{}) {
+	packageLogger().Fatalf(format, args...)
+}
+
+// Panic logs using the DefaultLogger, see Logger.Panic.
+func Panic(args ...interface{}) {
+	packageLogger().Panic(args...)
+}
+
+// Panicf logs using the DefaultLogger, see Logger.Panicf.
+func Panicf(format string, args ...interface{}) {
+	packageLogger().Panicf(format, args...)
+}
+
+// Critical logs a message using CRITICAL as log level and the DefaultLogger.
+func Critical(args ...interface{}) {
+	packageLogger().Critical(args...)
+}
+
+// Criticalf logs a message using CRITICAL as log level and the DefaultLogger.
+func Criticalf(format string, args ...interface{}) {
+	packageLogger().Criticalf(format, args...)
+}
+
+// Error logs a message using ERROR as log level and the DefaultLogger.
+func Error(args ...interface{}) {
+	packageLogger().Error(args...)
+}
+
+// Errorf logs a message using ERROR as log level and the DefaultLogger.
+func Errorf(format string, args ...interface{}) {
+	packageLogger().Errorf(format, args...)
+}
+
+// Warning logs a message using WARNING as log level and the DefaultLogger.
+func Warning(args ...interface{}) {
+	packageLogger().Warning(args...)
+}
+
+// Warningf logs a message using WARNING as log level and the DefaultLogger.
+func Warningf(format string, args ...interface{}) {
+	packageLogger().Warningf(format, args...)
+}
+
+// Notice logs a message using NOTICE as log level and the DefaultLogger.
+func Notice(args ...interface{}) {
+	packageLogger().Notice(args...)
+}
+
+// Noticef logs a message using NOTICE as log level and the DefaultLogger.
+func Noticef(format string, args ...interface{}) {
+	packageLogger().Noticef(format, args...)
+}
+
+// Info logs a message using INFO as log level and the DefaultLogger.
+func Info(args ...interface{}) {
+	packageLogger().Info(args...)
+}
+
+// Infof logs a message using INFO as log level and the DefaultLogger.
+func Infof(format string, args ...interface{}) {
+	packageLogger().Infof(format, args...)
+}
+
+// Debug logs a message using DEBUG as log level and the DefaultLogger.
+func Debug(args ...interface{}) {
+	packageLogger().Debug(args...)
+}
+
+// Debugf logs a message using DEBUG as log level and the DefaultLogger.
+func Debugf(format string, args ...interface{}) {
+	packageLogger().Debugf(format, args...)
+}
@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestPackageFunctions(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	defer Reset()
+
+	_, _, line, _ := runtime.Caller(0)
+	Infof("hello %s", "world")
+	rec := MemoryRecordN(backend, 0)
+	if rec.Message() != "hello world" || rec.Level != INFO || rec.Module != filepath.Base(os.Args[0]) {
+		t.Errorf("unexpected record: %s %s %s", rec.Level, rec.Module, rec.Message())
+	}
+	if file, l, _, _ := rec.Caller(); filepath.Base(file) != "logger_package_test.go" || l != line+1 {
+		t.Errorf("unexpected caller %s:%d", file, l)
+	}
+
+	log := NewLogger("pkg")
+	log.ExtraCalldepth = 1
+	SetDefaultLogger(log)
+	wrapped := func() {
+		Error("wrapped")
+	}
+	wrapped()
+	rec = MemoryRecordN(backend, 1)
+	if rec.Module != "pkg" || rec.Level != ERROR {
+		t.Errorf("unexpected record: %s %s", rec.Level, rec.Module)
+	}
+	if _, l, _, _ := rec.Caller(); l != line+16 {
+		t.Errorf("unexpected caller line %d, expected %d", l, line+16)
+	}
+
+	SetDefaultLogger(nil)
+	if DefaultLogger() != MainLogger() {
+		t.Errorf("default logger not restored")
+	}
+}
@@ -2,91 +2,273 @@ package logging
 
 import "strings"
 
+// LogPrefix is a Logger which prefixes the messages of all records. If the
+// parent logger supports it, like the loggers created by NewLogger, the prefix
+// is kept in Record.Prefix, so the text formatters add it before the message
+// and the structured ones, like the JSONFormatter, output it as a separate
+// field. Otherwise it's concatenated into the message.
 type LogPrefix struct {
 	Logger
 	prefix string
+	// log is Logger skipping the frame of the LogPrefix methods, so the
+	// caller is reported instead of them.
+	log Logger
+	// base is log without the prefix.
+	base Logger
+	// structured reports whether log adds the prefix into the records.
+	structured bool
 }
 
-func (this LogPrefix) Parent() Logger {
+// calldepthLogger is implemented by the loggers which can skip extra frames
+// when getting the calling function.
+type calldepthLogger interface {
+	withCalldepth(n int) Logger
+}
+
+// addCalldepth returns a copy of logger which skips n more frames, or logger
+// itself if it doesn't support it.
+func addCalldepth(logger Logger, n int) Logger {
+	if l, ok := logger.(calldepthLogger); ok {
+		return l.withCalldepth(n)
+	}
+	return logger
+}
+
+// prefixLogger is implemented by the loggers which can add a prefix into the
+// records.
+type prefixLogger interface {
+	withPrefix(prefix string) (Logger, bool)
+}
+
+// newLogPrefix creates the LogPrefix of parent, whose methods skip calldepth
+// frames.
+func newLogPrefix(parent Logger, prefix string, calldepth int) *LogPrefix {
+	p := &LogPrefix{Logger: parent}
+	p.base = addCalldepth(parent, calldepth)
+	p.setPrefix(prefix)
+	return p
+}
+
+// setPrefix sets the prefix and builds the logger which adds it.
+func (this *LogPrefix) setPrefix(prefix string) {
+	this.prefix, this.log, this.structured = prefix, this.base, false
+	if l, ok := this.base.(prefixLogger); ok {
+		this.log, this.structured = l.withPrefix(prefix)
+		if !this.structured {
+			this.log = this.base
+		}
+	}
+}
+
+// withPrefix implements the prefixLogger interface, so the prefixes of
+// nested LogPrefix are added into the same record.
+func (this *LogPrefix) withPrefix(prefix string) (Logger, bool) {
+	if !this.structured {
+		return nil, false
+	}
+	l, ok := this.log.(prefixLogger)
+	if !ok {
+		return nil, false
+	}
+	log, ok := l.withPrefix(prefix)
+	if !ok {
+		return nil, false
+	}
+	return &LogPrefix{this.Logger, this.prefix, log, this.base, true}, true
+}
+
+// args returns args with the prefix, unless it's added into the records.
+func (this *LogPrefix) args(args []interface{}) []interface{} {
+	if this.structured {
+		return args
+	}
+	return append([]interface{}{this.prefix}, args...)
+}
+
+// format returns format with the prefix, unless it's added into the records.
+func (this *LogPrefix) format(format string) string {
+	if this.structured {
+		return format
+	}
+	return this.prefix + " " + format
+}
+
+func (this *LogPrefix) logger() Logger {
+	if this.log != nil {
+		return this.log
+	}
+	return this.Logger
+}
+
+// unprefixed returns the logger which doesn't add the prefix into the records.
+func (this *LogPrefix) unprefixed() Logger {
+	if this.base != nil {
+		return this.base
+	}
 	return this.Logger
 }
 
-func (this LogPrefix) Prefix() string {
+func (this *LogPrefix) withCalldepth(n int) Logger {
+	return &LogPrefix{this.Logger, this.prefix, addCalldepth(this.logger(), n), addCalldepth(this.base, n), this.structured}
+}
+
+func (this *LogPrefix) Parent() Logger {
+	return this.Logger
+}
+
+func (this *LogPrefix) Prefix() string {
 	return this.prefix
 }
 
-func (this LogPrefix) SetPrefix(v string) {
-	this.prefix = v
+// Parents returns the parent loggers, from the nearest to the first one which
+// isn't a LogPrefixer.
+func (this *LogPrefix) Parents() (parents []Logger) {
+	for l := this.Logger; l != nil; {
+		parents = append(parents, l)
+		p, ok := l.(LogPrefixer)
+		if !ok {
+			break
+		}
+		l = p.Parent()
+	}
+	return
+}
+
+// FullPrefix returns the prefixes of the parents and the prefix of the
+// logger, from the outermost, joined by spaces. It's the prefix added before
+// the messages, eg. "a -> b ->" for WithPrefix(WithPrefix(l, "a"), "b").
+func (this *LogPrefix) FullPrefix() string {
+	prefixes := []string{this.prefix}
+	for _, l := range this.Parents() {
+		if p, ok := l.(LogPrefixer); ok {
+			prefixes = append([]string{p.Prefix()}, prefixes...)
+		}
+	}
+	return strings.Join(prefixes, " ")
+}
+
+// SetPrefix replaces the prefix of the logger. It must not be called while
+// other goroutines use the logger.
+func (this *LogPrefix) SetPrefix(v string) {
+	if this.base == nil {
+		this.base = addCalldepth(this.Logger, 1)
+	}
+	this.setPrefix(v)
+}
+
+// IsEnabledFor returns true if the parent logger is enabled for level.
+func (this *LogPrefix) IsEnabledFor(level Level) bool {
+	return this.logger().IsEnabledFor(level)
 }
 
-func (this LogPrefix) Fatal(args ...interface{}) {
-	this.Logger.Fatal(append([]interface{}{this.prefix}, args...)...)
+func (this *LogPrefix) Fatal(args ...interface{}) {
+	this.logger().Fatal(this.args(args)...)
 }
 
-func (this LogPrefix) Fatalf(format string, args ...interface{}) {
-	this.Logger.Fatalf(this.prefix+" "+format, args...)
+func (this *LogPrefix) Fatalf(format string, args ...interface{}) {
+	this.logger().Fatalf(this.format(format), args...)
 }
 
-func (this LogPrefix) Panic(args ...interface{}) {
-	this.Logger.Panic(append([]interface{}{this.prefix}, args...)...)
+// Panic logs the message with the prefix concatenated, so the panic value also
+// has it.
+func (this *LogPrefix) Panic(args ...interface{}) {
+	this.unprefixed().Panic(append([]interface{}{this.prefix}, args...)...)
 }
 
-func (this LogPrefix) Panicf(format string, args ...interface{}) {
-	this.Logger.Panicf(this.prefix+" "+format, args...)
+// Panicf logs the message with the prefix concatenated, so the panic value
+// also has it.
+func (this *LogPrefix) Panicf(format string, args ...interface{}) {
+	this.unprefixed().Panicf(this.prefix+" "+format, args...)
 }
 
-func (this LogPrefix) Critical(args ...interface{}) {
-	this.Logger.Critical(append([]interface{}{this.prefix}, args...)...)
+func (this *LogPrefix) Critical(args ...interface{}) {
+	this.logger().Critical(this.args(args)...)
 }
 
-func (this LogPrefix) Criticalf(format string, args ...interface{}) {
-	this.Logger.Criticalf(this.prefix+" "+format, args...)
+func (this *LogPrefix) Criticalf(format string, args ...interface{}) {
+	this.logger().Criticalf(this.format(format), args...)
 }
 
-func (this LogPrefix) Error(args ...interface{}) {
-	this.Logger.Error(append([]interface{}{this.prefix}, args...)...)
+func (this *LogPrefix) Error(args ...interface{}) {
+	this.logger().Error(this.args(args)...)
 }
 
-func (this LogPrefix) Errorf(format string, args ...interface{}) {
-	this.Logger.Errorf(this.prefix+" "+format, args...)
+func (this *LogPrefix) Errorf(format string, args ...interface{}) {
+	this.logger().Errorf(this.format(format), args...)
 }
 
-func (this LogPrefix) Warning(args ...interface{}) {
-	this.Logger.Warning(append([]interface{}{this.prefix}, args...)...)
+func (this *LogPrefix) Warning(args ...interface{}) {
+	this.logger().Warning(this.args(args)...)
 }
 
-func (this LogPrefix) Warningf(format string, args ...interface{}) {
-	this.Logger.Warningf(this.prefix+" "+format, args...)
+func (this *LogPrefix) Warningf(format string, args ...interface{}) {
+	this.logger().Warningf(this.format(format), args...)
 }
 
-func (this LogPrefix) Notice(args ...interface{}) {
-	this.Logger.Notice(append([]interface{}{this.prefix}, args...)...)
+func (this *LogPrefix) Notice(args ...interface{}) {
+	this.logger().Notice(this.args(args)...)
 }
 
-func (this LogPrefix) Noticef(format string, args ...interface{}) {
-	this.Logger.Noticef(this.prefix+" "+format, args...)
+func (this *LogPrefix) Noticef(format string, args ...interface{}) {
+	this.logger().Noticef(this.format(format), args...)
 }
 
-func (this LogPrefix) Info(args ...interface{}) {
-	this.Logger.Info(append([]interface{}{this.prefix}, args...)...)
+func (this *LogPrefix) Info(args ...interface{}) {
+	this.logger().Info(this.args(args)...)
 }
 
-func (this LogPrefix) Infof(format string, args ...interface{}) {
-	this.Logger.Infof(this.prefix+" "+format, args...)
+func (this *LogPrefix) Infof(format string, args ...interface{}) {
+	this.logger().Infof(this.format(format), args...)
+}
+
+func (this *LogPrefix) Debug(args ...interface{}) {
+	this.logger().Debug(this.args(args)...)
+}
+
+func (this *LogPrefix) Debugf(format string, args ...interface{}) {
+	this.logger().Debugf(this.format(format), args...)
+}
+
+func (this *LogPrefix) WithFields(keyvals ...interface{}) Logger {
+	var base Logger
+	if this.base != nil {
+		base = this.base.WithFields(keyvals...)
+	}
+	return &LogPrefix{this.Logger.WithFields(keyvals...), this.prefix, this.logger().WithFields(keyvals...), base, this.structured}
+}
+
+func (this *LogPrefix) WithModule(module string) Logger {
+	var base Logger
+	if this.base != nil {
+		base = this.base.WithModule(module)
+	}
+	return &LogPrefix{this.Logger.WithModule(module), this.prefix, this.logger().WithModule(module), base, this.structured}
+}
+
+func (this *LogPrefix) WithError(err error) Logger {
+	if err == nil {
+		return this
+	}
+	return this.WithFields(ErrorKey, err)
 }
 
-func (this LogPrefix) Debug(args ...interface{}) {
-	this.Logger.Debug(append([]interface{}{this.prefix}, args...)...)
+func (this *LogPrefix) Named(suffix string) Logger {
+	return newLogPrefix(this.Logger.Named(suffix), this.prefix, 1)
 }
 
-func (this LogPrefix) Debugf(format string, args ...interface{}) {
-	this.Logger.Debugf(this.prefix+" "+format, args...)
+func (this *LogPrefix) Clone() Logger {
+	return newLogPrefix(this.Logger.Clone(), this.prefix, 1)
 }
 
+// WithPrefix returns a logger which adds prefix, without the surrounding
+// spaces, followed by the separator before the messages. The separator
+// defaults to " ->". Prefixed parents compose: their prefixes are added
+// first, separated by a space, so WithPrefix(WithPrefix(l, "a", ":"), "b")
+// logs "a: b -> message". See LogPrefix.FullPrefix.
 func WithPrefix(parent Logger, prefix string, sep ...string) LogPrefixer {
 	s := " ->"
 	if len(sep) > 0 {
 		s = sep[0]
 	}
-	return &LogPrefix{parent, strings.TrimSpace(prefix) + s}
+	return newLogPrefix(parent, strings.TrimSpace(prefix)+s, 1)
 }
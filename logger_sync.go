@@ -0,0 +1,101 @@
+package logging
+
+// SyncLogger logs records which are written synchronously, even by the async
+// backends, and returns the error of the backend instead of passing it to the
+// error handler. Use it for the records which must be confirmed, eg. the audit
+// ones:
+//
+//	if err := log.Sync().Critical("user", id, "deleted"); err != nil {
+//		return err
+//	}
+//
+// The async backends write the pending records before the synchronous one, so
+// the order is kept.
+type SyncLogger struct {
+	log *Log
+}
+
+// Sync returns a SyncLogger of l. Only the records of the logger writer are
+// synchronous, so the records of a Tee logger aren't.
+func (l *Log) Sync() *SyncLogger {
+	return &SyncLogger{l}
+}
+
+// IsSync returns true if the record was logged by a SyncLogger, so the async
+// backends must write it before returning.
+func (r *Record) IsSync() bool {
+	return r.sync
+}
+
+func (s *SyncLogger) write(lvl Level, format *string, args ...interface{}) (err error) {
+	child := *s.log
+	child.ErrorHandler = func(e error) {
+		err = e
+	}
+	child.ExtraCalldepth++
+	if w, ok := innerWriter(s.log.writer).(*defaultWriter); ok {
+		child.writer = rebaseWriter(s.log.writer, &defaultWriter{l: &child, module: w.module, sync: true})
+	}
+	child.write(lvl, format, args...)
+	return
+}
+
+// Critical logs a message using CRITICAL as log level.
+func (s *SyncLogger) Critical(args ...interface{}) error {
+	return s.write(CRITICAL, nil, args...)
+}
+
+// Criticalf logs a message using CRITICAL as log level.
+func (s *SyncLogger) Criticalf(format string, args ...interface{}) error {
+	return s.write(CRITICAL, &format, args...)
+}
+
+// Error logs a message using ERROR as log level.
+func (s *SyncLogger) Error(args ...interface{}) error {
+	return s.write(ERROR, nil, args...)
+}
+
+// Errorf logs a message using ERROR as log level.
+func (s *SyncLogger) Errorf(format string, args ...interface{}) error {
+	return s.write(ERROR, &format, args...)
+}
+
+// Warning logs a message using WARNING as log level.
+func (s *SyncLogger) Warning(args ...interface{}) error {
+	return s.write(WARNING, nil, args...)
+}
+
+// Warningf logs a message using WARNING as log level.
+func (s *SyncLogger) Warningf(format string, args ...interface{}) error {
+	return s.write(WARNING, &format, args...)
+}
+
+// Notice logs a message using NOTICE as log level.
+func (s *SyncLogger) Notice(args ...interface{}) error {
+	return s.write(NOTICE, nil, args...)
+}
+
+// Noticef logs a message using NOTICE as log level.
+func (s *SyncLogger) Noticef(format string, args ...interface{}) error {
+	return s.write(NOTICE, &format, args...)
+}
+
+// Info logs a message using INFO as log level.
+func (s *SyncLogger) Info(args ...interface{}) error {
+	return s.write(INFO, nil, args...)
+}
+
+// Infof logs a message using INFO as log level.
+func (s *SyncLogger) Infof(format string, args ...interface{}) error {
+	return s.write(INFO, &format, args...)
+}
+
+// Debug logs a message using DEBUG as log level.
+func (s *SyncLogger) Debug(args ...interface{}) error {
+	return s.write(DEBUG, nil, args...)
+}
+
+// Debugf logs a message using DEBUG as log level.
+func (s *SyncLogger) Debugf(format string, args ...interface{}) error {
+	return s.write(DEBUG, &format, args...)
+}
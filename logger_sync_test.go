@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSyncLogger(t *testing.T) {
+	InitForTesting(DEBUG)
+	memory := NewMemoryBackend(8)
+	slow := &blockingBackend{make(chan struct{}), memory}
+	multi := AsyncMultiLogger(AsyncMultiOptions{}, slow)
+	defer multi.Close()
+	SetBackend(multi)
+
+	log := NewLogger("audit")
+	log.Info("async")
+	done := make(chan error)
+	_, _, line, _ := runtime.Caller(0)
+	go func() { done <- log.Sync().Critical("audit") }()
+	close(slow.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := memoryRecords(memory); len(lines) != 2 || lines[0] != "async" || lines[1] != "audit" {
+		t.Fatalf("unexpected records: %q", lines)
+	}
+	rec := MemoryRecordN(memory, 1)
+	if !rec.IsSync() || MemoryRecordN(memory, 0).IsSync() {
+		t.Errorf("unexpected sync flags")
+	}
+	if file, l, _, _ := rec.Caller(); filepath.Base(file) != "logger_sync_test.go" || l != line+1 {
+		t.Errorf("unexpected caller %s:%d", file, l)
+	}
+}
+
+func TestSyncLoggerError(t *testing.T) {
+	InitForTesting(DEBUG)
+	backendErr := errors.New("disk full")
+	SetBackend(AsyncMultiLogger(AsyncMultiOptions{}, errorBackend{backendErr}))
+
+	var handled []error
+	log := NewLogger("audit")
+	log.ErrorHandler = func(err error) {
+		handled = append(handled, err)
+	}
+	if err := log.Sync().Errorf("%d", 1); err != backendErr {
+		t.Errorf("unexpected error %v", err)
+	}
+	if len(handled) != 0 {
+		t.Errorf("sync error passed to the error handler: %v", handled)
+	}
+	if err := log.WithFields("k", "v").(*Log).Sync().Debug("fields"); err != backendErr {
+		t.Errorf("unexpected error of the fields logger %v", err)
+	}
+	SetLevel(INFO, "audit")
+	if err := log.Sync().Debug("disabled"); err != nil {
+		t.Errorf("unexpected error of a disabled level %v", err)
+	}
+}
+
+// slowBackend takes a while to log each record.
+type slowBackend struct{}
+
+func (slowBackend) Log(level Level, calldepth int, rec *Record) error {
+	time.Sleep(100 * time.Microsecond)
+	return nil
+}
+
+func TestSyncLoggerBusy(t *testing.T) {
+	InitForTesting(DEBUG)
+	multi := AsyncMultiLogger(AsyncMultiOptions{QueueSize: 4}, slowBackend{})
+	defer multi.Close()
+	SetBackend(multi)
+
+	// the queue never gets empty while the other goroutine logs
+	log := NewLogger("audit")
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				log.Info("busy")
+			}
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-stopped
+	}()
+	// lets the other goroutine fill the queue
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() { done <- log.Sync().Critical("audit") }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the sync record waited for the records queued after it")
+	}
+}
@@ -4,7 +4,13 @@
 
 package logging
 
-import "testing"
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
 
 type Password string
 
@@ -52,6 +58,7 @@ func TestPrivateBackend(t *testing.T) {
 	lvlBackend := AddModuleLevel(privateBackend)
 	lvlBackend.SetLevel(DEBUG, "")
 	log.SetBackend(lvlBackend)
+	defer log.SetBackend(nil)
 	log.Debug("to private backend")
 	if stdBackend.size > 0 {
 		t.Errorf("something in stdBackend, size of backend: %d", stdBackend.size)
@@ -60,3 +67,428 @@ func TestPrivateBackend(t *testing.T) {
 		t.Error("logged to defaultBackend:", MemoryRecordN(privateBackend, 0))
 	}
 }
+
+func TestLoggerUseBackend(t *testing.T) {
+	stdBackend := InitForTesting(DEBUG)
+	SetFormatter(MustStringFormatter("%{level} %{message}"))
+	buf := &bytes.Buffer{}
+
+	log := NewLogger("private")
+	log.UseBackend(NewLogBackend(buf, "", 0))
+	log.Debug("private")
+	NewLogger("other").Info("other")
+	if buf.String() != "DEBUG private\n" {
+		t.Errorf("unexpected private output %q", buf.String())
+	}
+	if lines := memoryRecords(stdBackend); len(lines) != 1 || lines[0] != "INFO other" {
+		t.Errorf("unexpected default records %q", lines)
+	}
+
+	log.Backend().SetLevel(INFO, "")
+	log.Debug("dropped")
+	if strings.Contains(buf.String(), "dropped") {
+		t.Errorf("private backend level not respected: %q", buf.String())
+	}
+
+	log.SetBackend((*moduleLeveled)(nil))
+	if log.Backend() != nil {
+		t.Errorf("nil pointer backend set")
+	}
+	log.UseBackend(nil)
+	log.Info("default")
+	if lines := memoryRecords(stdBackend); len(lines) != 2 || lines[1] != "INFO default" {
+		t.Errorf("default backend not restored: %q", lines)
+	}
+}
+
+func TestLoggerClock(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	fixed := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	log := NewLogger("clock")
+	log.Clock = ClockFunc(func() time.Time { return fixed })
+	log.Info("fixed")
+	GetOrCreateLogger("test").Info("global")
+
+	if record := MemoryRecordN(backend, 0); !record.Time.Equal(fixed) {
+		t.Errorf("unexpected time: %v", record.Time)
+	}
+	if record := MemoryRecordN(backend, 1); !record.Time.Equal(time.Unix(0, 0)) {
+		t.Errorf("unexpected time: %v", record.Time)
+	}
+}
+
+func TestLoggerNamed(t *testing.T) {
+	InitForTesting(DEBUG)
+	private := NewMemoryBackend(8)
+	parent := NewLogger("server")
+	parent.SetBackend(AddModuleLevel(private))
+
+	db := parent.Named("db")
+	if GetLogger("server.db") != db {
+		t.Fatalf("named logger not registered")
+	}
+	if parent.Named("db") != db {
+		t.Errorf("named logger created twice")
+	}
+
+	db.Info("query")
+	db.Backend().SetLevel(ERROR, "server.db")
+	if db.IsEnabledFor(INFO) {
+		t.Errorf("module level of named logger not used")
+	}
+	db.Info("ignored")
+	parent.Info("parent")
+
+	if lines := memoryRecords(private); len(lines) != 2 || lines[0] != "query" || lines[1] != "parent" {
+		t.Errorf("unexpected records: %q", lines)
+	}
+	if name := MemoryRecordN(private, 0).Module; name != "server.db" {
+		t.Errorf("unexpected module: %s", name)
+	}
+}
+
+func TestLoggerClone(t *testing.T) {
+	InitForTesting(DEBUG)
+	private := NewMemoryBackend(8)
+	base := NewLogger("clone")
+	base.SetBackend(AddModuleLevel(private))
+
+	clone := base.WithFields("k", "v").Clone()
+	if GetLogger("clone") != nil {
+		t.Fatalf("clone registered")
+	}
+	clone.SetBackend(AddModuleLevel(NewMemoryBackend(8)))
+	if base.Backend() == clone.Backend() {
+		t.Errorf("backend of the clone shared")
+	}
+
+	clone = WithPrefix(base.WithFields("k", "v"), "req").Clone()
+	clone.Info("cloned")
+	if lines := memoryRecords(private); len(lines) != 1 || lines[0] != "req -> cloned" {
+		t.Errorf("unexpected records: %q", lines)
+	}
+	if rec := MemoryRecordN(private, 0); rec.Module != "clone" || len(rec.Fields) != 0 {
+		t.Errorf("unexpected record: %s %v", rec.Module, rec.Fields)
+	}
+}
+
+func TestLoggers(t *testing.T) {
+	GetOrCreateLogger("names.b")
+	GetOrCreateLogger("names.a")
+
+	var names []string
+	for _, name := range Loggers() {
+		if strings.HasPrefix(name, "names.") {
+			names = append(names, name)
+		}
+	}
+	if len(names) != 2 || names[0] != "names.a" || names[1] != "names.b" {
+		t.Errorf("unexpected names: %q", names)
+	}
+}
+
+func TestRemoveLogger(t *testing.T) {
+	l := GetOrCreateLogger("remove")
+	RemoveLogger("remove")
+	if GetLogger("remove") != nil {
+		t.Fatalf("logger not removed")
+	}
+	if GetOrCreateLogger("remove") == l {
+		t.Errorf("removed logger returned")
+	}
+
+	var registry SyncedLoggers
+	registry.GetOrCreate("a")
+	registry.GetOrCreate("b")
+	registry.Remove("a")
+	if names := registry.Names(); len(names) != 1 || names[0] != "b" {
+		t.Errorf("unexpected names: %q", names)
+	}
+	registry.Reset()
+	if names := registry.Names(); len(names) != 0 {
+		t.Errorf("unexpected names: %q", names)
+	}
+	registry.GetOrCreate("c")
+	if registry.Get("c") == nil {
+		t.Errorf("logger not registered after reset")
+	}
+}
+
+func TestResetOptions(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	Reset(ResetOptions{Sequence: 41, TimeNow: func() time.Time { return now }})
+	defer Reset()
+
+	backend := NewMemoryBackend(8)
+	SetBackend(backend)
+	GetOrCreateLogger("test").Info("frozen")
+
+	rec := MemoryRecordN(backend, 0)
+	if rec.ID != 42 || !rec.Time.Equal(now) {
+		t.Errorf("unexpected record id %d and time %s", rec.ID, rec.Time)
+	}
+
+	SetSequence(99)
+	SetTimeNow(nil)
+	GetOrCreateLogger("test").Info("now")
+	if rec = MemoryRecordN(backend, 1); rec.ID != 100 || rec.Time.Equal(now) {
+		t.Errorf("unexpected record id %d and time %s", rec.ID, rec.Time)
+	}
+}
+
+func TestSaveGlobals(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	Reset(ResetOptions{Sequence: 41, TimeNow: func() time.Time { return now }})
+	defer Reset()
+	backend := NewMemoryBackend(8)
+	SetBackend(backend).SetLevel(WARNING, "test")
+	SetFormatter(GlogFormatter)
+	SetGlobalField("env", "test")
+	SetIDStringGenerator(func() string { return "id" })
+	logger := NewLogger("default")
+	SetDefaultLogger(logger)
+
+	restore := SaveGlobals()
+	InitForTesting(DEBUG)
+	SetGlobalField("env", "other")
+	restore()
+
+	GetOrCreateLogger("test").Info("disabled")
+	GetOrCreateLogger("test").Warning("enabled")
+	rec := MemoryRecordN(backend, 0)
+	if rec == nil || rec.Message() != "enabled" || MemoryRecordN(backend, 1) != nil {
+		t.Fatal("the backend wasn't restored")
+	}
+	if rec.ID != 42 || rec.IDString != "id" || !rec.Time.Equal(now) {
+		t.Errorf("unexpected record %d %q %s", rec.ID, rec.IDString, rec.Time)
+	}
+	if value, _ := GlobalFields().Get("env"); value != "test" {
+		t.Errorf("unexpected global fields %v", GlobalFields())
+	}
+	if GetFormatter() != GlogFormatter || DefaultLogger() != logger {
+		t.Error("the formatter or the default logger weren't restored")
+	}
+}
+
+// flushBackend counts the Flush calls.
+type flushBackend struct {
+	*MemoryBackend
+	flushed int
+}
+
+func (b *flushBackend) Flush() error {
+	b.flushed++
+	return nil
+}
+
+func TestLoggerFlush(t *testing.T) {
+	InitForTesting(DEBUG)
+	defer Reset()
+
+	flusher := &flushBackend{MemoryBackend: NewMemoryBackend(8)}
+	SetBackend(MultiLogger(
+		NewBackendFormatter(flusher, DefaultFormatter),
+		NewFilterBackend(NewMemoryBackend(8), ModuleMatches("*")),
+	))
+	log := GetOrCreateLogger("test")
+	if err := log.Flush(); err != nil || flusher.flushed != 1 {
+		t.Errorf("unexpected flush: %v %d", err, flusher.flushed)
+	}
+	if err := WithPrefix(log, "p").Flush(); err != nil || flusher.flushed != 2 {
+		t.Errorf("unexpected prefix flush: %v %d", err, flusher.flushed)
+	}
+
+	SetBackend(NewMemoryBackend(8))
+	if err := log.Flush(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestLoggerPanicFlush(t *testing.T) {
+	InitForTesting(DEBUG)
+	defer Reset()
+
+	flusher := &flushBackend{MemoryBackend: NewMemoryBackend(8)}
+	SetBackend(flusher)
+	log := GetOrCreateLogger("test").WithFields("k", "v")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+		if flusher.flushed != 1 {
+			t.Errorf("backend not flushed before panic: %d", flusher.flushed)
+		}
+	}()
+	log.Panicf("fatal %d", 1)
+}
+
+func TestLoggerPanicWait(t *testing.T) {
+	InitForTesting(DEBUG)
+	defer Reset()
+
+	channel := NewChannelMemoryBackend(8)
+	defer channel.Stop()
+	SetBackend(channel)
+	log := GetOrCreateLogger("test").WithFields("k", "v")
+	if !asyncUnflushedWriter(log.(*Log).writer) {
+		t.Error("the channel backend is async and can't be flushed")
+	}
+	start := time.Now()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		log.Panic("fatal")
+	}()
+	if d := time.Since(start); d < fatalWait {
+		t.Errorf("panicked after %v, without waiting for the channel backend", d)
+	}
+
+	SetBackend(NewLogBackend(&bytes.Buffer{}, "", 0))
+	if asyncUnflushedWriter(log.(*Log).writer) {
+		t.Error("the log backend is synchronous")
+	}
+	start = time.Now()
+	func() {
+		defer func() {
+			recover()
+		}()
+		log.Panic("fatal")
+	}()
+	if d := time.Since(start); d >= fatalWait {
+		t.Errorf("panicked after %v, waiting for a synchronous backend", d)
+	}
+}
+
+func TestLoggerSetPrefix(t *testing.T) {
+	InitForTesting(DEBUG)
+	buf := &bytes.Buffer{}
+	SetBackend(NewLogBackend(buf, "", 0))
+
+	log := WithPrefix(GetOrCreateLogger("test"), "old")
+	log.SetPrefix("new ->")
+	log.Info("hello")
+	WithPrefix(log, "child").Infof("%s", "world")
+
+	if log.Prefix() != "new ->" {
+		t.Errorf("unexpected prefix %q", log.Prefix())
+	}
+	if expected := "new -> hello\nnew -> child -> world\n"; buf.String() != expected {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+// plainWriter hides the WriteFields method of the LogWriter.
+type plainWriter struct {
+	LogWriter
+}
+
+func TestLoggerNestedPrefix(t *testing.T) {
+	InitForTesting(DEBUG)
+	buf := &bytes.Buffer{}
+	SetBackend(NewLogBackend(buf, "", 0))
+
+	plain := NewLogger("plain")
+	plain.writer = plainWriter{DefaultWriter(plain, "plain")}
+
+	for _, root := range []Logger{GetOrCreateLogger("structured"), plain} {
+		buf.Reset()
+		a := WithPrefix(root, " a ", ":")
+		b := WithPrefix(a, "b", " |")
+		c := WithPrefix(b, "c", "")
+		c.Info("hello")
+		c.Infof("%s", "world")
+
+		if prefix := c.FullPrefix(); prefix != "a: b | c" {
+			t.Errorf("unexpected full prefix %q", prefix)
+		}
+		if expected := "a: b | c hello\na: b | c world\n"; buf.String() != expected {
+			t.Errorf("unexpected output %q", buf.String())
+		}
+		if parents := c.Parents(); len(parents) != 3 || parents[0] != b || parents[1] != a || parents[2] != root {
+			t.Errorf("unexpected parents %v", parents)
+		}
+	}
+}
+
+func TestResetOutput(t *testing.T) {
+	defer Reset()
+
+	var buf bytes.Buffer
+	Reset(ResetOptions{Output: &buf})
+	NewLogger("output").Info("buffered")
+	if !strings.HasSuffix(buf.String(), " buffered\n") {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+
+	defer func() { DefaultOutput = os.Stderr }()
+	UseStdout()
+	if DefaultOutput != os.Stdout || GetBackend().(*moduleLeveled).backend.(*LogBackend).Logger.Writer() != os.Stdout {
+		t.Errorf("default backend not writing into stdout")
+	}
+}
+
+func TestLoggerWithoutDefaultBackend(t *testing.T) {
+	defer Reset()
+
+	defaultBackend.Store(backendBox{})
+	log := NewLogger("nil")
+	log.Info("discarded")
+	if log.IsEnabledFor(CRITICAL) {
+		t.Error("enabled without backend")
+	}
+	if err := log.Flush(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoggerRacingSetBackend(t *testing.T) {
+	defer Reset()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			NewLogger("race").Info("racing")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		SetBackend(NewMemoryBackend(8))
+	}
+	<-done
+}
+
+func TestLoggerWithModule(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	SetLevel(WARNING, "caller")
+
+	log := WithPrefix(GetOrCreateLogger("helper").WithFields("k", "v"), "p")
+	relabeled := log.WithModule("caller")
+	relabeled.Info("dropped")
+	relabeled.Warning("relabeled")
+	log.Info("helper")
+
+	if lines := memoryRecords(backend); len(lines) != 2 {
+		t.Fatalf("unexpected records: %q", lines)
+	}
+	rec := MemoryRecordN(backend, 0)
+	if rec.Module != "caller" || rec.Message() != "p -> relabeled" {
+		t.Errorf("unexpected record: %s %q", rec.Module, rec.Message())
+	}
+	if v, _ := rec.Fields.Get("k"); v != "v" {
+		t.Errorf("fields not kept: %v", rec.Fields)
+	}
+	if file, _, _, _ := rec.Caller(); !strings.HasSuffix(file, "logger_test.go") {
+		t.Errorf("unexpected caller %s", file)
+	}
+	if rec = MemoryRecordN(backend, 1); rec.Module != "helper" {
+		t.Errorf("unexpected module %s", rec.Module)
+	}
+	if GetLogger("caller") != nil {
+		t.Error("module logger registered")
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/moisespsena-go/logging/logrbridge
+
+go 1.18
+
+require (
+	github.com/go-logr/logr v1.4.2
+	github.com/moisespsena-go/logging v0.0.0
+)
+
+replace github.com/moisespsena-go/logging => ../
@@ -0,0 +1,97 @@
+// Package logrbridge provides a logr.LogSink which writes the records into a
+// logging.Logger, so libraries expecting a logr.Logger, like the Kubernetes
+// controller-runtime, can log into the logging backends. It's a separate
+// module, so logr isn't a dependency of the logging package.
+package logrbridge
+
+import (
+	"github.com/go-logr/logr"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// LogSink is a logr.LogSink which forwards records to a logging.Logger. The
+// key/value pairs are added as structured fields.
+type LogSink struct {
+	logger    logging.Logger
+	values    []interface{}
+	calldepth int
+}
+
+// NewLogSink creates a new logr.LogSink which writes into logger:
+//
+//	log := logr.New(logrbridge.NewLogSink(logging.GetOrCreateLogger("controller")))
+func NewLogSink(logger logging.Logger) logr.LogSink {
+	return &LogSink{logger: logger}
+}
+
+// ConvertLevel converts the logr verbosity to the logging Level.
+//
+//	V(0)      INFO
+//	V(1) ...  DEBUG
+func ConvertLevel(v int) logging.Level {
+	if v <= 0 {
+		return logging.INFO
+	}
+	return logging.DEBUG
+}
+
+// Init implements logr.LogSink.
+func (s *LogSink) Init(info logr.RuntimeInfo) {
+	s.calldepth = info.CallDepth
+}
+
+// Enabled implements logr.LogSink.
+func (s *LogSink) Enabled(level int) bool {
+	return s.logger.IsEnabledFor(ConvertLevel(level))
+}
+
+// Info implements logr.LogSink.
+func (s *LogSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.write(ConvertLevel(level), nil, msg, keysAndValues)
+}
+
+// Error implements logr.LogSink. The record is logged using ERROR as level
+// and err as the logging.ErrorKey field.
+func (s *LogSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.write(logging.ERROR, err, msg, keysAndValues)
+}
+
+func (s *LogSink) write(level logging.Level, err error, msg string, keysAndValues []interface{}) {
+	fields := logging.NewFields(s.values...)
+	fields = append(fields, logging.NewFields(keysAndValues...)...)
+	if err != nil {
+		fields = append(fields, logging.Field{Key: logging.ErrorKey, Value: err})
+	}
+
+	// calldepth=2 brings the stack up to the caller of the LogSink methods.
+	// The CallDepth of the logr runtime skips the frames of logr.Logger.
+	w := s.logger.Writer()
+	if fw, ok := w.(logging.FieldsLogWriter); ok {
+		fw.WriteFields(level, 2+s.calldepth, fields, nil, msg)
+	} else {
+		w.Write(level, 2+s.calldepth, nil, msg)
+	}
+}
+
+// WithValues implements logr.LogSink.
+func (s *LogSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	s2 := *s
+	s2.values = append(append(make([]interface{}, 0, len(s.values)+len(keysAndValues)), s.values...), keysAndValues...)
+	return &s2
+}
+
+// WithName implements logr.LogSink. The name is appended to the module of
+// the logger, see logging.Logger.Named.
+func (s *LogSink) WithName(name string) logr.LogSink {
+	s2 := *s
+	s2.logger = s.logger.Named(name)
+	return &s2
+}
+
+// WithCallDepth implements logr.CallDepthLogSink.
+func (s *LogSink) WithCallDepth(depth int) logr.LogSink {
+	s2 := *s
+	s2.calldepth += depth
+	return &s2
+}
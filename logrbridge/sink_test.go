@@ -0,0 +1,51 @@
+package logrbridge
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	"github.com/moisespsena-go/logging"
+)
+
+func memoryRecordN(b *logging.MemoryBackend, n int) *logging.Record {
+	node := b.Head()
+	for i := 0; i < n && node != nil; i++ {
+		node = node.Next()
+	}
+	if node == nil {
+		return nil
+	}
+	return node.Record
+}
+
+func TestLogSink(t *testing.T) {
+	backend := logging.InitForTesting(logging.INFO)
+	logging.SetFormatter(logging.MustStringFormatter("%{level} %{module} %{message} %{fields}"))
+
+	log := logr.New(NewLogSink(logging.NewLogger("k8s"))).WithValues("controller", "pods")
+	log.Info("reconciled", "name", "web", "replicas", 3)
+	log.V(1).Info("ignored")
+	log.WithName("cache").Error(errors.New("timeout"), "sync failed", "attempt", 2)
+
+	expected := []string{
+		"INFO k8s reconciled controller=pods name=web replicas=3",
+		"ERROR k8s.cache sync failed controller=pods attempt=2 error=timeout",
+	}
+	for i, line := range expected {
+		rec := memoryRecordN(backend, i)
+		if rec == nil || rec.Formatted(0) != line {
+			t.Errorf("%d: unexpected record %v, expected %q", i, rec, line)
+		} else if file, _, _, _ := rec.Caller(); filepath.Base(file) != "sink_test.go" {
+			t.Errorf("%d: unexpected caller %s", i, file)
+		}
+	}
+	if rec := memoryRecordN(backend, 2); rec != nil {
+		t.Errorf("unexpected record %q", rec.Formatted(0))
+	}
+	if log.V(1).Enabled() {
+		t.Error("V(1) enabled with INFO level")
+	}
+}
@@ -0,0 +1,42 @@
+// Package logtest helps the tests asserting on the logged records.
+package logtest
+
+import (
+	"strings"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// Captured contains the records logged since Capture.
+type Captured struct {
+	backend *logging.MemoryBackend
+}
+
+// Records returns the captured records.
+func (c *Captured) Records() []*logging.Record {
+	return c.backend.Records()
+}
+
+// Messages returns the messages of the captured records.
+func (c *Captured) Messages() (messages []string) {
+	for _, rec := range c.Records() {
+		messages = append(messages, rec.Message())
+	}
+	return
+}
+
+// Contains reports whether a record was captured with level and a message
+// containing substr.
+func (c *Captured) Contains(level logging.Level, substr string) bool {
+	for _, rec := range c.Records() {
+		if rec.Level == level && strings.Contains(rec.Message(), substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of captured records.
+func (c *Captured) Len() int {
+	return len(c.Records())
+}
@@ -0,0 +1,32 @@
+//go:build go1.14
+// +build go1.14
+
+package logtest
+
+import (
+	"testing"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// Capture replaces the default backend by a memory backend, enabling all
+// levels, until the end of the test:
+//
+//	func TestSomething(t *testing.T) {
+//		logs := logtest.Capture(t)
+//		doSomething()
+//		if !logs.Contains(logging.ERROR, "failed") {
+//			t.Error("error not logged")
+//		}
+//	}
+//
+// Like logging.InitForTesting, the global state is reset: the sequence
+// number, the module levels, the global fields, the default formatter and
+// logger, the ID generators and the time of the records, which is frozen. It's
+// restored by t.Cleanup, see logging.SaveGlobals. The loggers with their own
+// backend are not captured, and the tests using Capture must not run in
+// parallel. It requires go 1.14.
+func Capture(t testing.TB) *Captured {
+	t.Cleanup(logging.SaveGlobals())
+	return &Captured{logging.InitForTesting(logging.DEBUG)}
+}
@@ -0,0 +1,46 @@
+//go:build go1.14
+// +build go1.14
+
+package logtest
+
+import (
+	"testing"
+
+	"github.com/moisespsena-go/logging"
+)
+
+func TestCapture(t *testing.T) {
+	previous := logging.GetBackend()
+	logging.SetGlobalField("env", "test")
+	defer logging.SetGlobalField("env", nil)
+
+	t.Run("capture", func(t *testing.T) {
+		logs := Capture(t)
+		log := logging.GetOrCreateLogger("logtest")
+		log.Debug("debug enabled")
+		log.Errorf("request %d failed", 7)
+
+		if logs.Len() != 2 {
+			t.Errorf("expected 2 records, got %d", logs.Len())
+		}
+		if msgs := logs.Messages(); len(msgs) != 2 || msgs[0] != "debug enabled" || msgs[1] != "request 7 failed" {
+			t.Errorf("unexpected messages %q", msgs)
+		}
+		if !logs.Contains(logging.ERROR, "7 failed") || logs.Contains(logging.INFO, "failed") {
+			t.Error("unexpected Contains result")
+		}
+		if id := logs.Records()[0].ID; id != 1 {
+			t.Errorf("sequence not reset: %d", id)
+		}
+		if _, ok := logging.GlobalFields().Get("env"); ok {
+			t.Error("the global fields weren't reset")
+		}
+	})
+
+	if logging.GetBackend() != previous {
+		t.Error("the previous backend wasn't restored")
+	}
+	if value, _ := logging.GlobalFields().Get("env"); value != "test" {
+		t.Errorf("the global fields weren't restored: %v", logging.GlobalFields())
+	}
+}
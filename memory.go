@@ -26,9 +26,9 @@ func InitForTesting(level Level) *MemoryBackend {
 	leveledBackend.SetLevel(level, "")
 	SetBackend(leveledBackend)
 
-	timeNow = func() time.Time {
+	SetTimeNow(func() time.Time {
 		return time.Unix(0, 0).UTC()
-	}
+	})
 	return memoryBackend
 }
 
@@ -41,7 +41,11 @@ type node struct {
 // Next returns the next record node. If there's no node available, it will
 // return nil.
 func (n *node) Next() *node {
-	return n.next
+	return (*node)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&n.next))))
+}
+
+func (n *node) setNext(next *node) {
+	atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&n.next)), unsafe.Pointer(next))
 }
 
 // MemoryBackend is a simple memory based logging backend that will not produce
@@ -61,6 +65,7 @@ func NewMemoryBackend(size int) *MemoryBackend {
 func (b *MemoryBackend) Log(level Level, calldepth int, rec *Record) error {
 	var size int32
 
+	rec.Retain()
 	n := &node{Record: rec}
 	np := unsafe.Pointer(n)
 
@@ -76,9 +81,9 @@ func (b *MemoryBackend) Log(level Level, calldepth int, rec *Record) error {
 		)
 		if swapped == true {
 			if tailp == nil {
-				b.head = np
+				atomic.StorePointer(&b.head, np)
 			} else {
-				(*node)(tailp).next = n
+				(*node)(tailp).setNext(n)
 			}
 			size = atomic.AddInt32(&b.size, 1)
 			break
@@ -90,15 +95,15 @@ func (b *MemoryBackend) Log(level Level, calldepth int, rec *Record) error {
 	// eventual consistent.
 	if b.maxSize > 0 && size > b.maxSize {
 		for {
-			headp := b.head
-			head := (*node)(b.head)
-			if head.next == nil {
+			headp := atomic.LoadPointer(&b.head)
+			next := (*node)(headp).Next()
+			if next == nil {
 				break
 			}
 			swapped := atomic.CompareAndSwapPointer(
 				&b.head,
 				headp,
-				unsafe.Pointer(head.next),
+				unsafe.Pointer(next),
 			)
 			if swapped == true {
 				atomic.AddInt32(&b.size, -1)
@@ -115,7 +120,28 @@ func (b *MemoryBackend) Log(level Level, calldepth int, rec *Record) error {
 // Note: new records can get added while iterating. Hence the number of records
 // iterated over might be larger than the maximum size.
 func (b *MemoryBackend) Head() *node {
-	return (*node)(b.head)
+	return (*node)(atomic.LoadPointer(&b.head))
+}
+
+// Records returns a snapshot of the records kept in memory, from the oldest to
+// the newest. It's safe to call while records are logged.
+func (b *MemoryBackend) Records() []*Record {
+	return b.RecordsLevel(DEBUG)
+}
+
+// RecordsLevel is like Records, but only returns the records with the given
+// level or a more severe one.
+func (b *MemoryBackend) RecordsLevel(level Level) (records []*Record) {
+	max := int(atomic.LoadInt32(&b.size))
+	if b.maxSize > 0 && max > int(b.maxSize) {
+		max = int(b.maxSize)
+	}
+	for i, n := 0, b.Head(); n != nil && i < max; i, n = i+1, n.Next() {
+		if n.Record.Level <= level {
+			records = append(records, n.Record)
+		}
+	}
+	return
 }
 
 type event int
@@ -223,6 +249,7 @@ func (b *ChannelMemoryBackend) Stop() {
 
 // Log implements the Log method required by Backend.
 func (b *ChannelMemoryBackend) Log(level Level, calldepth int, rec *Record) error {
+	rec.Retain()
 	b.incoming <- rec
 	return nil
 }
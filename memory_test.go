@@ -115,3 +115,52 @@ func TestChannelMemoryBackend(t *testing.T) {
 		t.Errorf("unexpected eof: %s", record.Formatted(0))
 	}
 }
+
+func TestMemoryBackendRecords(t *testing.T) {
+	backend := NewMemoryBackend(4)
+	SetBackend(backend)
+
+	log := GetOrCreateLogger("test")
+	for i := 0; i < 6; i++ {
+		if i%2 == 0 {
+			log.Errorf("%d", i)
+		} else {
+			log.Infof("%d", i)
+		}
+	}
+
+	records := backend.Records()
+	if len(records) != 4 {
+		t.Fatalf("record length: %d", len(records))
+	}
+	for i, record := range records {
+		if expected := strconv.Itoa(i + 2); record.Formatted(0) != expected {
+			t.Errorf("record %d: %s != %s", i, record.Formatted(0), expected)
+		}
+	}
+
+	records = backend.RecordsLevel(WARNING)
+	if len(records) != 2 || records[0].Formatted(0) != "2" || records[1].Formatted(0) != "4" {
+		t.Errorf("unexpected records: %v", records)
+	}
+}
+
+func TestMemoryBackendConcurrentRecords(t *testing.T) {
+	backend := NewMemoryBackend(16)
+	SetBackend(backend)
+
+	log := GetOrCreateLogger("test")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			log.Info("concurrent")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if n := len(backend.Records()); n > 16 {
+			t.Fatalf("too many records: %d", n)
+		}
+	}
+	<-done
+}
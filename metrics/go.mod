@@ -0,0 +1,10 @@
+module github.com/moisespsena-go/logging/metrics
+
+go 1.12
+
+require (
+	github.com/moisespsena-go/logging v0.0.0
+	github.com/prometheus/client_golang v1.11.1
+)
+
+replace github.com/moisespsena-go/logging => ../
@@ -0,0 +1,127 @@
+// Package metrics provides a backend which counts the records into Prometheus
+// metrics. It's a separate module, so the Prometheus client isn't a dependency
+// of the logging package.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// PromOptions configures NewPromBackend.
+type PromOptions struct {
+	// Namespace and Subsystem prefix the names of the metrics.
+	Namespace string
+	Subsystem string
+	// SizeBuckets enables the histogram of the message sizes, in bytes, by
+	// level. Use prometheus.ExponentialBuckets(64, 4, 6) for sizes from 64
+	// bytes to 64 kilobytes.
+	SizeBuckets []float64
+}
+
+// PromBackend counts the records, by level and module, and passes them to the
+// inner backend.
+type PromBackend struct {
+	inner   logging.Backend
+	records *prometheus.CounterVec
+	sizes   *prometheus.HistogramVec
+}
+
+// NewPromBackend creates a backend which increments the log_records_total
+// counter, labeled by level and module, for each record, and passes the
+// record to inner. If registerer is nil, prometheus.DefaultRegisterer is used.
+// The metrics already registered, eg. by other PromBackend, are reused.
+//
+// It's a thin wrapper, so it composes with the others, eg:
+//
+//	logging.SetBackend(logging.MultiLogger(
+//		metrics.MustPromBackend(fileBackend, nil),
+//		httpBackend,
+//	))
+func NewPromBackend(inner logging.Backend, registerer prometheus.Registerer, options ...PromOptions) (b *PromBackend, err error) {
+	var opts PromOptions
+	for _, opts = range options {
+	}
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	b = &PromBackend{inner: inner}
+	records := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: opts.Namespace,
+		Subsystem: opts.Subsystem,
+		Name:      "log_records_total",
+		Help:      "Number of log records by level and module.",
+	}, []string{"level", "module"})
+	if b.records, err = registerCounterVec(registerer, records); err != nil {
+		return nil, err
+	}
+
+	if len(opts.SizeBuckets) > 0 {
+		sizes := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opts.Namespace,
+			Subsystem: opts.Subsystem,
+			Name:      "log_message_size_bytes",
+			Help:      "Size of the log messages by level.",
+			Buckets:   opts.SizeBuckets,
+		}, []string{"level"})
+		if b.sizes, err = registerHistogramVec(registerer, sizes); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// MustPromBackend is like NewPromBackend but panics if the metrics can't be
+// registered.
+func MustPromBackend(inner logging.Backend, registerer prometheus.Registerer, options ...PromOptions) *PromBackend {
+	b, err := NewPromBackend(inner, registerer, options...)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// registerCounterVec registers c, or returns the CounterVec already
+// registered with the same description.
+func registerCounterVec(registerer prometheus.Registerer, c *prometheus.CounterVec) (*prometheus.CounterVec, error) {
+	if err := registerer.Register(c); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(*prometheus.CounterVec); ok {
+				return existing, nil
+			}
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// registerHistogramVec registers h, or returns the HistogramVec already
+// registered with the same description.
+func registerHistogramVec(registerer prometheus.Registerer, h *prometheus.HistogramVec) (*prometheus.HistogramVec, error) {
+	if err := registerer.Register(h); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(*prometheus.HistogramVec); ok {
+				return existing, nil
+			}
+		}
+		return nil, err
+	}
+	return h, nil
+}
+
+// Log implements the logging.Backend interface.
+func (b *PromBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	name := level.String()
+	b.records.WithLabelValues(name, rec.Module).Inc()
+	if b.sizes != nil {
+		b.sizes.WithLabelValues(name).Observe(float64(len(rec.Message())))
+	}
+	return b.inner.Log(level, calldepth+1, rec)
+}
+
+// Flush implements the logging.Flusher interface, flushing the inner backend.
+func (b *PromBackend) Flush() error {
+	return logging.FlushBackend(b.inner)
+}
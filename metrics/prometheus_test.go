@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/moisespsena-go/logging"
+)
+
+func TestPromBackend(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	memory := logging.NewMemoryBackend(8)
+	b, err := NewPromBackend(memory, registry, PromOptions{SizeBuckets: []float64{4, 16}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := logging.NewLogger("prom")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.Info("first")
+	log.Info("second")
+	log.Error("failed")
+
+	if n := testutil.ToFloat64(b.records.WithLabelValues("INFO", "prom")); n != 2 {
+		t.Errorf("unexpected INFO count: %v", n)
+	}
+	if n := testutil.ToFloat64(b.records.WithLabelValues("ERROR", "prom")); n != 1 {
+		t.Errorf("unexpected ERROR count: %v", n)
+	}
+	if memory.Head() == nil {
+		t.Errorf("records not passed to the inner backend")
+	}
+	if n := testutil.CollectAndCount(b.sizes); n != 2 {
+		t.Errorf("unexpected histogram series: %d", n)
+	}
+
+	b2, err := NewPromBackend(memory, registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b2.records != b.records {
+		t.Errorf("registered counter not reused")
+	}
+}
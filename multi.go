@@ -4,54 +4,242 @@
 
 package logging
 
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
 // TODO remove Level stuff from the multi logger. Do one thing.
 
+// MultiBackend is a multi logger whose backends can be added and removed
+// while logging, eg. to add a debug backend at runtime:
+//
+//	multi := logging.SetBackend(fileBackend, httpBackend).(logging.MultiBackend)
+//	multi.Add(debugBackend)
+//	defer multi.Remove(debugBackend)
+type MultiBackend interface {
+	LeveledBackend
+	Printer
+	Flusher
+	// Close closes the backends which implement io.Closer.
+	io.Closer
+	// Add adds backend, wrapped with AddModuleLevel.
+	Add(backend Backend)
+	// Remove removes backend, as passed to MultiLogger or Add. It returns
+	// false if backend wasn't found.
+	Remove(backend Backend) bool
+	// Backends returns the backends, as passed to MultiLogger or Add.
+	Backends() []Backend
+	// SetFallback sets the backend which receives the records when all
+	// enabled backends failed to log them, eg. a LogBackend writing into
+	// os.Stderr. The errors are returned anyway. Nil disables it.
+	SetFallback(backend Backend)
+}
+
+// MultiError is returned by the multi logger when more than one backend
+// failed. The errors are in the order of the backends.
+type MultiError []error
+
+func (e MultiError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "logger: " + strconv.Itoa(len(e)) + " backends failed: " + strings.Join(msgs, "; ")
+}
+
+// err returns nil without errors, the error itself if there is only one, or
+// e otherwise.
+func (e MultiError) err() error {
+	switch len(e) {
+	case 0:
+		return nil
+	case 1:
+		return e[0]
+	}
+	return e
+}
+
 // multiLogger is a log multiplexer which can be used to utilize multiple log
 // backends at once.
 type multiLogger struct {
+	// mu guards the slices, which are replaced, never changed, so a copy of
+	// them can be used without the lock.
+	mu       sync.RWMutex
 	backends []LeveledBackend
+	// added are the backends as passed to MultiLogger and Add.
+	added    []Backend
+	fallback Backend
 }
 
 // MultiLogger creates a logger which contain multiple loggers.
-func MultiLogger(backends ...Backend) LeveledBackend {
-	var leveledBackends []LeveledBackend
+func MultiLogger(backends ...Backend) MultiBackend {
+	b := &multiLogger{}
 	for _, backend := range backends {
-		leveledBackends = append(leveledBackends, AddModuleLevel(backend))
+		b.backends = append(b.backends, AddModuleLevel(backend))
+		b.added = append(b.added, backend)
 	}
-	return &multiLogger{leveledBackends}
+	return b
 }
 
-// Log passes the log record to all backends.
-func (b *multiLogger) Log(level Level, calldepth int, rec *Record) (err error) {
-	for _, backend := range b.backends {
+// sameBackend reports whether a and b are the same backend. Backends of not
+// comparable types are never the same.
+func sameBackend(a, b Backend) bool {
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t != nil && t.Comparable() && a == b
+}
+
+// leveled returns the leveled backends.
+func (b *multiLogger) leveled() []LeveledBackend {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.backends
+}
+
+// Add implements the MultiBackend interface.
+func (b *multiLogger) Add(backend Backend) {
+	b.add(backend)
+}
+
+// add adds backend and returns it wrapped with AddModuleLevel.
+func (b *multiLogger) add(backend Backend) LeveledBackend {
+	leveled := AddModuleLevel(backend)
+	b.mu.Lock()
+	b.backends = append(b.backends[:len(b.backends):len(b.backends)], leveled)
+	b.added = append(b.added[:len(b.added):len(b.added)], backend)
+	b.mu.Unlock()
+	invalidateLevels()
+	return leveled
+}
+
+// Remove implements the MultiBackend interface.
+func (b *multiLogger) Remove(backend Backend) bool {
+	return b.remove(backend) >= 0
+}
+
+// remove removes backend and returns its index, or -1 if it wasn't found.
+func (b *multiLogger) remove(backend Backend) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, added := range b.added {
+		if sameBackend(added, backend) {
+			b.backends = append(b.backends[:i:i], b.backends[i+1:]...)
+			b.added = append(b.added[:i:i], b.added[i+1:]...)
+			invalidateLevels()
+			return i
+		}
+	}
+	return -1
+}
+
+// Backends implements the MultiBackend interface.
+func (b *multiLogger) Backends() []Backend {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]Backend(nil), b.added...)
+}
+
+// Close implements the MultiBackend interface. All closers are closed, and
+// the first error is returned.
+func (b *multiLogger) Close() (err error) {
+	for _, backend := range b.Backends() {
+		if c, ok := backend.(io.Closer); ok {
+			if e := c.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return
+}
+
+// SetFallback implements the MultiBackend interface.
+func (b *multiLogger) SetFallback(backend Backend) {
+	b.mu.Lock()
+	b.fallback = backend
+	b.mu.Unlock()
+}
+
+// Log passes the log record to all enabled backends, even if some of them
+// fail. If more than one failed, the errors are returned as a MultiError. If
+// all of them failed, the record is passed to the fallback backend, if set.
+func (b *multiLogger) Log(level Level, calldepth int, rec *Record) error {
+	var (
+		errs    MultiError
+		enabled int
+	)
+	for _, backend := range b.leveled() {
 		if backend.IsEnabledFor(level, rec.Module) {
+			enabled++
 			// Shallow copy of the record for the formatted cache on Record and get the
 			// record formatter from the backend.
 			r2 := *rec
-			if e := backend.Log(level, calldepth+1, &r2); e != nil {
-				err = e
+			if err := backend.Log(level, calldepth+1, &r2); err != nil {
+				errs = append(errs, err)
 			}
 		}
 	}
+	if enabled > 0 && len(errs) == enabled {
+		b.mu.RLock()
+		fallback := b.fallback
+		b.mu.RUnlock()
+		if fallback != nil {
+			r2 := *rec
+			if err := fallback.Log(level, calldepth+1, &r2); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs.err()
+}
+
+// PrintLevel implements the LevelPrinter interface, printing into the backends
+// enabled for level.
+func (b *multiLogger) PrintLevel(level Level, args ...interface{}) error {
+	return b.Log(level, 1, PrintRecord(level, args...))
+}
+
+// Flush implements the Flusher interface, flushing all backends.
+func (b *multiLogger) Flush() (err error) {
+	for _, backend := range b.leveled() {
+		if e := FlushBackend(backend); e != nil && err == nil {
+			err = e
+		}
+	}
 	return
 }
 
-// Print passes the args record to all print.
-func (b *multiLogger) Print(args ...interface{}) (err error) {
-	for _, backend := range b.backends {
+func (b *multiLogger) wrappedBackends() []Backend {
+	leveled := b.leveled()
+	backends := make([]Backend, len(leveled))
+	for i, backend := range leveled {
+		backends[i] = backend
+	}
+	return backends
+}
+
+// Print passes the args record to all print, even if some of them fail, like
+// Log.
+func (b *multiLogger) Print(args ...interface{}) error {
+	var errs MultiError
+	for _, backend := range b.leveled() {
 		if p, ok := backend.(Printer); ok {
-			if err = p.Print(args); err != nil {
-				return
+			if err := p.Print(args...); err != nil {
+				errs = append(errs, err)
 			}
 		}
 	}
-	return
+	return errs.err()
 }
 
-// GetLevel returns the highest level enabled by all backends.
+// GetLevel returns the highest level enabled by all backends, or OFF if all
+// of them are disabled.
 func (b *multiLogger) GetLevel(module string) Level {
-	var level Level
-	for _, backend := range b.backends {
+	level := OFF
+	for _, backend := range b.leveled() {
 		if backendLevel := backend.GetLevel(module); backendLevel > level {
 			level = backendLevel
 		}
@@ -61,14 +249,14 @@ func (b *multiLogger) GetLevel(module string) Level {
 
 // SetLevel propagates the same level to all backends.
 func (b *multiLogger) SetLevel(level Level, module string) {
-	for _, backend := range b.backends {
+	for _, backend := range b.leveled() {
 		backend.SetLevel(level, module)
 	}
 }
 
 // IsEnabledFor returns true if any of the backends are enabled for it.
 func (b *multiLogger) IsEnabledFor(level Level, module string) bool {
-	for _, backend := range b.backends {
+	for _, backend := range b.leveled() {
 		if backend.IsEnabledFor(level, module) {
 			return true
 		}
@@ -76,15 +264,300 @@ func (b *multiLogger) IsEnabledFor(level Level, module string) bool {
 	return false
 }
 
+// OverflowPolicy defines what happens when a record is logged into a full
+// queue.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until the queue has room for the record.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest queued record to make room for the
+	// new one.
+	OverflowDropOldest
+	// OverflowDropNewest drops the new record.
+	OverflowDropNewest
+)
+
+// DefaultMultiQueueSize is the default size of the queue of each backend of
+// an async multi logger.
+const DefaultMultiQueueSize = 1024
+
+// AsyncMultiOptions configures AsyncMultiLogger.
+type AsyncMultiOptions struct {
+	// QueueSize is the number of records queued for each backend. Defaults to
+	// DefaultMultiQueueSize.
+	QueueSize int
+	// Overflow is the policy used when the queue of a backend is full.
+	Overflow OverflowPolicy
+}
+
+// AsyncMultiBackend is a multi logger which passes the records to each backend
+// in background.
+type AsyncMultiBackend interface {
+	LeveledBackend
+	Printer
+	// Flush waits until all queued records are passed to the backends and
+	// flushes them.
+	Flush() error
+	// Close drains the queues and stops the workers. The backends aren't
+	// closed.
+	Close() error
+	// Add adds backend, with its own queue and worker.
+	Add(backend Backend)
+	// Remove drains the queue of backend and removes it. It returns false if
+	// backend wasn't found.
+	Remove(backend Backend) bool
+	// Backends returns the backends, as passed to AsyncMultiLogger or Add.
+	Backends() []Backend
+}
+
+// asyncMultiLogger is a multiLogger with a queue and a worker per backend.
+type asyncMultiLogger struct {
+	*multiLogger
+	opts    AsyncMultiOptions
+	workers []*multiWorker
+	mu      sync.RWMutex
+	closed  bool
+}
+
+// AsyncMultiLogger creates a multi logger which queues the records of each
+// backend and passes them into the backend using its own goroutine, so a slow
+// backend doesn't block the others.
+//
+// The records are formatted by the workers, so the caller based verbs of the
+// formatters, like %{shortfile}, aren't available.
+func AsyncMultiLogger(opts AsyncMultiOptions, backends ...Backend) AsyncMultiBackend {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultMultiQueueSize
+	}
+	b := &asyncMultiLogger{multiLogger: MultiLogger(backends...).(*multiLogger), opts: opts}
+	for _, backend := range b.backends {
+		b.workers = append(b.workers, newMultiWorker(backend, opts))
+	}
+	return b
+}
+
+var errMultiClosed = errors.New("logger: multi logger is closed")
+
+// Log queues the log record to all enabled backends.
+func (b *asyncMultiLogger) Log(level Level, calldepth int, rec *Record) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return errMultiClosed
+	}
+	if rec.IsSync() {
+		return b.logSync(level, calldepth+1, rec)
+	}
+	for _, w := range b.workers {
+		if w.backend.IsEnabledFor(level, rec.Module) {
+			// each worker has its own copy, as formatting the record changes
+			// its Args
+			backend, r2 := w.backend, rec.Clone()
+			w.push(func() {
+				if err := backend.Log(level, 0, r2); err != nil {
+					HandleError(err)
+				}
+			})
+		}
+	}
+	return nil
+}
+
+// logSync passes the synchronous record into the enabled backends, after their
+// queued records, and returns their errors.
+func (b *asyncMultiLogger) logSync(level Level, calldepth int, rec *Record) error {
+	var errs MultiError
+	for _, w := range b.workers {
+		if w.backend.IsEnabledFor(level, rec.Module) {
+			w.flushQueued()
+			if err := w.backend.Log(level, calldepth+1, rec); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs.err()
+}
+
+// Print queues the args to all printer backends.
+func (b *asyncMultiLogger) Print(args ...interface{}) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return errMultiClosed
+	}
+	for _, w := range b.workers {
+		if p, ok := w.backend.(Printer); ok {
+			w.push(func() {
+				if err := p.Print(args...); err != nil {
+					HandleError(err)
+				}
+			})
+		}
+	}
+	return nil
+}
+
+// PrintLevel implements the LevelPrinter interface.
+func (b *asyncMultiLogger) PrintLevel(level Level, args ...interface{}) error {
+	return b.Log(level, 1, PrintRecord(level, args...))
+}
+
+// Flush waits until all queued records are passed to the backends and
+// flushes them.
+func (b *asyncMultiLogger) Flush() error {
+	b.mu.RLock()
+	workers := b.workers
+	b.mu.RUnlock()
+	for _, w := range workers {
+		w.flush()
+	}
+	return b.multiLogger.Flush()
+}
+
+// Add implements the AsyncMultiBackend interface. Nothing is added after
+// Close.
+func (b *asyncMultiLogger) Add(backend Backend) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.workers = append(b.workers[:len(b.workers):len(b.workers)], newMultiWorker(b.multiLogger.add(backend), b.opts))
+}
+
+// Remove implements the AsyncMultiBackend interface.
+func (b *asyncMultiLogger) Remove(backend Backend) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := b.multiLogger.remove(backend)
+	if i < 0 {
+		return false
+	}
+	w := b.workers[i]
+	b.workers = append(b.workers[:i:i], b.workers[i+1:]...)
+	if !b.closed {
+		w.close()
+	}
+	return true
+}
+
+// Close drains the queues and stops the workers.
+func (b *asyncMultiLogger) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	for _, w := range b.workers {
+		w.close()
+	}
+	return nil
+}
+
+// multiWorker passes the queued records into a backend.
+type multiWorker struct {
+	backend  LeveledBackend
+	overflow OverflowPolicy
+	queue    chan func()
+	mu       sync.Mutex
+	cond     *sync.Cond
+	pending  int
+	// finished is the number of queued functions done or dropped.
+	finished uint64
+	stopped  chan struct{}
+}
+
+func newMultiWorker(backend LeveledBackend, opts AsyncMultiOptions) *multiWorker {
+	w := &multiWorker{
+		backend:  backend,
+		overflow: opts.Overflow,
+		queue:    make(chan func(), opts.QueueSize),
+		stopped:  make(chan struct{}),
+	}
+	w.cond = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+func (w *multiWorker) run() {
+	defer close(w.stopped)
+	for f := range w.queue {
+		f()
+		w.done()
+	}
+}
+
+// done marks a queued function as done or dropped.
+func (w *multiWorker) done() {
+	w.mu.Lock()
+	w.pending--
+	w.finished++
+	w.cond.Broadcast()
+	w.mu.Unlock()
+}
+
+// push queues f following the overflow policy.
+func (w *multiWorker) push(f func()) {
+	w.mu.Lock()
+	w.pending++
+	w.mu.Unlock()
+
+	switch w.overflow {
+	case OverflowDropNewest:
+		select {
+		case w.queue <- f:
+		default:
+			w.done()
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case w.queue <- f:
+				return
+			default:
+			}
+			select {
+			case <-w.queue:
+				w.done()
+			default:
+			}
+		}
+	default:
+		w.queue <- f
+	}
+}
+
+func (w *multiWorker) flush() {
+	w.mu.Lock()
+	for w.pending > 0 {
+		w.cond.Wait()
+	}
+	w.mu.Unlock()
+}
+
+// flushQueued waits until the functions pending when it was called are done
+// or dropped, but not the ones queued later, which may keep the worker busy
+// indefinitely.
+func (w *multiWorker) flushQueued() {
+	w.mu.Lock()
+	for target := w.finished + uint64(w.pending); w.finished < target; {
+		w.cond.Wait()
+	}
+	w.mu.Unlock()
+}
+
+func (w *multiWorker) close() {
+	close(w.queue)
+	<-w.stopped
+}
+
 // Tee copy log messages to all loggers.
 func Tee(logger ...Logger) Logger {
-	var writers = make([]LogWriter, len(logger))
+	var writers = make(multiWriter, len(logger))
 	for i, l := range logger {
 		writers[i] = l.Writer()
 	}
-	return &Log{Basic: NewBasic(NewWriter(func(lvl Level, extraCalldepth int, format *string, args ...interface{}) {
-		for _, w := range writers {
-			w.Write(lvl, 2+extraCalldepth, format, args...)
-		}
-	}))}
+	return &Log{Basic: NewBasic(writers)}
 }
@@ -4,7 +4,12 @@
 
 package logging
 
-import "testing"
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
 
 func TestMultiLogger(t *testing.T) {
 	log1 := NewMemoryBackend(8)
@@ -49,3 +54,194 @@ func TestMultiLoggerLevel(t *testing.T) {
 		t.Errorf("log2 received")
 	}
 }
+
+func TestMultiLoggerAddRemove(t *testing.T) {
+	log1 := NewMemoryBackend(8)
+	debug := NewMemoryBackend(8)
+	multi := MultiLogger(log1)
+	SetBackend(multi)
+	log := NewLogger("test")
+
+	multi.Add(debug)
+	log.Info("added")
+	if rec := MemoryRecordN(debug, 0); rec == nil || rec.Message() != "added" {
+		t.Fatalf("added backend not used: %v", rec)
+	}
+	if backends := multi.Backends(); len(backends) != 2 || backends[1] != debug {
+		t.Errorf("unexpected backends: %v", backends)
+	}
+
+	if !multi.Remove(debug) || multi.Remove(debug) {
+		t.Errorf("unexpected Remove results")
+	}
+	log.Info("removed")
+	if rec := MemoryRecordN(debug, 1); rec != nil {
+		t.Errorf("removed backend used: %v", rec)
+	}
+	if rec := MemoryRecordN(log1, 1); rec == nil || rec.Message() != "removed" {
+		t.Errorf("unexpected record: %v", rec)
+	}
+}
+
+func TestMultiLoggerErrors(t *testing.T) {
+	err1, err2 := errors.New("down"), errors.New("full")
+	memory := NewMemoryBackend(8)
+	multi := MultiLogger(errorBackend{err1}, memory)
+
+	err := multi.Log(INFO, 0, PrintRecord(INFO, "first"))
+	if err != err1 {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if rec := MemoryRecordN(memory, 0); rec == nil || rec.Message() != "first" {
+		t.Fatalf("second backend skipped: %v", rec)
+	}
+
+	fallback := NewMemoryBackend(8)
+	multi = MultiLogger(errorBackend{err1}, errorBackend{err2})
+	multi.SetFallback(fallback)
+	err = multi.Log(INFO, 0, PrintRecord(INFO, "second"))
+	if errs, ok := err.(MultiError); !ok || len(errs) != 2 || errs[0] != err1 || errs[1] != err2 {
+		t.Errorf("unexpected error: %v", err)
+	} else if err.Error() != "logger: 2 backends failed: down; full" {
+		t.Errorf("unexpected message: %s", err)
+	}
+	if rec := MemoryRecordN(fallback, 0); rec == nil || rec.Message() != "second" {
+		t.Errorf("fallback not used: %v", rec)
+	}
+}
+
+// closeCounter counts the Close calls.
+type closeCounter int
+
+func (c *closeCounter) Close() error {
+	*c++
+	return nil
+}
+
+func TestMultiLoggerClose(t *testing.T) {
+	var closed closeCounter
+	multi := MultiLogger(NewBackendClose(NewMemoryBackend(8), &closed), NewMemoryBackend(8))
+	if _, ok := multi.(BackendCloser); !ok {
+		t.Fatalf("multi logger isn't a BackendCloser")
+	}
+	if err := multi.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if closed != 1 {
+		t.Errorf("unexpected Close calls: %d", closed)
+	}
+}
+
+// blockingBackend waits for release before returning from Log.
+type blockingBackend struct {
+	release chan struct{}
+	memory  *MemoryBackend
+}
+
+func (b *blockingBackend) Log(level Level, calldepth int, rec *Record) error {
+	<-b.release
+	return b.memory.Log(level, calldepth+1, rec)
+}
+
+func TestAsyncMultiLogger(t *testing.T) {
+	fast := NewMemoryBackend(8)
+	slow := &blockingBackend{make(chan struct{}), NewMemoryBackend(8)}
+	multi := AsyncMultiLogger(AsyncMultiOptions{}, fast, slow)
+	SetBackend(multi)
+
+	log := GetOrCreateLogger("test")
+	log.Info("first")
+	log.Info("second")
+
+	multi.(*asyncMultiLogger).workers[0].flush()
+	if rec := MemoryRecordN(fast, 1); rec == nil || rec.Message() != "second" {
+		t.Fatalf("fast backend blocked by the slow one: %v", rec)
+	}
+
+	close(slow.release)
+	multi.Flush()
+	if rec := MemoryRecordN(slow.memory, 1); rec == nil || rec.Message() != "second" {
+		t.Errorf("unexpected slow record: %v", rec)
+	}
+
+	if err := multi.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := multi.Log(INFO, 0, &Record{}); err != errMultiClosed {
+		t.Errorf("expected closed error, got %v", err)
+	}
+}
+
+func TestAsyncMultiLoggerRedaction(t *testing.T) {
+	InitForTesting(DEBUG)
+	SetFormatter(MustStringFormatter("%{message}"))
+	first, second := &bytes.Buffer{}, &bytes.Buffer{}
+	multi := AsyncMultiLogger(AsyncMultiOptions{}, NewLogBackend(first, "", 0), NewLogBackend(second, "", 0))
+	SetBackend(multi)
+
+	log := NewLogger("test")
+	for i := 0; i < 4; i++ {
+		log.Info("password:", Password("secret"))
+	}
+	if err := multi.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Repeat("password: ******\n", 4)
+	for _, buf := range []*bytes.Buffer{first, second} {
+		if buf.String() != expected {
+			t.Errorf("unexpected output %q", buf.String())
+		}
+	}
+}
+
+func TestAsyncMultiLoggerDropOldest(t *testing.T) {
+	slow := &blockingBackend{make(chan struct{}), NewMemoryBackend(8)}
+	multi := AsyncMultiLogger(AsyncMultiOptions{QueueSize: 2, Overflow: OverflowDropOldest}, slow)
+	SetBackend(multi)
+
+	log := GetOrCreateLogger("test")
+	for i := 0; i < 6; i++ {
+		log.Infof("%d", i)
+	}
+	close(slow.release)
+	if err := multi.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The worker may take the first record before the queue is full.
+	var msgs []string
+	for node := slow.memory.Head(); node != nil; node = node.Next() {
+		msgs = append(msgs, node.Record.Message())
+	}
+	if n := len(msgs); n < 2 || n > 3 || msgs[n-2] != "4" || msgs[n-1] != "5" {
+		t.Errorf("unexpected records: %v", msgs)
+	}
+}
+
+func TestAsyncMultiLoggerAddRemove(t *testing.T) {
+	log1 := NewMemoryBackend(8)
+	debug := NewMemoryBackend(8)
+	multi := AsyncMultiLogger(AsyncMultiOptions{}, log1)
+	defer multi.Close()
+	SetBackend(multi)
+	log := NewLogger("test")
+
+	multi.Add(debug)
+	log.Info("added")
+	multi.Flush()
+	if rec := MemoryRecordN(debug, 0); rec == nil || rec.Message() != "added" {
+		t.Fatalf("added backend not used: %v", rec)
+	}
+
+	if !multi.Remove(debug) {
+		t.Fatalf("backend not removed")
+	}
+	log.Info("removed")
+	multi.Flush()
+	if rec := MemoryRecordN(debug, 1); rec != nil {
+		t.Errorf("removed backend used: %v", rec)
+	}
+	if rec := MemoryRecordN(log1, 1); rec == nil || rec.Message() != "removed" {
+		t.Errorf("unexpected record: %v", rec)
+	}
+}
@@ -0,0 +1,35 @@
+package logging
+
+// nullBackend discards all records.
+type nullBackend struct{}
+
+// NewNullBackend returns a backend which discards all records. It's a
+// LeveledBackend with all levels disabled, so after SetBackend(NewNullBackend())
+// the loggers return false from IsEnabledFor and don't even create the records
+// nor format the messages.
+func NewNullBackend() PrinterLeveledBackend {
+	return nullBackend{}
+}
+
+// Log implements the Backend interface.
+func (nullBackend) Log(Level, int, *Record) error {
+	return nil
+}
+
+// Print implements the Printer interface.
+func (nullBackend) Print(...interface{}) error {
+	return nil
+}
+
+// GetLevel returns -1, a level below CRITICAL, for all modules.
+func (nullBackend) GetLevel(string) Level {
+	return -1
+}
+
+// SetLevel does nothing.
+func (nullBackend) SetLevel(Level, string) {}
+
+// IsEnabledFor returns false for all levels.
+func (nullBackend) IsEnabledFor(Level, string) bool {
+	return false
+}
@@ -0,0 +1,34 @@
+package logging
+
+import "testing"
+
+type countStringer struct{ count *int }
+
+func (s countStringer) String() string {
+	*s.count++
+	return "value"
+}
+
+func TestNullBackend(t *testing.T) {
+	InitForTesting(DEBUG)
+	SetBackend(NewNullBackend())
+
+	log := GetOrCreateLogger("test")
+	for _, level := range AllLevels {
+		if log.IsEnabledFor(level) {
+			t.Errorf("%s enabled", level)
+		}
+	}
+
+	var count int
+	log.Criticalf("%s", countStringer{&count})
+	log.Debug(countStringer{&count})
+	if count != 0 {
+		t.Errorf("message formatted %d times", count)
+	}
+}
+
+func BenchmarkLogNullBackend(b *testing.B) {
+	SetBackend(NewNullBackend())
+	RunLogBenchmark(b)
+}
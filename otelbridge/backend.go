@@ -0,0 +1,160 @@
+// Package otelbridge provides a backend which exports the records as
+// OpenTelemetry log records. It's a separate module, so the OpenTelemetry SDK
+// isn't a dependency of the logging package.
+package otelbridge
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// Options configures NewOTelBackend.
+type Options struct {
+	// Batch configures the batch processor, eg. its export interval and
+	// maximum batch size.
+	Batch []sdklog.BatchProcessorOption
+	// Provider adds options to the logger provider, eg. the resource.
+	Provider []sdklog.LoggerProviderOption
+}
+
+// OTelBackend converts the records into OpenTelemetry log records and emits
+// them into a logger provider, whose batch processor sends them to the
+// exporter. The module of the records is the instrumentation scope.
+type OTelBackend struct {
+	provider *sdklog.LoggerProvider
+	loggers  sync.Map
+}
+
+// NewOTelBackend creates a backend which exports the records with exporter,
+// eg. the otlploggrpc exporter of an OpenTelemetry collector. The records are
+// batched by the batch processor of the SDK, which exports them
+// periodically, when the batch is full and on Flush or Close.
+func NewOTelBackend(exporter sdklog.Exporter, options ...Options) *OTelBackend {
+	var opts Options
+	for _, opts = range options {
+	}
+	providerOpts := append([]sdklog.LoggerProviderOption{
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter, opts.Batch...)),
+	}, opts.Provider...)
+	return &OTelBackend{provider: sdklog.NewLoggerProvider(providerOpts...)}
+}
+
+// ConvertLevel converts the Level to the OpenTelemetry severity.
+//
+//	CRITICAL  FATAL
+//	ERROR     ERROR
+//	WARNING   WARN
+//	NOTICE    INFO2
+//	INFO      INFO
+//	DEBUG     DEBUG
+func ConvertLevel(level logging.Level) otellog.Severity {
+	switch level {
+	case logging.CRITICAL:
+		return otellog.SeverityFatal
+	case logging.ERROR:
+		return otellog.SeverityError
+	case logging.WARNING:
+		return otellog.SeverityWarn
+	case logging.NOTICE:
+		return otellog.SeverityInfo2
+	case logging.INFO:
+		return otellog.SeverityInfo
+	}
+	return otellog.SeverityDebug
+}
+
+// logger returns the OpenTelemetry logger of module.
+func (b *OTelBackend) logger(module string) otellog.Logger {
+	if l, ok := b.loggers.Load(module); ok {
+		return l.(otellog.Logger)
+	}
+	l, _ := b.loggers.LoadOrStore(module, b.provider.Logger(module))
+	return l.(otellog.Logger)
+}
+
+// Log implements the logging.Backend interface. The message is the body, and
+// the fields and the caller are the attributes, the caller as the
+// code.filepath, code.lineno and code.function attributes.
+func (b *OTelBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	var r otellog.Record
+	r.SetTimestamp(rec.Time)
+	r.SetObservedTimestamp(time.Now())
+	r.SetSeverity(ConvertLevel(level))
+	r.SetSeverityText(level.String())
+	r.SetBody(otellog.StringValue(rec.Message()))
+
+	for _, field := range rec.Fields {
+		r.AddAttributes(otellog.KeyValue{Key: field.Key, Value: convertValue(field.Value)})
+	}
+
+	file, line, fn, ok := rec.Caller()
+	if !ok {
+		var pc uintptr
+		if pc, file, line, ok = runtime.Caller(calldepth + 1); ok {
+			if f := runtime.FuncForPC(pc); f != nil {
+				fn = f.Name()
+			}
+		}
+	}
+	if ok {
+		r.AddAttributes(
+			otellog.String("code.filepath", file),
+			otellog.Int("code.lineno", line),
+			otellog.String("code.function", fn),
+		)
+	}
+
+	b.logger(rec.Module).Emit(context.Background(), r)
+	return nil
+}
+
+// convertValue converts a field value to an attribute value. The Redactor
+// values are redacted and the types without attribute value are formatted
+// with fmt.Sprint.
+func convertValue(v interface{}) otellog.Value {
+	if redactor, ok := v.(logging.Redactor); ok {
+		v = redactor.Redacted()
+	}
+	switch v := v.(type) {
+	case string:
+		return otellog.StringValue(v)
+	case bool:
+		return otellog.BoolValue(v)
+	case int:
+		return otellog.IntValue(v)
+	case int64:
+		return otellog.Int64Value(v)
+	case int32:
+		return otellog.Int64Value(int64(v))
+	case float64:
+		return otellog.Float64Value(v)
+	case float32:
+		return otellog.Float64Value(float64(v))
+	case []byte:
+		return otellog.BytesValue(v)
+	case error:
+		return otellog.StringValue(v.Error())
+	case nil:
+		return otellog.Value{}
+	}
+	return otellog.StringValue(fmt.Sprint(v))
+}
+
+// Flush implements the logging.Flusher interface, exporting the batched
+// records.
+func (b *OTelBackend) Flush() error {
+	return b.provider.ForceFlush(context.Background())
+}
+
+// Close exports the batched records and shuts the exporter down.
+func (b *OTelBackend) Close() error {
+	return b.provider.Shutdown(context.Background())
+}
@@ -0,0 +1,74 @@
+package otelbridge
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	"github.com/moisespsena-go/logging"
+)
+
+type memoryExporter struct {
+	mu       sync.Mutex
+	records  []sdklog.Record
+	shutdown bool
+}
+
+func (e *memoryExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, r := range records {
+		e.records = append(e.records, r.Clone())
+	}
+	return nil
+}
+
+func (e *memoryExporter) Shutdown(ctx context.Context) error {
+	e.shutdown = true
+	return nil
+}
+
+func (e *memoryExporter) ForceFlush(ctx context.Context) error {
+	return nil
+}
+
+func TestOTelBackend(t *testing.T) {
+	exporter := &memoryExporter{}
+	b := NewOTelBackend(exporter)
+
+	log := logging.NewLogger("otel")
+	log.SetBackend(logging.AddModuleLevel(b))
+	log.WithFields("user", "bob", "attempt", 2).Warning("login failed")
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !exporter.shutdown {
+		t.Errorf("exporter not shut down")
+	}
+	if len(exporter.records) != 1 {
+		t.Fatalf("unexpected records: %d", len(exporter.records))
+	}
+
+	r := exporter.records[0]
+	if r.Body().AsString() != "login failed" || r.Severity() != otellog.SeverityWarn || r.SeverityText() != "WARNING" {
+		t.Errorf("unexpected record: %s %s %s", r.Body(), r.Severity(), r.SeverityText())
+	}
+	if r.InstrumentationScope().Name != "otel" {
+		t.Errorf("unexpected scope: %s", r.InstrumentationScope().Name)
+	}
+	attrs := map[string]otellog.Value{}
+	r.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attrs[kv.Key] = kv.Value
+		return true
+	})
+	if attrs["user"].AsString() != "bob" || attrs["attempt"].AsInt64() != 2 {
+		t.Errorf("unexpected attributes: %v", attrs)
+	}
+	if attrs["code.lineno"].AsInt64() == 0 || attrs["code.function"].AsString() == "" {
+		t.Errorf("caller not added: %v", attrs)
+	}
+}
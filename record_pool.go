@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	recordPool    = sync.Pool{New: func() interface{} { return new(Record) }}
+	recordPooling int32
+)
+
+// SetRecordPooling enables or disables the reuse of the records created by
+// the loggers. When enabled, a record is reused once the backend chain returns,
+// so backends keeping it after Log returns must call Record.Retain, or keep a
+// Clone instead. The backends of this package already do so. Disabled by
+// default.
+func SetRecordPooling(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&recordPooling, v)
+}
+
+// newRecord returns an empty record, from the pool if it's enabled.
+func newRecord() *Record {
+	if atomic.LoadInt32(&recordPooling) == 0 {
+		return &Record{}
+	}
+	r := recordPool.Get().(*Record)
+	r.pooled = true
+	return r
+}
+
+// releaseRecord puts r back into the pool if it came from there and wasn't
+// retained.
+func releaseRecord(r *Record) {
+	if !r.pooled || atomic.LoadInt32(&r.retained) != 0 {
+		return
+	}
+	*r = Record{}
+	recordPool.Put(r)
+}
+
+// Retain marks the record as kept after the Log call which received it, so it
+// isn't reused by the record pool.
+func (r *Record) Retain() {
+	atomic.StoreInt32(&r.retained, 1)
+}
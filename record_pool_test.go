@@ -0,0 +1,42 @@
+package logging
+
+import "testing"
+
+func TestRecordPoolRetain(t *testing.T) {
+	SetRecordPooling(true)
+	defer SetRecordPooling(false)
+
+	backend := InitForTesting(DEBUG)
+	log := GetOrCreateLogger("test")
+	for i := 0; i < 100; i++ {
+		log.Infof("line %d", i)
+	}
+	lines := memoryRecords(backend)
+	if len(lines) != 100 || lines[0] != "line 0" || lines[99] != "line 99" {
+		t.Errorf("retained records were reused: %q", lines)
+	}
+}
+
+func TestRecordPoolRelease(t *testing.T) {
+	r := newRecord()
+	if r.pooled {
+		t.Errorf("record from disabled pool")
+	}
+
+	SetRecordPooling(true)
+	defer SetRecordPooling(false)
+	r = newRecord()
+	r.Module = "test"
+	r.Retain()
+	releaseRecord(r)
+	if r.Module != "test" {
+		t.Errorf("retained record was reset")
+	}
+
+	r = newRecord()
+	r.Module = "test"
+	releaseRecord(r)
+	if r.Module != "" {
+		t.Errorf("released record wasn't reset")
+	}
+}
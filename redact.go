@@ -0,0 +1,260 @@
+package logging
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// maxRedactDepth limits the nesting walked by the deep redaction, which also
+// protects it from cyclic values.
+const maxRedactDepth = 16
+
+var (
+	deepRedaction int32
+	redactorType  = reflect.TypeOf((*Redactor)(nil)).Elem()
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// SetDeepRedaction enables or disables the deep redaction of the record
+// arguments, done when the message, the %{message} verb of the formatters, is
+// built. When enabled, the structs, maps, slices, arrays and pointers passed
+// as arguments are walked and the values implementing Redactor, or the
+// exported struct fields tagged with `log:"redact"`, are redacted, and the
+// fields tagged with `log:"-"` are omitted:
+//
+//	type Creds struct {
+//		User  string
+//		Pass  string `log:"redact"`
+//		Token string `log:"-"`
+//	}
+//
+//	log.Infof("%+v", Creds{"bob", "secret", "abc"}) // {User:bob Pass:******}
+//
+// The arguments are copied when something is redacted, so the values of the
+// caller are never changed.
+//
+// Walking the arguments uses reflection, which costs a couple microseconds for
+// each struct argument, and copying them allocates, so it's disabled by
+// default. Only the Redactor arguments themselves are redacted then.
+func SetDeepRedaction(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&deepRedaction, v)
+}
+
+// RedactDeep returns v with the Redactor values and the fields tagged with
+// `log:"redact"` redacted, and without the fields tagged with `log:"-"`. If
+// there is nothing to change, v is returned as is, otherwise a copy is
+// returned. Unexported struct fields are not walked.
+//
+// The tagged string fields are replaced by Redact of their value, the other
+// tagged fields by their zero value. The structs without some field are
+// copied into new struct types, which don't have the methods and the
+// unexported fields of the original types.
+func RedactDeep(v interface{}) interface{} {
+	if rv, ok := redactValue(reflect.ValueOf(v), 0); ok {
+		return rv.Interface()
+	}
+	return v
+}
+
+// redactValue returns a copy of v with the sensitive values redacted and true,
+// or v and false if there is nothing to redact. The copy may have other type
+// than v.
+func redactValue(v reflect.Value, depth int) (reflect.Value, bool) {
+	if !v.IsValid() || depth > maxRedactDepth {
+		return v, false
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return v, false
+		}
+	}
+	if v.Type().Implements(redactorType) && v.CanInterface() {
+		return reflect.ValueOf(v.Interface().(Redactor).Redacted()), true
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if e, ok := redactValue(v.Elem(), depth+1); ok {
+			t := v.Type().Elem()
+			if fv, fits := fitValue(e, t); fits {
+				e = fv
+			} else {
+				t = e.Type()
+			}
+			p := reflect.New(t)
+			p.Elem().Set(e)
+			return p, true
+		}
+	case reflect.Interface:
+		return redactValue(v.Elem(), depth+1)
+	case reflect.Struct:
+		return redactStruct(v, depth)
+	case reflect.Map:
+		var (
+			t      = v.Type()
+			values = map[interface{}]reflect.Value{}
+			fits   = true
+		)
+		for _, key := range v.MapKeys() {
+			if ev, ok := redactValue(v.MapIndex(key), depth+1); ok {
+				if ev, ok = fitValue(ev, t.Elem()); !ok {
+					fits = false
+				}
+				values[key.Interface()] = ev
+			}
+		}
+		if len(values) == 0 {
+			return v, false
+		}
+		if !fits {
+			t = reflect.MapOf(t.Key(), interfaceType)
+		}
+		c := reflect.MakeMapWithSize(t, v.Len())
+		for _, key := range v.MapKeys() {
+			ev, ok := values[key.Interface()]
+			if !ok {
+				ev = v.MapIndex(key)
+			}
+			c.SetMapIndex(key, ev)
+		}
+		return c, true
+	case reflect.Slice, reflect.Array:
+		var (
+			t      = v.Type()
+			values = map[int]reflect.Value{}
+			fits   = true
+		)
+		for i := 0; i < v.Len(); i++ {
+			if ev, ok := redactValue(v.Index(i), depth+1); ok {
+				if ev, ok = fitValue(ev, t.Elem()); !ok {
+					fits = false
+				}
+				values[i] = ev
+			}
+		}
+		if len(values) == 0 {
+			return v, false
+		}
+		var c reflect.Value
+		switch {
+		case !fits:
+			c = reflect.MakeSlice(reflect.SliceOf(interfaceType), v.Len(), v.Len())
+		case t.Kind() == reflect.Slice:
+			c = reflect.MakeSlice(t, v.Len(), v.Len())
+		default:
+			c = reflect.New(t).Elem()
+		}
+		for i := 0; i < v.Len(); i++ {
+			ev, ok := values[i]
+			if !ok {
+				ev = v.Index(i)
+			}
+			c.Index(i).Set(ev)
+		}
+		return c, true
+	}
+	return v, false
+}
+
+// redactStruct returns a copy of the struct v with the tagged fields redacted
+// or omitted. If a field is omitted or its redacted value doesn't fit its type,
+// the copy is a new struct type with only the exported fields of v.
+func redactStruct(v reflect.Value, depth int) (reflect.Value, bool) {
+	var (
+		t       = v.Type()
+		values  = make([]reflect.Value, t.NumField())
+		changed bool
+		fits    = true
+	)
+	for i := range values {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		var (
+			fv reflect.Value
+			ok bool
+		)
+		switch field.Tag.Get("log") {
+		case "-":
+			changed, fits = true, false
+			continue
+		case "redact":
+			fv, ok = redactTagged(v.Field(i)), true
+		default:
+			fv, ok = redactValue(v.Field(i), depth+1)
+		}
+		if !ok {
+			values[i] = v.Field(i)
+			continue
+		}
+		changed = true
+		if values[i], ok = fitValue(fv, field.Type); !ok {
+			fits = false
+		}
+	}
+	if !changed {
+		return v, false
+	}
+
+	if fits {
+		c := reflect.New(t).Elem()
+		c.Set(v)
+		for i, fv := range values {
+			if fv.IsValid() {
+				c.Field(i).Set(fv)
+			}
+		}
+		return c, true
+	}
+
+	var fields []reflect.StructField
+	for i, fv := range values {
+		if fv.IsValid() {
+			field := t.Field(i)
+			fields = append(fields, reflect.StructField{Name: field.Name, Type: fv.Type(), Tag: field.Tag})
+		}
+	}
+	c := reflect.New(reflect.StructOf(fields)).Elem()
+	var j int
+	for _, fv := range values {
+		if fv.IsValid() {
+			c.Field(j).Set(fv)
+			j++
+		}
+	}
+	return c, true
+}
+
+// redactTagged returns the redacted value of a field tagged with
+// `log:"redact"`.
+func redactTagged(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.String {
+		return reflect.ValueOf(Redact(v.String()))
+	}
+	if v.Type().Implements(redactorType) {
+		if rv, ok := redactValue(v, 0); ok {
+			return rv
+		}
+	}
+	return reflect.Zero(v.Type())
+}
+
+// fitValue returns v as a value of type t and true, or v and false if it can't
+// be converted.
+func fitValue(v reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	switch {
+	case !v.IsValid():
+		return reflect.Zero(t), true
+	case v.Type().AssignableTo(t):
+		return v, true
+	case v.Kind() == t.Kind() && v.Type().ConvertibleTo(t):
+		return v.Convert(t), true
+	}
+	return v, false
+}
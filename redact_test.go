@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type redactCreds struct {
+	User  string
+	Pass  string `log:"redact"`
+	Token Password
+	Pin   int `log:"redact"`
+	note  Password
+}
+
+type redactRequest struct {
+	Creds   *redactCreds
+	Headers map[string]Password
+	Keys    []interface{}
+}
+
+func TestDeepRedaction(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	log := MustGetLogger("test")
+
+	creds := &redactCreds{User: "bob", Pass: "secret", Token: "abc", Pin: 1234}
+	req := redactRequest{
+		Creds:   creds,
+		Headers: map[string]Password{"Authorization": "Bearer"},
+		Keys:    []interface{}{Password("key"), 1},
+	}
+
+	log.Infof("%+v", creds)
+	if msg := MemoryRecordN(backend, 0).Message(); msg != "&{User:bob Pass:secret Token:abc Pin:1234 note:}" {
+		t.Errorf("unexpected message without deep redaction: %s", msg)
+	}
+
+	SetDeepRedaction(true)
+	defer SetDeepRedaction(false)
+
+	log.Infof("%+v", creds)
+	if msg := MemoryRecordN(backend, 1).Message(); msg != "&{User:bob Pass:****** Token:*** Pin:0 note:}" {
+		t.Errorf("unexpected message: %s", msg)
+	}
+	log.Infof("%v %v %v", req.Creds.User, req.Headers, req.Keys)
+	if msg := MemoryRecordN(backend, 2).Message(); msg != "bob map[Authorization:******] [*** 1]" {
+		t.Errorf("unexpected message: %s", msg)
+	}
+
+	if creds.Pass != "secret" || creds.Token != "abc" || creds.Pin != 1234 ||
+		req.Headers["Authorization"] != "Bearer" || req.Keys[0] != Password("key") {
+		t.Errorf("the logged values were changed: %+v %+v", creds, req)
+	}
+}
+
+func TestRedactDeepUnchanged(t *testing.T) {
+	v := &redactRequest{}
+	if RedactDeep(v) != v {
+		t.Error("expected the same value when there is nothing to redact")
+	}
+}
+
+type redactAccount struct {
+	Name   string
+	Creds  *redactCreds
+	Nested struct {
+		Secret string `log:"-"`
+		Public string
+	}
+	Hidden *redactCreds `log:"-"`
+}
+
+func TestRedactDeepTags(t *testing.T) {
+	account := redactAccount{Name: "acme", Creds: &redactCreds{User: "bob", Pass: "secret"}}
+	account.Nested.Secret = "hidden"
+	account.Nested.Public = "shown"
+	account.Hidden = account.Creds
+
+	if s := fmt.Sprintf("%+v", RedactDeep(account)); !strings.HasPrefix(s, "{Name:acme Creds:0x") ||
+		!strings.HasSuffix(s, " Nested:{Public:shown}}") {
+		t.Errorf("unexpected value: %s", s)
+	}
+	redacted := reflect.ValueOf(RedactDeep(&account)).Elem()
+	if s := fmt.Sprintf("%+v", redacted.FieldByName("Creds").Interface()); s != "&{User:bob Pass:****** Token: Pin:0 note:}" {
+		t.Errorf("unexpected creds: %s", s)
+	}
+	if s := fmt.Sprintf("%+v", redacted.FieldByName("Nested").Interface()); s != "{Public:shown}" {
+		t.Errorf("unexpected nested struct: %s", s)
+	}
+	if redacted.FieldByName("Hidden").IsValid() {
+		t.Error("the field tagged with log:\"-\" wasn't omitted")
+	}
+	if account.Nested.Secret != "hidden" || account.Creds.Pass != "secret" {
+		t.Errorf("the value was changed: %+v", account)
+	}
+}
+
+func BenchmarkRedactDeep(b *testing.B) {
+	creds := &redactCreds{User: "bob", Pass: "secret", Token: "abc"}
+	for i := 0; i < b.N; i++ {
+		RedactDeep(creds)
+	}
+}
@@ -0,0 +1,110 @@
+package logging
+
+import "strings"
+
+// routingBackend passes each record to the backend of its module.
+type routingBackend struct {
+	def    LeveledBackend
+	routes map[string]LeveledBackend
+}
+
+// NewRoutingBackend creates a backend which passes the records of the modules
+// in routes, or of their child modules, to the backend of the route, and the
+// other records to defaultBackend. The longest matching route is used, where
+// the modules are separated by '/' or '.', eg. the route "audit" matches the
+// modules "audit", "audit/login" and "audit.db".
+//
+// The backends are wrapped with AddModuleLevel, and the routing backend is a
+// LeveledBackend: the level of a module is set in the backend of its route,
+// and the default level, of the empty module, is set in all backends. The
+// returned backend implements Printer if defaultBackend does.
+func NewRoutingBackend(defaultBackend Backend, routes map[string]Backend) LeveledBackend {
+	b := &routingBackend{
+		def:    AddModuleLevel(defaultBackend),
+		routes: make(map[string]LeveledBackend, len(routes)),
+	}
+	for module, backend := range routes {
+		b.routes[module] = AddModuleLevel(backend)
+	}
+	if _, ok := defaultBackend.(Printer); ok {
+		return &routingPrinter{b}
+	}
+	return b
+}
+
+// route returns the backend of module.
+func (b *routingBackend) route(module string) LeveledBackend {
+	for {
+		if backend, ok := b.routes[module]; ok {
+			return backend
+		}
+		i := strings.LastIndexAny(module, "/.")
+		if i < 0 {
+			return b.def
+		}
+		module = module[:i]
+	}
+}
+
+// Log implements the Backend interface.
+func (b *routingBackend) Log(level Level, calldepth int, rec *Record) error {
+	return b.route(rec.Module).Log(level, calldepth+1, rec)
+}
+
+// GetLevel returns the level of module in the backend of its route.
+func (b *routingBackend) GetLevel(module string) Level {
+	return b.route(module).GetLevel(module)
+}
+
+// SetLevel sets the level of module in the backend of its route. The default
+// level, of the empty module, is set in all backends.
+func (b *routingBackend) SetLevel(level Level, module string) {
+	if module != "" {
+		b.route(module).SetLevel(level, module)
+		return
+	}
+	b.def.SetLevel(level, module)
+	for _, backend := range b.routes {
+		backend.SetLevel(level, module)
+	}
+}
+
+// IsEnabledFor returns true if the backend of the module route is enabled for
+// level.
+func (b *routingBackend) IsEnabledFor(level Level, module string) bool {
+	return b.route(module).IsEnabledFor(level, module)
+}
+
+// PrintLevel implements the LevelPrinter interface, printing into the default
+// backend.
+func (b *routingBackend) PrintLevel(level Level, args ...interface{}) error {
+	return b.Log(level, 1, PrintRecord(level, args...))
+}
+
+// Flush implements the Flusher interface, flushing all backends.
+func (b *routingBackend) Flush() error {
+	err := FlushBackend(b.def)
+	for _, backend := range b.routes {
+		if e := FlushBackend(backend); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (b *routingBackend) wrappedBackends() []Backend {
+	backends := []Backend{b.def}
+	for _, backend := range b.routes {
+		backends = append(backends, backend)
+	}
+	return backends
+}
+
+type routingPrinter struct {
+	*routingBackend
+}
+
+// Print implements the Printer interface, printing into the default backend.
+func (b *routingPrinter) Print(args ...interface{}) error {
+	return b.def.(Printer).Print(args...)
+}
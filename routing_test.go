@@ -0,0 +1,28 @@
+package logging
+
+import "testing"
+
+func TestRoutingBackend(t *testing.T) {
+	InitForTesting(DEBUG)
+	app := NewMemoryBackend(8)
+	audit := NewMemoryBackend(8)
+	SetBackend(NewRoutingBackend(app, map[string]Backend{"audit": audit}))
+	SetLevel(INFO, "")
+	SetLevel(WARNING, "audit/login")
+
+	GetOrCreateLogger("audit").Info("audit")
+	GetOrCreateLogger("audit/login").Info("ignored")
+	GetOrCreateLogger("audit/login").Warning("login")
+	GetOrCreateLogger("auditor").Info("app")
+	GetOrCreateLogger("app").Debug("ignored")
+
+	if lines := memoryRecords(audit); len(lines) != 2 || lines[0] != "audit" || lines[1] != "login" {
+		t.Errorf("unexpected audit records: %q", lines)
+	}
+	if lines := memoryRecords(app); len(lines) != 1 || lines[0] != "app" {
+		t.Errorf("unexpected app records: %q", lines)
+	}
+	if GetLevel("audit/login") != WARNING || GetLevel("audit") != INFO {
+		t.Errorf("unexpected levels")
+	}
+}
@@ -0,0 +1,256 @@
+package logging
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// SamplingOptions configures the sampling backend.
+type SamplingOptions struct {
+	// Every logs 1 of every n identical records. Values lower than 2 disable
+	// this sampling.
+	Every int
+
+	// Rate is the number of records per second allowed for each module and
+	// level, using a token bucket of Burst size. Zero disables rate limiting.
+	Rate  float64
+	Burst int
+
+	// ByFormat identifies identical records by the format string instead of
+	// the formatted message. Records logged without format are always
+	// identified by the message.
+	ByFormat bool
+
+	// SummaryInterval is the interval of the "suppressed N messages" records.
+	// They are logged by the next record after the interval, or by a timer
+	// when no record follows. Defaults to one minute.
+	SummaryInterval time.Duration
+}
+
+// Every returns SamplingOptions which logs 1 of every n identical records.
+func Every(n int) SamplingOptions {
+	return SamplingOptions{Every: n}
+}
+
+type samplingKey struct {
+	module  string
+	level   Level
+	message string
+	format  bool
+}
+
+type samplingBucketKey struct {
+	module string
+	level  Level
+}
+
+type samplingState struct {
+	seen       uint64
+	suppressed uint64
+	formatter  Formatter
+}
+
+type samplingBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// samplingBackend drops repetitive records and periodically logs how many
+// records were suppressed.
+type samplingBackend struct {
+	inner       Backend
+	opts        SamplingOptions
+	mu          sync.Mutex
+	states      map[samplingKey]*samplingState
+	buckets     map[samplingBucketKey]*samplingBucket
+	lastSummary time.Time
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// SamplingBackend is a Backend which drops repetitive records.
+type SamplingBackend interface {
+	Backend
+	// Flush logs the summary of the suppressed records and flushes the inner
+	// backend.
+	Flush() error
+	// Close stops the timer of the summaries, logs the pending ones and
+	// closes the inner backend if it's an io.Closer.
+	Close() error
+}
+
+// NewSamplingBackend creates a backend which drops or rate limits repetitive
+// records before passing them to inner. A summary record with the number of
+// suppressed records is logged once per SummaryInterval, even if no other
+// record is logged, so nothing is silently lost. The summaries are logged by a
+// goroutine stopped by Close.
+func NewSamplingBackend(inner Backend, opts SamplingOptions) SamplingBackend {
+	if opts.SummaryInterval == 0 {
+		opts.SummaryInterval = time.Minute
+	}
+	if opts.Rate > 0 && opts.Burst < 1 {
+		opts.Burst = 1
+	}
+	b := &samplingBackend{
+		inner:       inner,
+		opts:        opts,
+		states:      map[samplingKey]*samplingState{},
+		buckets:     map[samplingBucketKey]*samplingBucket{},
+		lastSummary: timeNow(),
+		stop:        make(chan struct{}),
+	}
+	go b.run(time.NewTicker(opts.SummaryInterval))
+	return b
+}
+
+// run logs the pending summaries on each tick, so the ones of a burst followed
+// by silence aren't lost, until Close.
+func (b *samplingBackend) run(ticker *time.Ticker) {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.mu.Lock()
+			summaries := b.summaries(timeNow())
+			b.mu.Unlock()
+			if err := b.logSummaries(1, summaries); err != nil {
+				HandleError(err)
+			}
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// logSummaries passes the summary records to the inner backend.
+func (b *samplingBackend) logSummaries(calldepth int, summaries []*Record) (err error) {
+	for _, summary := range summaries {
+		if e := b.inner.Log(summary.Level, calldepth+1, summary); e != nil {
+			err = e
+		}
+	}
+	return
+}
+
+func (b *samplingBackend) key(rec *Record) samplingKey {
+	key := samplingKey{module: rec.Module, level: rec.Level}
+	if b.opts.ByFormat && rec.fmt != nil {
+		key.message, key.format = *rec.fmt, true
+	} else {
+		key.message = rec.Message()
+	}
+	return key
+}
+
+// allow reports whether the token bucket of the module and level has a token.
+func (b *samplingBackend) allow(rec *Record, now time.Time) bool {
+	key := samplingBucketKey{rec.Module, rec.Level}
+	bucket, ok := b.buckets[key]
+	if !ok {
+		bucket = &samplingBucket{float64(b.opts.Burst), now}
+		b.buckets[key] = bucket
+	} else {
+		bucket.tokens += now.Sub(bucket.last).Seconds() * b.opts.Rate
+		if bucket.tokens > float64(b.opts.Burst) {
+			bucket.tokens = float64(b.opts.Burst)
+		}
+		bucket.last = now
+	}
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// summaries returns the summary records and resets the suppressed counters.
+func (b *samplingBackend) summaries(now time.Time) (records []*Record) {
+	b.lastSummary = now
+	for key, state := range b.states {
+		if state.suppressed > 0 {
+			format := "suppressed %d messages: %s"
+			formatter := state.formatter
+			if formatter == nil {
+				formatter = GetFormatter()
+			}
+			r := &Record{
+				Time:      now,
+				Module:    key.module,
+				Level:     key.level,
+				Args:      []interface{}{state.suppressed, key.message},
+				fmt:       &format,
+				formatter: formatter,
+			}
+			r.setID()
+			records = append(records, r)
+		}
+		delete(b.states, key)
+	}
+	return
+}
+
+// Log implements the Backend interface.
+func (b *samplingBackend) Log(level Level, calldepth int, rec *Record) (err error) {
+	now := timeNow()
+	key := b.key(rec)
+
+	b.mu.Lock()
+	state, ok := b.states[key]
+	if !ok {
+		state = &samplingState{}
+		b.states[key] = state
+	}
+	state.seen++
+
+	pass := b.opts.Every < 2 || (state.seen-1)%uint64(b.opts.Every) == 0
+	if pass && b.opts.Rate > 0 {
+		pass = b.allow(rec, now)
+	}
+	if !pass {
+		state.suppressed++
+		state.formatter = rec.formatter
+	}
+
+	var summaries []*Record
+	if now.Sub(b.lastSummary) >= b.opts.SummaryInterval {
+		summaries = b.summaries(now)
+	}
+	b.mu.Unlock()
+
+	err = b.logSummaries(calldepth+1, summaries)
+	if pass {
+		if e := b.inner.Log(level, calldepth+1, rec); e != nil {
+			err = e
+		}
+	}
+	return
+}
+
+// Flush logs the summary of the suppressed records and flushes the inner
+// backend.
+func (b *samplingBackend) Flush() (err error) {
+	b.mu.Lock()
+	summaries := b.summaries(timeNow())
+	b.mu.Unlock()
+
+	err = b.logSummaries(1, summaries)
+	if e := FlushBackend(b.inner); e != nil {
+		err = e
+	}
+	return
+}
+
+// Close implements the SamplingBackend interface.
+func (b *samplingBackend) Close() (err error) {
+	b.stopOnce.Do(func() {
+		close(b.stop)
+	})
+	err = b.Flush()
+	if c, ok := b.inner.(io.Closer); ok {
+		if e := c.Close(); e != nil {
+			err = e
+		}
+	}
+	return
+}
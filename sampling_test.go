@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func memoryRecords(b *MemoryBackend) (lines []string) {
+	for node := b.Head(); node != nil; node = node.Next() {
+		lines = append(lines, node.Record.Formatted(0))
+	}
+	return
+}
+
+func TestSamplingBackendEvery(t *testing.T) {
+	InitForTesting(DEBUG)
+	memory := NewMemoryBackend(64)
+	sampling := NewSamplingBackend(memory, Every(3))
+	defer sampling.Close()
+	SetBackend(sampling)
+
+	log := GetOrCreateLogger("test")
+	for i := 0; i < 7; i++ {
+		log.Error("flood")
+	}
+	log.Error("other")
+	sampling.Flush()
+
+	expected := []string{"flood", "flood", "flood", "other", "suppressed 4 messages: flood"}
+	lines := memoryRecords(memory)
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected records: %q", lines)
+	}
+	for i, e := range expected {
+		if lines[i] != e {
+			t.Errorf("%d: %q != %q", i, lines[i], e)
+		}
+	}
+}
+
+func TestSamplingBackendRate(t *testing.T) {
+	InitForTesting(DEBUG)
+	now := time.Unix(0, 0)
+	SetTimeNow(func() time.Time { return now })
+
+	memory := NewMemoryBackend(64)
+	sampling := NewSamplingBackend(memory, SamplingOptions{
+		Rate:            1,
+		Burst:           2,
+		ByFormat:        true,
+		SummaryInterval: 10 * time.Second,
+	})
+	defer sampling.Close()
+	SetBackend(sampling)
+
+	log := GetOrCreateLogger("test")
+	for i := 0; i < 5; i++ {
+		log.Errorf("flood %d", i)
+	}
+	now = now.Add(time.Second)
+	log.Errorf("flood %d", 5)
+	log.Errorf("flood %d", 6)
+	now = now.Add(10 * time.Second)
+	log.Errorf("flood %d", 7)
+
+	expected := []string{"flood 0", "flood 1", "flood 5", "suppressed 4 messages: flood %d", "flood 7"}
+	lines := memoryRecords(memory)
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected records: %q", lines)
+	}
+	for i, e := range expected {
+		if lines[i] != e {
+			t.Errorf("%d: %q != %q", i, lines[i], e)
+		}
+	}
+}
+
+// messagesBackend keeps the messages of the records logged by any goroutine.
+type messagesBackend struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (b *messagesBackend) Log(level Level, calldepth int, rec *Record) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages = append(b.messages, rec.Message())
+	return nil
+}
+
+func (b *messagesBackend) get() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.messages...)
+}
+
+func TestSamplingBackendSilence(t *testing.T) {
+	InitForTesting(DEBUG)
+	messages := &messagesBackend{}
+	sampling := NewSamplingBackend(messages, SamplingOptions{Every: 3, SummaryInterval: 20 * time.Millisecond})
+	SetBackend(sampling)
+
+	// a burst followed by silence
+	log := GetOrCreateLogger("test")
+	for i := 0; i < 5; i++ {
+		log.Error("flood")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(messages.get()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if lines := messages.get(); len(lines) != 3 || lines[2] != "suppressed 3 messages: flood" {
+		t.Fatalf("unexpected records: %q", lines)
+	}
+
+	if err := sampling.Close(); err != nil {
+		t.Fatal(err)
+	}
+	log.Error("flood")
+	log.Error("flood")
+	time.Sleep(50 * time.Millisecond)
+	if lines := messages.get(); len(lines) != 4 {
+		t.Errorf("summary logged after Close: %q", lines)
+	}
+}
@@ -0,0 +1,110 @@
+//go:build go1.21
+// +build go1.21
+
+// Package slogbridge provides a log/slog Handler which writes the records into
+// a logging.Logger.
+package slogbridge
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/moisespsena-go/logging"
+)
+
+// Handler is a slog.Handler which forwards records to a logging.Logger. The
+// record attributes are added as structured fields.
+type Handler struct {
+	logger logging.Logger
+	fields logging.Fields
+	group  string
+}
+
+// NewHandler creates a new slog.Handler which writes into logger.
+func NewHandler(logger logging.Logger) slog.Handler {
+	return &Handler{logger: logger}
+}
+
+// ConvertLevel converts the slog level to the logging Level.
+//
+//	level < Info      DEBUG
+//	level < Info+2    INFO
+//	level < Warn      NOTICE
+//	level < Error     WARNING
+//	level < Error+4   ERROR
+//	otherwise         CRITICAL
+func ConvertLevel(level slog.Level) logging.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return logging.DEBUG
+	case level < slog.LevelInfo+2:
+		return logging.INFO
+	case level < slog.LevelWarn:
+		return logging.NOTICE
+	case level < slog.LevelError:
+		return logging.WARNING
+	case level < slog.LevelError+4:
+		return logging.ERROR
+	}
+	return logging.CRITICAL
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.IsEnabledFor(ConvertLevel(level))
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	fields := h.fields.Copy()
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, h.group, a)
+		return true
+	})
+
+	w := h.logger.Writer()
+	if fw, ok := w.(logging.FieldsLogWriter); ok {
+		fw.WriteFields(ConvertLevel(r.Level), 1, fields, nil, r.Message)
+	} else {
+		w.Write(ConvertLevel(r.Level), 1, nil, r.Message)
+	}
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.fields = h.fields.Copy()
+	for _, a := range attrs {
+		h2.fields = appendAttr(h2.fields, h.group, a)
+	}
+	return &h2
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.group = h.group + name + "."
+	return &h2
+}
+
+func appendAttr(fields logging.Fields, group string, a slog.Attr) logging.Fields {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		prefix := group
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			fields = appendAttr(fields, prefix, ga)
+		}
+		return fields
+	}
+	return append(fields, logging.Field{Key: group + a.Key, Value: a.Value.Any()})
+}
@@ -0,0 +1,71 @@
+//go:build go1.21
+// +build go1.21
+
+package slogbridge
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/moisespsena-go/logging"
+)
+
+func TestHandler(t *testing.T) {
+	backend := logging.InitForTesting(logging.DEBUG)
+	logging.SetFormatter(logging.MustStringFormatter("%{level} %{message} %{fields}"))
+
+	log := slog.New(NewHandler(logging.GetOrCreateLogger("slog")))
+	log.With("a", 1).WithGroup("g").Info("hello", "b", 2, slog.Group("c", "d", 3))
+	log.Error("failed")
+
+	expected := []string{
+		"INFO hello a=1 g.b=2 g.c.d=3",
+		"ERROR failed ",
+	}
+	node := backend.Head()
+	for i, e := range expected {
+		if node == nil {
+			t.Fatalf("%d: record not found", i)
+		}
+		if line := node.Record.Formatted(0); e != line {
+			t.Errorf("%d: %q != %q", i, line, e)
+		}
+		if node.Record.Module != "slog" {
+			t.Errorf("%d: unexpected module %q", i, node.Record.Module)
+		}
+		node = node.Next()
+	}
+}
+
+func TestHandlerEnabled(t *testing.T) {
+	logging.InitForTesting(logging.DEBUG)
+	logging.SetLevel(logging.WARNING, "slog-enabled")
+
+	h := NewHandler(logging.GetOrCreateLogger("slog-enabled"))
+	if h.Enabled(context.Background(), slog.LevelInfo) {
+		t.Errorf("info should be disabled")
+	}
+	if !h.Enabled(context.Background(), slog.LevelWarn) {
+		t.Errorf("warn should be enabled")
+	}
+}
+
+func TestConvertLevel(t *testing.T) {
+	tests := []struct {
+		level    slog.Level
+		expected logging.Level
+	}{
+		{slog.LevelDebug, logging.DEBUG},
+		{slog.LevelInfo, logging.INFO},
+		{slog.LevelInfo + 2, logging.NOTICE},
+		{slog.LevelWarn, logging.WARNING},
+		{slog.LevelError, logging.ERROR},
+		{slog.LevelError + 4, logging.CRITICAL},
+	}
+	for _, test := range tests {
+		if level := ConvertLevel(test.level); level != test.expected {
+			t.Errorf("%s: %s != %s", test.level, level, test.expected)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package logging
+
+// levelSplitBackend routes each record to one of two backends based on its
+// level.
+type levelSplitBackend struct {
+	threshold Level
+	high      Backend
+	low       Backend
+}
+
+// NewLevelSplitBackend creates a backend which passes the records with level
+// threshold or more severe to high and the others to low. For example, to log
+// WARNING and above into stderr and the rest into stdout:
+//
+//	NewLevelSplitBackend(WARNING, NewLogBackend(os.Stderr, "", 0), NewLogBackend(os.Stdout, "", 0))
+//
+// The returned backend implements Printer if both backends do. Printed values
+// have no level, so they are passed to low.
+func NewLevelSplitBackend(threshold Level, high Backend, low Backend) Backend {
+	b := &levelSplitBackend{threshold, high, low}
+	if _, ok := high.(Printer); ok {
+		if _, ok = low.(Printer); ok {
+			return &levelSplitPrinter{b}
+		}
+	}
+	return b
+}
+
+// Log implements the Backend interface.
+func (b *levelSplitBackend) Log(level Level, calldepth int, rec *Record) error {
+	if level <= b.threshold {
+		return b.high.Log(level, calldepth+1, rec)
+	}
+	return b.low.Log(level, calldepth+1, rec)
+}
+
+// Flush implements the Flusher interface, flushing both backends.
+func (b *levelSplitBackend) Flush() error {
+	err := FlushBackend(b.high)
+	if e := FlushBackend(b.low); err == nil {
+		err = e
+	}
+	return err
+}
+
+func (b *levelSplitBackend) wrappedBackends() []Backend {
+	return []Backend{b.high, b.low}
+}
+
+type levelSplitPrinter struct {
+	*levelSplitBackend
+}
+
+// Print implements the Printer interface.
+func (b *levelSplitPrinter) Print(args ...interface{}) error {
+	return b.low.(Printer).Print(args...)
+}
@@ -0,0 +1,21 @@
+package logging
+
+import "testing"
+
+func TestLevelSplitBackend(t *testing.T) {
+	InitForTesting(DEBUG)
+	high := NewMemoryBackend(8)
+	low := NewMemoryBackend(8)
+	SetBackend(NewLevelSplitBackend(WARNING, high, low))
+
+	log := GetOrCreateLogger("test")
+	log.Notice("notice")
+	log.Error("error")
+
+	if lines := memoryRecords(low); len(lines) != 1 || lines[0] != "notice" {
+		t.Errorf("unexpected low records: %q", lines)
+	}
+	if lines := memoryRecords(high); len(lines) != 1 || lines[0] != "error" {
+		t.Errorf("unexpected high records: %q", lines)
+	}
+}
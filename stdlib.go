@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"os"
+)
+
+// RedirectStdlibLog sends the lines of the standard library default logger,
+// used by the log.Print functions, into logger using level. The flags of the
+// standard logger are set to zero to avoid double timestamps, and if some
+// code sets the date or time flags again, they are removed from the lines.
+// The prefix of the standard logger is kept in the message.
+//
+// It returns a function which restores the flags of the standard logger and
+// its default output, os.Stderr.
+func RedirectStdlibLog(logger Logger, level Level) (restore func()) {
+	flags := log.Flags()
+	// Skip the frames of Write, logLine and the standard logger.
+	w := &ioWriter{log: addCalldepth(logger, 4), level: level, trim: trimStdlibHeader}
+	log.SetOutput(w)
+	log.SetFlags(0)
+	return func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+	}
+}
+
+// trimStdlibHeader removes the date and time written by the standard logger
+// using its current flags.
+func trimStdlibHeader(line []byte) []byte {
+	flags := log.Flags()
+	if flags&(log.Ldate|log.Ltime|log.Lmicroseconds) == 0 {
+		return line
+	}
+	var prefix []byte
+	if p := log.Prefix(); p != "" {
+		if !bytes.HasPrefix(line, []byte(p)) {
+			return line
+		}
+		prefix, line = []byte(p), line[len(p):]
+	}
+	var n int
+	if flags&log.Ldate != 0 {
+		n += len("2006/01/02 ")
+	}
+	if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		n += len("15:04:05 ")
+		if flags&log.Lmicroseconds != 0 {
+			n += len(".000000")
+		}
+	}
+	if len(line) < n {
+		return append(prefix, line...)
+	}
+	return append(prefix, line[n:]...)
+}
@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"log"
+	"path/filepath"
+	"testing"
+)
+
+func TestRedirectStdlibLog(t *testing.T) {
+	backend := InitForTesting(DEBUG)
+	restore := RedirectStdlibLog(NewLogger("stdlib"), NOTICE)
+	defer restore()
+
+	if log.Flags() != 0 {
+		t.Errorf("unexpected flags %d", log.Flags())
+	}
+	log.Printf("plain")
+
+	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
+	log.SetPrefix("dep: ")
+	defer log.SetPrefix("")
+	log.Print("with header")
+
+	for i, expected := range []string{"plain", "dep: with header"} {
+		rec := MemoryRecordN(backend, i)
+		if rec == nil || rec.Level != NOTICE || rec.Module != "stdlib" || rec.Message() != expected {
+			t.Errorf("%d: unexpected record %v, expected %q", i, rec, expected)
+		} else if file, _, _, _ := rec.Caller(); filepath.Base(file) != "stdlib_test.go" {
+			t.Errorf("%d: unexpected caller %s", i, file)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// teeBackend writes the formatted records into a writer before passing them
+// to the primary backend.
+type teeBackend struct {
+	primary   Backend
+	formatter Formatter
+	mu        sync.Mutex
+	w         io.Writer
+}
+
+// NewTeeBackend creates a backend which writes each record, formatted by
+// formatter and followed by a newline, into w and then passes it to primary.
+// It's meant for debugging and tests, eg. to dump the records of a backend
+// into a buffer. If formatter is nil, the formatter of the record is used.
+//
+// The errors writing into w are passed to the default error handler (see
+// SetErrorHandler), so they don't affect primary. The returned backend
+// implements Printer if primary does, writing the printed values into w too.
+func NewTeeBackend(primary Backend, w io.Writer, formatter Formatter) Backend {
+	b := &teeBackend{primary: primary, formatter: formatter, w: w}
+	if _, ok := primary.(Printer); ok {
+		return &teePrinter{b}
+	}
+	return b
+}
+
+// write writes the line into w, reporting the error to the default error
+// handler.
+func (b *teeBackend) write(line []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, err := b.w.Write(line); err != nil {
+		HandleError(err)
+	}
+}
+
+// Log implements the Backend interface.
+func (b *teeBackend) Log(level Level, calldepth int, rec *Record) error {
+	var buf bytes.Buffer
+	if b.formatter != nil {
+		if err := b.formatter.Format(calldepth+1, rec, &buf); err != nil {
+			HandleError(err)
+		}
+	} else {
+		if rec.formatter == nil {
+			rec.formatter = GetFormatter()
+		}
+		buf.WriteString(rec.Formatted(calldepth + 1))
+	}
+	buf.WriteByte('\n')
+	b.write(buf.Bytes())
+	return b.primary.Log(level, calldepth+1, rec)
+}
+
+// Flush implements the Flusher interface.
+func (b *teeBackend) Flush() error {
+	return FlushBackend(b.primary)
+}
+
+func (b *teeBackend) wrappedBackends() []Backend {
+	return []Backend{b.primary}
+}
+
+type teePrinter struct {
+	*teeBackend
+}
+
+// Print implements the Printer interface.
+func (b *teePrinter) Print(args ...interface{}) error {
+	b.write([]byte(fmt.Sprint(args...) + "\n"))
+	return b.primary.(Printer).Print(args...)
+}
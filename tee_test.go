@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("tee failed")
+}
+
+func TestTeeBackend(t *testing.T) {
+	InitForTesting(DEBUG)
+	defer SetErrorHandler(nil)
+
+	var buf bytes.Buffer
+	memory := NewMemoryBackend(8)
+	SetBackend(NewTeeBackend(memory, &buf, MustStringFormatter("%{level} %{message}")))
+	log := GetOrCreateLogger("tee")
+	log.Info("first")
+	log.Error("second")
+
+	if buf.String() != "INFO first\nERROR second\n" {
+		t.Errorf("unexpected tee output: %q", buf.String())
+	}
+	if lines := memoryRecords(memory); len(lines) != 2 || lines[0] != "first" {
+		t.Errorf("unexpected records: %q", lines)
+	}
+
+	var handled []error
+	SetErrorHandler(func(err error) { handled = append(handled, err) })
+	SetBackend(NewTeeBackend(memory, failingWriter{}, nil))
+	log.Info("third")
+	if len(handled) != 1 || handled[0].Error() != "tee failed" {
+		t.Errorf("unexpected handled errors: %v", handled)
+	}
+	if lines := memoryRecords(memory); len(lines) != 3 || lines[2] != "third" {
+		t.Errorf("unexpected records: %q", lines)
+	}
+}
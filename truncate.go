@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// truncateBackend truncates the long messages before passing the records to
+// the inner backend.
+type truncateBackend struct {
+	inner Backend
+	max   int
+}
+
+// NewTruncateBackend creates a backend which truncates the messages longer
+// than maxMessageBytes, appending the "…(truncated N bytes)" marker, where N
+// is the number of bytes removed, so a huge message doesn't fill the disk or
+// the ingestion quota. The message is cut before a multibyte character, never
+// into it. The message includes the prefix, but not the fields.
+//
+// The returned backend implements Printer if inner does, truncating the
+// printed values too.
+func NewTruncateBackend(inner Backend, maxMessageBytes int) Backend {
+	b := &truncateBackend{inner, maxMessageBytes}
+	if _, ok := inner.(Printer); ok {
+		return &truncatePrinter{b}
+	}
+	return b
+}
+
+// truncate returns msg truncated to the max bytes, and true, or false if it
+// isn't longer than max.
+func (b *truncateBackend) truncate(msg string) (string, bool) {
+	if len(msg) <= b.max {
+		return msg, false
+	}
+	n := b.max
+	for n > 0 && !utf8.RuneStart(msg[n]) {
+		n--
+	}
+	return msg[:n] + fmt.Sprintf("…(truncated %d bytes)", len(msg)-n), true
+}
+
+// Log implements the Backend interface.
+func (b *truncateBackend) Log(level Level, calldepth int, rec *Record) error {
+	msg, ok := b.truncate(rec.Message())
+	if !ok {
+		return b.inner.Log(level, calldepth+1, rec)
+	}
+	// Make a shallow copy of the record with the truncated message, which
+	// already includes the prefix.
+	r2 := *rec
+	r2.Prefix = ""
+	r2.Args = []interface{}{msg}
+	r2.fmt = nil
+	r2.message = &msg
+	r2.formatted, r2.formattedBy = "", nil
+	return b.inner.Log(level, calldepth+1, &r2)
+}
+
+// Flush implements the Flusher interface.
+func (b *truncateBackend) Flush() error {
+	return FlushBackend(b.inner)
+}
+
+func (b *truncateBackend) wrappedBackends() []Backend {
+	return []Backend{b.inner}
+}
+
+type truncatePrinter struct {
+	*truncateBackend
+}
+
+// Print implements the Printer interface.
+func (b *truncatePrinter) Print(args ...interface{}) error {
+	if msg, ok := b.truncate(fmt.Sprint(args...)); ok {
+		args = []interface{}{msg}
+	}
+	return b.inner.(Printer).Print(args...)
+}
@@ -0,0 +1,38 @@
+package logging
+
+import "testing"
+
+func TestTruncateBackend(t *testing.T) {
+	InitForTesting(DEBUG)
+	memory := NewMemoryBackend(8)
+	SetBackend(NewTruncateBackend(memory, 10))
+
+	log := GetOrCreateLogger("truncate")
+	log.Info("012345678")
+	log.Info("0123456789")
+	log.Info("0123456789abc")
+	log.Infof("%s%s", "aaaaaaaaa", "é")
+
+	expected := []string{
+		"012345678",
+		"0123456789",
+		"0123456789…(truncated 3 bytes)",
+		"aaaaaaaaa…(truncated 2 bytes)",
+	}
+	lines := memoryRecords(memory)
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected records: %q", lines)
+	}
+	for i, line := range lines {
+		if line != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], line)
+		}
+	}
+	if msg := MemoryRecordN(memory, 2).Data().Message; msg != expected[2] {
+		t.Errorf("unexpected record data message %q", msg)
+	}
+
+	if _, ok := NewTruncateBackend(NewNullBackend(), 10).(Printer); !ok {
+		t.Error("printer backend not kept")
+	}
+}
@@ -1,11 +1,21 @@
 package logging
 
-import "sync/atomic"
+import (
+	"sync/atomic"
+	"time"
+)
 
 type LogWriter interface {
 	Write(lvl Level, extraCalldepth int, format *string, args ...interface{})
 }
 
+// FieldsLogWriter is a LogWriter which accepts structured fields to be added
+// into the records.
+type FieldsLogWriter interface {
+	LogWriter
+	WriteFields(lvl Level, extraCalldepth int, fields Fields, format *string, args ...interface{})
+}
+
 type writerFunc func(lvl Level, extraCalldepth int, format *string, args ...interface{})
 
 func (w writerFunc) Write(lvl Level, extraCalldepth int, format *string, args ...interface{}) {
@@ -16,35 +26,259 @@ func NewWriter(f func(lvl Level, extraCalldepth int, format *string, args ...int
 	return writerFunc(f)
 }
 
+// clocked is implemented by loggers with a Clock, like Basic.
+type clocked interface {
+	now() time.Time
+}
+
+// errorHandled is implemented by loggers with an ErrorHandler, like Basic.
+type errorHandled interface {
+	handleError(err error)
+}
+
+type defaultWriter struct {
+	l      Logger
+	module string
+	// sync marks the records as synchronous, see SyncLogger.
+	sync bool
+}
+
+// DefaultWriter returns a LogWriter which logs the records of module into the
+// backend of l. The extraCalldepth of Write is the number of frames between
+// the caller of the logger and the Write call, eg. BasicCalldepth for Basic.
 func DefaultWriter(l Logger, module string) LogWriter {
-	return NewWriter(func(lvl Level, extraCalldepth int, format *string, args ...interface{}) {
-		if !l.IsEnabledFor(lvl) {
-			return
+	return &defaultWriter{l: l, module: module}
+}
+
+func (w *defaultWriter) Write(lvl Level, extraCalldepth int, format *string, args ...interface{}) {
+	w.write(lvl, extraCalldepth, nil, format, args...)
+}
+
+func (w *defaultWriter) WriteFields(lvl Level, extraCalldepth int, fields Fields, format *string, args ...interface{}) {
+	w.write(lvl, extraCalldepth, fields, format, args...)
+}
+
+func (w *defaultWriter) write(lvl Level, extraCalldepth int, fields Fields, format *string, args ...interface{}) {
+	if !w.l.IsEnabledFor(lvl) {
+		return
+	}
+
+	var now time.Time
+	if c, ok := w.l.(clocked); ok {
+		now = c.now()
+	} else {
+		now = timeNow()
+	}
+
+	// Create the logging record and pass it in to the backend
+	record := newRecord()
+	record.setID()
+	record.Time = now
+	record.Module = w.module
+	record.Level = lvl
+	record.Prefix, record.Fields = splitPrefix(fields)
+	record.Fields = withGlobalFields(record.Fields)
+	record.fmt = format
+	record.Args = args
+	record.sync = w.sync
+	// extraCalldepth is the number of frames between the caller of the
+	// logger and the writer, eg. Basic.Calldepth + Basic.ExtraCalldepth, and
+	// 2 skips the frames of the writer, write and Write or WriteFields.
+	record.pc = callerPC(2 + extraCalldepth)
+	if atomic.LoadInt32(&goroutineVerbUsed) != 0 {
+		record.goroutine = goroutineID()
+	}
+	defer releaseRecord(record)
+
+	backend := w.l.Backend()
+	if backend == nil {
+		backend = getDefaultBackend()
+	}
+	if err := backend.Log(lvl, 2+extraCalldepth, record); err != nil {
+		if h, ok := w.l.(errorHandled); ok {
+			h.handleError(err)
+		} else {
+			HandleError(err)
 		}
+	}
+}
 
-		// Create the logging record and pass it in to the backend
-		record := &Record{
-			ID:     atomic.AddUint64(&sequenceNo, 1),
-			Time:   timeNow(),
-			Module: module,
-			Level:  lvl,
-			fmt:    format,
-			Args:   args,
+// Flush implements the Flusher interface, flushing the backend of the logger.
+func (w *defaultWriter) Flush() error {
+	return w.l.Flush()
+}
+
+// prefixValue is the value of the fields used to pass the prefixes of
+// LogPrefix to the writer.
+type prefixValue string
+
+// splitPrefix returns a copy of fields without the prefix fields and the
+// prefixes joined.
+func splitPrefix(fields Fields) (prefix string, rest Fields) {
+	for i, field := range fields {
+		if p, ok := field.Value.(prefixValue); ok {
+			if rest == nil {
+				rest = append(make(Fields, 0, len(fields)), fields[:i]...)
+			}
+			if prefix != "" {
+				prefix += " "
+			}
+			prefix += string(p)
+		} else if rest != nil {
+			rest = append(rest, field)
 		}
+	}
+	if rest == nil {
+		return "", fields.Copy()
+	}
+	if len(rest) == 0 {
+		rest = nil
+	}
+	return
+}
 
-		// TODO use channels to fan out the records to all backends?
-		// TODO in case of errors, do something (tricky)
+// fieldsWriter adds fields to all records written by the parent writer.
+type fieldsWriter struct {
+	parent LogWriter
+	fields Fields
+}
+
+// WithFieldsWriter returns a LogWriter which adds fields to all records
+// written into w. If w isn't a FieldsLogWriter, the fields are discarded.
+func WithFieldsWriter(w LogWriter, fields Fields) LogWriter {
+	return &fieldsWriter{w, fields}
+}
 
-		// calldepth=2 brings the stack up to the caller of the level
-		// methods, Info(), Fatal(), etc.
-		// ExtraCallDepth allows this to be extended further up the stack in case we
-		// are wrapping these methods, eg. to expose them package level
+func (w *fieldsWriter) Write(lvl Level, extraCalldepth int, format *string, args ...interface{}) {
+	w.WriteFields(lvl, extraCalldepth+1, nil, format, args...)
+}
 
-		if backend := l.Backend(); backend != nil {
-			backend.Log(lvl, 2+extraCalldepth, record)
-			return
+func (w *fieldsWriter) WriteFields(lvl Level, extraCalldepth int, fields Fields, format *string, args ...interface{}) {
+	if fw, ok := w.parent.(FieldsLogWriter); ok {
+		fw.WriteFields(lvl, extraCalldepth+1, append(w.fields.Copy(), fields...), format, args...)
+		return
+	}
+	w.parent.Write(lvl, extraCalldepth+1, format, args...)
+}
+
+// Flush implements the Flusher interface, flushing the parent writer.
+func (w *fieldsWriter) Flush() error {
+	return flushWriter(w.parent)
+}
+
+// rebaseWriter returns w with its innermost default writer replaced by base,
+// keeping the fields added by the fields writers. Other writers are replaced
+// by base.
+func rebaseWriter(w LogWriter, base LogWriter) LogWriter {
+	if fw, ok := w.(*fieldsWriter); ok {
+		return &fieldsWriter{rebaseWriter(fw.parent, base), fw.fields}
+	}
+	return base
+}
+
+// innerWriter returns the writer wrapped by the fields writers of w.
+func innerWriter(w LogWriter) LogWriter {
+	for {
+		fw, ok := w.(*fieldsWriter)
+		if !ok {
+			return w
+		}
+		w = fw.parent
+	}
+}
+
+// enabledWriter is implemented by the writers which know whether they write
+// the records of a level.
+type enabledWriter interface {
+	isEnabledFor(level Level) bool
+}
+
+// writerEnabledFor reports whether w writes the records of level. The writers
+// which don't implement enabledWriter are always enabled.
+func writerEnabledFor(w LogWriter, level Level) bool {
+	if w == nil {
+		return false
+	}
+	if e, ok := w.(enabledWriter); ok {
+		return e.isEnabledFor(level)
+	}
+	return true
+}
+
+func (w *defaultWriter) isEnabledFor(level Level) bool {
+	return w.l.IsEnabledFor(level)
+}
+
+func (w *fieldsWriter) isEnabledFor(level Level) bool {
+	return writerEnabledFor(w.parent, level)
+}
+
+// isEnabledFor returns true if some writer is enabled for level.
+func (w multiWriter) isEnabledFor(level Level) bool {
+	for _, w := range w {
+		if writerEnabledFor(w, level) {
+			return true
 		}
+	}
+	return false
+}
+
+// flushWriter flushes w if it implements Flusher.
+func flushWriter(w LogWriter) error {
+	if f, ok := w.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// asyncUnflushedWriter reports whether the backends w writes into include an
+// async one which can't be flushed, see asyncUnflushed.
+func asyncUnflushedWriter(w LogWriter) bool {
+	switch w := w.(type) {
+	case *defaultWriter:
+		if l, ok := w.l.(*Log); ok {
+			if l.backend != nil {
+				return asyncUnflushed(l.backend)
+			}
+			return asyncUnflushed(getDefaultBackend())
+		}
+	case *fieldsWriter:
+		return asyncUnflushedWriter(w.parent)
+	case multiWriter:
+		for _, w := range w {
+			if asyncUnflushedWriter(w) {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-		defaultBackend.Log(lvl, 2+extraCalldepth, record)
-	})
+// multiWriter dispatches the writes to all writers.
+type multiWriter []LogWriter
+
+func (w multiWriter) Write(lvl Level, extraCalldepth int, format *string, args ...interface{}) {
+	for _, w := range w {
+		w.Write(lvl, 1+extraCalldepth, format, args...)
+	}
+}
+
+func (w multiWriter) WriteFields(lvl Level, extraCalldepth int, fields Fields, format *string, args ...interface{}) {
+	for _, w := range w {
+		if fw, ok := w.(FieldsLogWriter); ok {
+			fw.WriteFields(lvl, 1+extraCalldepth, fields, format, args...)
+		} else {
+			w.Write(lvl, 1+extraCalldepth, format, args...)
+		}
+	}
+}
+
+// Flush implements the Flusher interface, flushing all writers.
+func (w multiWriter) Flush() (err error) {
+	for _, w := range w {
+		if e := flushWriter(w); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
 }